@@ -0,0 +1,353 @@
+// Package client is a Go client for the golive REST API.
+//
+//	c := client.New("http://localhost:9090")
+//	job, err := c.CreateJob(ctx, models.JobCreateRequest{Description: "CI"})
+//	if err != nil {
+//		return err
+//	}
+//	err = c.StartJob(ctx, job.ID)
+//	job, err = c.WaitForState(ctx, job.ID, client.StatusRunning)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// Job statuses reported by the API.
+const (
+	StatusCreated   = "created"
+	StatusQueued    = "queued" // Waiting for the host to have the resources to run it
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "error"
+)
+
+// DefaultPollInterval is the time between checks of WaitForState.
+const DefaultPollInterval = time.Second
+
+var (
+	// ErrJobFailed is returned by WaitForState when the job failed before
+	// reaching the state.
+	ErrJobFailed = errors.New("job failed")
+	// ErrJobCompleted is returned by WaitForState when the job completed
+	// before it was seen running.
+	ErrJobCompleted = errors.New("job completed")
+)
+
+// Client calls a golive server. It is safe for concurrent use.
+type Client struct {
+	baseURL string
+
+	// HTTPClient sends the requests, http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// PollInterval is the time between checks of WaitForState,
+	// DefaultPollInterval when zero.
+	PollInterval time.Duration
+}
+
+// New returns a client for the server at baseURL, e.g. http://localhost:9090.
+func New(baseURL string) *Client {
+	return &Client{baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Error is a response with an error status.
+type Error struct {
+	StatusCode int
+	Code       models.ErrorCode
+	Message    string
+	Details    []models.FieldError // One entry per validation failure
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	for i, detail := range e.Details {
+		sep := "; "
+		if i == 0 {
+			sep = ": "
+		}
+		msg += sep + detail.Message
+	}
+	return fmt.Sprintf("%s (%d)", msg, e.StatusCode)
+}
+
+// IsNotFound reports whether err is a response for a missing job or template.
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// CreateJob creates a stream, it starts when StartJob is called or at its
+// scheduled time.
+func (c *Client) CreateJob(ctx context.Context, request models.JobCreateRequest) (*models.Job, error) {
+	var job models.Job
+	if err := c.do(ctx, http.MethodPost, "/jobs", request, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CreateJobFromTemplate creates a stream from a template. The overrides are
+// a JSON merge patch of the request of the template, nil keeps it as it is.
+func (c *Client) CreateJobFromTemplate(ctx context.Context, template string, overrides any) (*models.Job, error) {
+	body := map[string]any{"template": template}
+	if overrides != nil {
+		body["overrides"] = overrides
+	}
+	var job models.Job
+	if err := c.do(ctx, http.MethodPost, "/jobs", body, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetJob returns a stream.
+func (c *Client) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	var job models.Job
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListJobs returns all streams, oldest first.
+func (c *Client) ListJobs(ctx context.Context) ([]models.Job, error) {
+	return c.FindJobs(ctx, models.JobListQuery{})
+}
+
+// FindJobs returns the streams matching the filters of the query, on all
+// pages, e.g. models.JobListQuery{Owners: []string{"player-web"}}.
+func (c *Client) FindJobs(ctx context.Context, query models.JobListQuery) ([]models.Job, error) {
+	query.Limit, query.Offset = models.MaxJobListLimit, 0
+	var jobs []models.Job
+	for {
+		page, err := c.QueryJobs(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, page.Jobs...)
+		if page.NextOffset == 0 {
+			return jobs, nil
+		}
+		query.Offset = page.NextOffset
+	}
+}
+
+// QueryJobs returns a page of the streams matching the query, e.g.
+// models.JobListQuery{Statuses: []string{client.StatusRunning}, Sort: "-created"}.
+// A zero Limit returns the default page size.
+func (c *Client) QueryJobs(ctx context.Context, query models.JobListQuery) (*models.JobList, error) {
+	path := "/jobs"
+	if values := query.Values(); len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+	var list models.JobList
+	if err := c.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// UpdateJob changes the settings of a stream. The patch is a JSON merge patch
+// of the create request, e.g. map[string]any{"video": map[string]any{"bitrate": "2M"}}.
+// A running stream applies some settings live and restarts its encoder for the
+// others, the response tells which.
+func (c *Client) UpdateJob(ctx context.Context, id string, patch any) (*models.JobUpdateResponse, error) {
+	var update models.JobUpdateResponse
+	if err := c.do(ctx, http.MethodPatch, "/jobs/"+url.PathEscape(id), patch, &update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+// StartJob starts encoding a stream.
+func (c *Client) StartJob(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPut, "/jobs/"+url.PathEscape(id)+"/start", nil, nil)
+}
+
+// StopJob stops encoding a stream, its output stays until DeleteJob.
+func (c *Client) StopJob(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPut, "/jobs/"+url.PathEscape(id)+"/stop", nil, nil)
+}
+
+// CloneJob creates a new stream with the settings of a stream.
+func (c *Client) CloneJob(ctx context.Context, id string) (*models.Job, error) {
+	var job models.Job
+	if err := c.do(ctx, http.MethodPost, "/jobs/"+url.PathEscape(id)+"/clone", nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListRuns returns every start of a stream, oldest first.
+func (c *Client) ListRuns(ctx context.Context, id string) ([]models.Run, error) {
+	var runs []models.Run
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id)+"/runs", nil, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// GetRun returns a start of a stream, runs are numbered from 1.
+func (c *Client) GetRun(ctx context.Context, id string, run int) (*models.Run, error) {
+	var r models.Run
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/jobs/%s/runs/%d", url.PathEscape(id), run), nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// StopJobs stops the running streams matching the filters of the query, e.g.
+// models.JobListQuery{Labels: map[string]string{"ci_run": "8812"}}. The
+// query must have a filter, its page is ignored.
+func (c *Client) StopJobs(ctx context.Context, query models.JobListQuery) (*models.BulkJobResponse, error) {
+	return c.bulk(ctx, http.MethodPut, "/jobs/stop", query)
+}
+
+// DeleteJobs deletes the completed and failed streams matching the filters
+// of the query and their output. The query must have a filter, its page is
+// ignored.
+func (c *Client) DeleteJobs(ctx context.Context, query models.JobListQuery) (*models.BulkJobResponse, error) {
+	return c.bulk(ctx, http.MethodDelete, "/jobs", query)
+}
+
+func (c *Client) bulk(ctx context.Context, method, path string, query models.JobListQuery) (*models.BulkJobResponse, error) {
+	query.Sort, query.Limit, query.Offset = "", 0, 0
+	var response models.BulkJobResponse
+	if err := c.do(ctx, method, path+"?"+query.Values().Encode(), nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeleteJob deletes a completed or failed stream and its output.
+func (c *Client) DeleteJob(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/jobs/"+url.PathEscape(id), nil, nil)
+}
+
+// ListTemplates returns the built-in and saved templates.
+func (c *Client) ListTemplates(ctx context.Context) ([]models.Template, error) {
+	var templates []models.Template
+	if err := c.do(ctx, http.MethodGet, "/templates", nil, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// GetTemplate returns a template.
+func (c *Client) GetTemplate(ctx context.Context, name string) (*models.Template, error) {
+	var template models.Template
+	if err := c.do(ctx, http.MethodGet, "/templates/"+url.PathEscape(name), nil, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// SaveTemplate saves a template on the server, replacing the one with the same name.
+func (c *Client) SaveTemplate(ctx context.Context, template models.Template) (*models.Template, error) {
+	var saved models.Template
+	if err := c.do(ctx, http.MethodPut, "/templates/"+url.PathEscape(template.Name), template, &saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// DeleteTemplate deletes a saved template.
+func (c *Client) DeleteTemplate(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/templates/"+url.PathEscape(name), nil, nil)
+}
+
+// WaitForState polls a stream until it has the status, one of the Status
+// constants. It gives up when ctx is done, or with ErrJobFailed or
+// ErrJobCompleted when the stream can no longer get there. The last job seen
+// is returned with the error.
+func (c *Client) WaitForState(ctx context.Context, id, status string) (*models.Job, error) {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last *models.Job
+	for {
+		job, err := c.GetJob(ctx, id)
+		if err != nil && ctx.Err() == nil {
+			return last, err
+		}
+		if job != nil {
+			last = job
+			switch {
+			case job.Status == status:
+				return job, nil
+			case job.Status == StatusFailed:
+				return job, fmt.Errorf("waiting for job %s to be %s: %w", id, status, ErrJobFailed)
+			case job.Status == StatusCompleted && status == StatusRunning:
+				return job, fmt.Errorf("waiting for job %s to be %s: %w", id, status, ErrJobCompleted)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return last, fmt.Errorf("waiting for job %s to be %s: %w", id, status, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// do sends a request with body encoded as JSON and decodes the response into out.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/v1"+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func newError(resp *http.Response) *Error {
+	body, _ := io.ReadAll(resp.Body)
+	var errResp models.ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Message != "" {
+		return &Error{StatusCode: resp.StatusCode, Code: errResp.Code, Message: errResp.Message, Details: errResp.Details}
+	}
+	// Not from the API, e.g. a proxy in between
+	apiErr := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
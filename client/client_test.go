@@ -0,0 +1,423 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/internal/api/handlers"
+	"github.com/arunjeyaprasad/golive/internal/api/middleware"
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/gorilla/mux"
+)
+
+// fakeFFmpeg puts an ffmpeg on the PATH that runs until it is interrupted.
+// It takes nothing from the host, so the resource checks are off.
+func fakeFFmpeg(t *testing.T) {
+	policy := config.RESOURCE_POLICY
+	config.RESOURCE_POLICY = config.ResourcePolicyOff
+	t.Cleanup(func() { config.RESOURCE_POLICY = policy })
+	dir := t.TempDir()
+	script := "#!/bin/sh\ntrap 'exit 0' INT TERM\nwhile :; do sleep 0.1; done\n"
+	if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func newServer(t *testing.T) *Client {
+	mediaDir := config.DEFAULT_MEDIA_DIR
+	config.DEFAULT_MEDIA_DIR = t.TempDir()
+	t.Cleanup(func() { config.DEFAULT_MEDIA_DIR = mediaDir })
+	templatesDir := config.TEMPLATES_DIR
+	config.TEMPLATES_DIR = t.TempDir()
+	t.Cleanup(func() { config.TEMPLATES_DIR = templatesDir })
+
+	r := mux.NewRouter()
+	r.Use(middleware.MuxVars)
+	handlers.RegisterRoutes(r.PathPrefix("/v1").Subrouter())
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	c := New(srv.URL + "/")
+	c.PollInterval = 10 * time.Millisecond
+	return c
+}
+
+func TestClient_EndToEnd(t *testing.T) {
+	fakeFFmpeg(t)
+	c := newServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	job, err := c.CreateJob(ctx, models.JobCreateRequest{
+		Description: "Integration test",
+		VideoTrack:  &models.VideoTrack{Resolution: "640x360"},
+	})
+	if err != nil {
+		t.Fatalf("CreateJob() error = %v", err)
+	}
+	if job.Status != StatusCreated || job.Configuration.VideoTrack.Resolution != "640x360" {
+		t.Errorf("CreateJob() = %+v", job)
+	}
+
+	list, err := c.ListJobs(ctx)
+	if err != nil || len(list) != 1 || list[0].ID != job.ID {
+		t.Fatalf("ListJobs() = %v, %v, want the created job", list, err)
+	}
+	page, err := c.QueryJobs(ctx, models.JobListQuery{Statuses: []string{StatusRunning}, Query: "integration"})
+	if err != nil || page.Total != 0 || len(page.Jobs) != 0 {
+		t.Errorf("QueryJobs(running) = %+v, %v, want no jobs", page, err)
+	}
+	page, err = c.QueryJobs(ctx, models.JobListQuery{Statuses: []string{StatusCreated}, Query: "integration"})
+	if err != nil || page.Total != 1 || page.Jobs[0].ID != job.ID || page.Limit != models.DefaultJobListLimit {
+		t.Errorf("QueryJobs(created) = %+v, %v, want the created job", page, err)
+	}
+
+	if err := c.StartJob(ctx, job.ID); err != nil {
+		t.Fatalf("StartJob() error = %v", err)
+	}
+	running, err := c.WaitForState(ctx, job.ID, StatusRunning)
+	if err != nil {
+		t.Fatalf("WaitForState(running) error = %v", err)
+	}
+	if len(running.PlaybackURLs) == 0 {
+		t.Error("running job has no playback URLs")
+	}
+
+	update, err := c.UpdateJob(ctx, job.ID, map[string]any{"description": "Updated"})
+	if err != nil || update.Restarted || update.Job.Configuration.Description != "Updated" {
+		t.Errorf("UpdateJob(description) = %+v, %v, want a hot update", update, err)
+	}
+	update, err = c.UpdateJob(ctx, job.ID, map[string]any{"video": map[string]any{"bitrate": "2M"}})
+	if err != nil || !update.Restarted || update.Job.Status != StatusRunning {
+		t.Fatalf("UpdateJob(video) = %+v, %v, want a restarted running job", update, err)
+	}
+	if got := update.Job.Configuration.VideoTrack; got.BitRate != "2M" || got.Resolution != "640x360" {
+		t.Errorf("UpdateJob(video) video = %+v, want the bitrate merged into the track", got)
+	}
+	if update.Job.StreamingStartedAt != running.StreamingStartedAt {
+		t.Errorf("UpdateJob(video) restarted the run at %s, want %s", update.Job.StreamingStartedAt, running.StreamingStartedAt)
+	}
+
+	// Only stopped jobs can be deleted
+	var apiErr *Error
+	if err := c.DeleteJob(ctx, job.ID); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("DeleteJob() of a running job error = %v, want a 400 Error", err)
+	}
+
+	if err := c.StopJob(ctx, job.ID); err != nil {
+		t.Fatalf("StopJob() error = %v", err)
+	}
+	if _, err := c.WaitForState(ctx, job.ID, StatusCompleted); err != nil {
+		t.Fatalf("WaitForState(completed) error = %v", err)
+	}
+	if err := c.DeleteJob(ctx, job.ID); err != nil {
+		t.Fatalf("DeleteJob() error = %v", err)
+	}
+	if _, err := c.GetJob(ctx, job.ID); !IsNotFound(err) {
+		t.Errorf("GetJob() of a deleted job error = %v, want not found", err)
+	}
+}
+
+func TestClient_Runs(t *testing.T) {
+	fakeFFmpeg(t)
+	c := newServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	job, err := c.CreateJob(ctx, models.JobCreateRequest{Description: "Runs"})
+	if err != nil {
+		t.Fatalf("CreateJob() error = %v", err)
+	}
+	for run := 1; run <= 2; run++ {
+		if err := c.StartJob(ctx, job.ID); err != nil {
+			t.Fatalf("StartJob() #%d error = %v", run, err)
+		}
+		r, err := c.GetRun(ctx, job.ID, run)
+		if err != nil || r.Number != run || r.Status != StatusRunning {
+			t.Fatalf("GetRun(%d) = %+v, %v, want a running run", run, r, err)
+		}
+		var apiErr *Error
+		if err := c.StartJob(ctx, job.ID); !errors.As(err, &apiErr) || apiErr.Code != models.ErrorCodeInvalidState {
+			t.Errorf("StartJob() of a running job error = %v, want invalid_state", err)
+		}
+		if err := c.StopJob(ctx, job.ID); err != nil {
+			t.Fatalf("StopJob() #%d error = %v", run, err)
+		}
+		if _, err := os.Stat(filepath.Join(config.DEFAULT_MEDIA_DIR, job.ID, "runs", strconv.Itoa(run), "overlay.txt")); err != nil {
+			t.Errorf("run %d did not write to its own directory: %v", run, err)
+		}
+	}
+
+	runs, err := c.ListRuns(ctx, job.ID)
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("ListRuns() = %+v, %v, want 2 runs", runs, err)
+	}
+	for i, r := range runs {
+		if r.Number != i+1 || r.Status != StatusCompleted || r.StartedAt == "" || r.CompletedAt == "" || len(r.PlaybackURLs) == 0 {
+			t.Errorf("ListRuns()[%d] = %+v, want a completed run with playback URLs", i, r)
+		}
+	}
+	if runs[0].PlaybackURLs[0].URL == runs[1].PlaybackURLs[0].URL {
+		t.Errorf("runs share the playback URL %s", runs[0].PlaybackURLs[0].URL)
+	}
+	if _, err := c.GetRun(ctx, job.ID, 3); !IsNotFound(err) {
+		t.Errorf("GetRun(3) error = %v, want not found", err)
+	}
+
+	clone, err := c.CloneJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("CloneJob() error = %v", err)
+	}
+	if clone.ID == job.ID || clone.ClonedFrom != job.ID || clone.Status != StatusCreated || clone.Run != 0 || clone.Configuration.Description != "Runs" {
+		t.Errorf("CloneJob() = %+v, want a new job with the same configuration", clone)
+	}
+	if runs, err := c.ListRuns(ctx, clone.ID); err != nil || len(runs) != 0 {
+		t.Errorf("ListRuns() of the clone = %+v, %v, want none", runs, err)
+	}
+	if _, err := c.CloneJob(ctx, "missing"); !IsNotFound(err) {
+		t.Errorf("CloneJob() of a missing job error = %v, want not found", err)
+	}
+}
+
+func TestClient_BulkJobs(t *testing.T) {
+	fakeFFmpeg(t)
+	c := newServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var ids []string
+	for _, labels := range []map[string]string{{"ci_run": "8812"}, {"ci_run": "8812"}, {"ci_run": "8813"}} {
+		job, err := c.CreateJob(ctx, models.JobCreateRequest{Owner: "bulk-test", Labels: labels})
+		if err != nil {
+			t.Fatalf("CreateJob() error = %v", err)
+		}
+		ids = append(ids, job.ID)
+	}
+	if err := c.StartJob(ctx, ids[0]); err != nil {
+		t.Fatalf("StartJob() error = %v", err)
+	}
+	run := models.JobListQuery{Labels: map[string]string{"ci_run": "8812"}}
+	found, err := c.FindJobs(ctx, run)
+	if err != nil || len(found) != 2 {
+		t.Fatalf("FindJobs() = %+v, %v, want the 2 jobs of the run", found, err)
+	}
+
+	stopped, err := c.StopJobs(ctx, run)
+	if err != nil || !reflect.DeepEqual(stopped.Jobs, ids[:1]) || len(stopped.Skipped) != 1 || stopped.Skipped[0].ID != ids[1] {
+		t.Fatalf("StopJobs() = %+v, %v, want the running job stopped and the created one skipped", stopped, err)
+	}
+	deleted, err := c.DeleteJobs(ctx, run)
+	if err != nil || !reflect.DeepEqual(deleted.Jobs, ids[:1]) || len(deleted.Skipped) != 1 {
+		t.Fatalf("DeleteJobs() = %+v, %v, want the stopped job deleted", deleted, err)
+	}
+	if list, err := c.FindJobs(ctx, models.JobListQuery{Owners: []string{"bulk-test"}}); err != nil || len(list) != 2 {
+		t.Errorf("FindJobs(owner) = %+v, %v, want the 2 other jobs", list, err)
+	}
+
+	var apiErr *Error
+	if _, err := c.DeleteJobs(ctx, models.JobListQuery{}); !errors.As(err, &apiErr) || apiErr.Code != models.ErrorCodeInvalidRequest {
+		t.Errorf("DeleteJobs() without filters error = %v, want invalid_request", err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	c := newServer(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		call        func() error
+		wantStatus  int
+		wantCode    models.ErrorCode
+		wantDetails []models.FieldError
+	}{
+		{
+			name: "Validation errors",
+			call: func() error {
+				_, err := c.CreateJob(ctx, models.JobCreateRequest{
+					VideoTrack: &models.VideoTrack{BitRate: "5x"},
+					JobFormat:  models.JobFormat{SegmentLength: -1},
+				})
+				return err
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   models.ErrorCodeValidation,
+			wantDetails: []models.FieldError{
+				{Field: "segment_length", Message: "segment_length must be greater than 0"},
+				{Field: "video.bitrate", Message: "video bitrate must end with k or M"},
+			},
+		},
+		{
+			name:       "Missing job",
+			call:       func() error { return c.StartJob(ctx, "missing") },
+			wantStatus: http.StatusNotFound,
+			wantCode:   models.ErrorCodeNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apiErr *Error
+			if err := tt.call(); !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want an Error", err)
+			}
+			if apiErr.StatusCode != tt.wantStatus || apiErr.Code != tt.wantCode {
+				t.Errorf("StatusCode, Code = %d, %s, want %d, %s", apiErr.StatusCode, apiErr.Code, tt.wantStatus, tt.wantCode)
+			}
+			if !reflect.DeepEqual(apiErr.Details, tt.wantDetails) {
+				t.Errorf("Details = %+v, want %+v", apiErr.Details, tt.wantDetails)
+			}
+		})
+	}
+}
+
+func TestClient_PathEscape(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.EscapedPath()
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(srv.Close)
+	c := New(srv.URL)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want string
+	}{
+		{
+			name: "Job",
+			call: func() error { _, err := c.GetJob(ctx, "a b/c?d"); return err },
+			want: "/v1/jobs/a%20b%2Fc%3Fd",
+		},
+		{
+			name: "Job action",
+			call: func() error { return c.StartJob(ctx, "a#b") },
+			want: "/v1/jobs/a%23b/start",
+		},
+		{
+			name: "Run",
+			call: func() error { _, err := c.GetRun(ctx, "a/b", 2); return err },
+			want: "/v1/jobs/a%2Fb/runs/2",
+		},
+		{
+			name: "Template",
+			call: func() error { return c.DeleteTemplate(ctx, "../jobs") },
+			want: "/v1/templates/..%2Fjobs",
+		},
+		{
+			name: "Saved template",
+			call: func() error { _, err := c.SaveTemplate(ctx, models.Template{Name: "a?b"}); return err },
+			want: "/v1/templates/a%3Fb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("path = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Templates(t *testing.T) {
+	c := newServer(t)
+	ctx := context.Background()
+
+	saved, err := c.SaveTemplate(ctx, models.Template{
+		Name:        "team-720p",
+		Description: "Known good 720p",
+		Request: models.JobCreateRequest{
+			Description: "Team stream",
+			VideoTrack:  &models.VideoTrack{BitRate: "2M", Resolution: "1280x720"},
+		},
+	})
+	if err != nil || saved.BuiltIn {
+		t.Fatalf("SaveTemplate() = %+v, %v", saved, err)
+	}
+	list, err := c.ListTemplates(ctx)
+	if err != nil {
+		t.Fatalf("ListTemplates() error = %v", err)
+	}
+	var names []string
+	for _, template := range list {
+		names = append(names, template.Name)
+	}
+	if want := []string{"1080p-hevc", "4k-av1", "ll-hls-2s", "team-720p"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListTemplates() names = %v, want %v", names, want)
+	}
+
+	job, err := c.CreateJobFromTemplate(ctx, "team-720p", map[string]any{"video": map[string]any{"framerate": "25"}})
+	if err != nil {
+		t.Fatalf("CreateJobFromTemplate() error = %v", err)
+	}
+	if got := job.Configuration; got.Description != "Team stream" || got.VideoTrack.BitRate != "2M" || got.VideoTrack.Framerate != "25" || got.SegmentLength == 0 {
+		t.Errorf("CreateJobFromTemplate() config = %+v, want the template with the override and defaults", got)
+	}
+
+	var apiErr *Error
+	if _, err := c.CreateJobFromTemplate(ctx, "missing", nil); !errors.As(err, &apiErr) || apiErr.Code != models.ErrorCodeValidation || len(apiErr.Details) != 1 || apiErr.Details[0].Field != "template" {
+		t.Errorf("CreateJobFromTemplate() of a missing template error = %v, want a validation error of the template", err)
+	}
+	if _, err := c.SaveTemplate(ctx, models.Template{Name: "4k-av1"}); !errors.As(err, &apiErr) || apiErr.Code != models.ErrorCodeInvalidState {
+		t.Errorf("SaveTemplate() of a built-in template error = %v, want invalid_state", err)
+	}
+	if err := c.DeleteTemplate(ctx, "team-720p"); err != nil {
+		t.Fatalf("DeleteTemplate() error = %v", err)
+	}
+	if _, err := c.GetTemplate(ctx, "team-720p"); !IsNotFound(err) {
+		t.Errorf("GetTemplate() of a deleted template error = %v, want not found", err)
+	}
+}
+
+func TestClient_WaitForState(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		want     string
+		wantErr  error
+	}{
+		{name: "Reaches the state", statuses: []string{"created", "running"}, want: StatusRunning},
+		{name: "Fails", statuses: []string{"created", "error"}, want: StatusRunning, wantErr: ErrJobFailed},
+		{name: "Completes first", statuses: []string{"completed"}, want: StatusRunning, wantErr: ErrJobCompleted},
+		{name: "Times out", statuses: []string{"created"}, want: StatusCompleted, wantErr: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := tt.statuses
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := statuses[0]
+				if len(statuses) > 1 {
+					statuses = statuses[1:]
+				}
+				w.Write([]byte(`{"id": "job-1", "status": "` + status + `"}`))
+			}))
+			defer srv.Close()
+			c := New(srv.URL)
+			c.PollInterval = time.Millisecond
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			job, err := c.WaitForState(ctx, "job-1", tt.want)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WaitForState() error = %v, want %v", err, tt.wantErr)
+			}
+			if job == nil || (tt.wantErr == nil && job.Status != tt.want) {
+				t.Errorf("WaitForState() = %+v", job)
+			}
+		})
+	}
+}
@@ -1,14 +1,20 @@
 package config
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
-	MAX_JOB_COUNT              = 2
+	MAX_JOB_COUNT              = 0 // Jobs running at once, 0 for no limit
 	DEFAULT_SERVER_PORT        = 9090
+	API_VERSION                = "v1" // Path prefix of the API, the unprefixed routes are kept for older clients
 	DEFAULT_MEDIA_DIR          = "media"
+	TEMPLATES_DIR              = "job-templates"
 	DEFAULT_SEGMENT_LENGTH     = 6      // 6 seconds
 	DEFAULT_WINDOW_SIZE        = 6      // 6 segments
 	DEFAULT_VIDEO_CODEC        = "h264" // Default video codec
@@ -24,10 +30,50 @@ var (
 	MAX_VIDEO_WIDTH            = 3840   // 3840 pixels (4K)
 	MAX_VIDEO_HEIGHT           = 2160   // 2160 pixels (4K)
 	MAX_AUDIO_LANGUAGES        = 16     // Maximum number of audio languages supported
+	MAX_DVR_WINDOW_SECONDS     = 21600  // 6 hours
 	VALID_VIDEO_CODECS         = []string{"h264", "hevc", "vp9", "av1"}
 	VALID_AUDIO_CODECS         = []string{"aac", "mp3"}
+	WEBHOOK_URLS               = []string{} // Global webhooks receiving the events of every job
+	WEBHOOK_SECRET             = ""         // Signs deliveries to the global webhooks
+	WEBHOOK_MAX_ATTEMPTS       = 5          // Deliveries are retried with exponential backoff
 )
 
+// Retention of job output, zero values keep everything
+var (
+	RETENTION_TTL      time.Duration // Completed and failed jobs, and earlier runs, are deleted this long after they stopped
+	MAX_MEDIA_BYTES    int64         // The oldest completed jobs and earlier runs are deleted while the media directory is larger
+	RETENTION_INTERVAL = time.Minute // Time between checks of the retention policies
+)
+
+// Resource guardrails of jobs
+var (
+	RESOURCE_POLICY         = "reject"         // Starting a job the host cannot sustain: reject, queue or off
+	MIN_FREE_DISK_BYTES     = int64(1 << 30)   // Starts keep this free, running jobs stop below a quarter of it
+	RESOURCE_CHECK_INTERVAL = 10 * time.Second // Time between checks of the disk and the queue
+	// Delegated cgroup v2 directory the jobs with CPU or memory limits get a
+	// cgroup in, those limits are not enforced without it
+	CGROUP_ROOT = ""
+)
+
+// Stopping ffmpeg, it finalizes its output on SIGINT and is killed with its
+// process group if it has not exited after STOP_TIMEOUT
+var STOP_TIMEOUT = 10 * time.Second
+
+// Playback URLs, relative to the URL each request was sent to unless
+// PUBLIC_URL is set
+var (
+	PUBLIC_URL = ""         // Base URL clients reach the server at, e.g. https://streams.example.com/golive/
+	CDN_HOSTS  = []string{} // Hosts serving the media besides the public URL, each gets a copy of the playback URLs
+)
+
+// Resource policies
+const (
+	ResourcePolicyReject = "reject"
+	ResourcePolicyQueue  = "queue"
+	ResourcePolicyOff    = "off"
+)
+
+// Init creates the media directory, call it after Load.
 func Init() {
 	info, err := os.Stat(DEFAULT_MEDIA_DIR)
 	if err == nil && info.IsDir() {
@@ -37,3 +83,21 @@ func Init() {
 	slog.Info("Creating media directory", "path", DEFAULT_MEDIA_DIR)
 	os.Mkdir(DEFAULT_MEDIA_DIR, os.ModePerm)
 }
+
+// ParseSize parses a size in bytes with an optional K, M, G or T suffix of
+// powers of 1024, e.g. 50G.
+func ParseSize(s string) (int64, error) {
+	units := map[byte]int64{'K': 1 << 10, 'M': 1 << 20, 'G': 1 << 30, 'T': 1 << 40}
+	multiplier := int64(1)
+	if s != "" {
+		if unit, ok := units[strings.ToUpper(s)[len(s)-1]]; ok {
+			multiplier = unit
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > (1<<62)/multiplier {
+		return 0, fmt.Errorf("size must be a number of bytes with an optional K, M, G or T suffix")
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{size: "1024", want: 1024},
+		{size: "512K", want: 512 << 10},
+		{size: "20m", want: 20 << 20},
+		{size: "50G", want: 50 << 30},
+		{size: "2T", want: 2 << 40},
+		{size: "", wantErr: true},
+		{size: "G", wantErr: true},
+		{size: "1.5G", wantErr: true},
+		{size: "-1", wantErr: true},
+		{size: "10P", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			got, err := ParseSize(tt.size)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseSize() = %d, %v, want %d, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of the value of a setting, in increasing precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Codecs the encoder and the resource estimates know, the valid codecs are
+// a subset of them
+var (
+	SupportedVideoCodecs = []string{"h264", "hevc", "vp9", "av1"}
+	SupportedAudioCodecs = []string{"aac", "mp3"}
+)
+
+// Setting is a configuration value. It is set by the key Name in the
+// configuration file, the environment variable GOLIVE_NAME and the flag
+// --name of serve, with - for _.
+type Setting struct {
+	Name   string
+	Usage  string
+	Source string // Where the value came from, one of the Source constants
+	Secret bool   // The value is redacted when shown
+	value  value
+}
+
+// value is the variable behind a setting, Set validates what it is given.
+type value interface {
+	flag.Value
+	Get() any
+}
+
+// Env returns the environment variable of the setting.
+func (s *Setting) Env() string {
+	return "GOLIVE_" + strings.ToUpper(s.Name)
+}
+
+// Flag returns the command line flag of the setting, without dashes.
+func (s *Setting) Flag() string {
+	return strings.ReplaceAll(s.Name, "_", "-")
+}
+
+// Value returns the effective value, durations as strings like 10s.
+func (s *Setting) Value() any {
+	return s.value.Get()
+}
+
+// String returns the effective value as the environment variable takes it.
+func (s *Setting) String() string {
+	return s.value.String()
+}
+
+// settings are the configurable package variables.
+var settings = []*Setting{
+	{Name: "port", Usage: "port the server listens on", value: &intValue{p: &DEFAULT_SERVER_PORT, min: 1, max: 65535}},
+	{Name: "media_dir", Usage: "directory of the output of the jobs", value: &stringValue{p: &DEFAULT_MEDIA_DIR}},
+	{Name: "templates_dir", Usage: "directory of the saved job templates", value: &stringValue{p: &TEMPLATES_DIR}},
+	{Name: "max_jobs", Usage: "jobs running at once, 0 for no limit", value: &intValue{p: &MAX_JOB_COUNT, max: 1 << 16}},
+	{Name: "segment_length", Usage: "default segment length in seconds", value: &intValue{p: &DEFAULT_SEGMENT_LENGTH, min: 1, max: 60}},
+	{Name: "window_size", Usage: "default number of segments in the playlists", value: &intValue{p: &DEFAULT_WINDOW_SIZE, min: 1, max: 1 << 16}},
+	{Name: "max_video_bitrate_mbps", Usage: "highest video bitrate of a job", value: &intValue{p: &MAX_VIDEO_BITRATE_MBPS, min: 1, max: 1000}},
+	{Name: "max_audio_bitrate_kbps", Usage: "highest audio bitrate of a job", value: &intValue{p: &MAX_AUDIO_BITRATE_KBPS, min: 1, max: 10000}},
+	{Name: "max_video_fps", Usage: "highest frame rate of a job", value: &intValue{p: &MAX_VIDEO_FPS, min: 1, max: 240}},
+	{Name: "max_video_width", Usage: "widest video of a job in pixels", value: &intValue{p: &MAX_VIDEO_WIDTH, min: 16, max: 16384}},
+	{Name: "max_video_height", Usage: "tallest video of a job in pixels", value: &intValue{p: &MAX_VIDEO_HEIGHT, min: 16, max: 16384}},
+	{Name: "max_audio_languages", Usage: "most audio tracks of a job", value: &intValue{p: &MAX_AUDIO_LANGUAGES, min: 1, max: 64}},
+	{Name: "max_dvr_window_seconds", Usage: "deepest timeshift window of a job", value: &intValue{p: &MAX_DVR_WINDOW_SECONDS, min: 1, max: 7 * 24 * 3600}},
+	{Name: "video_codecs", Usage: "video codecs jobs can use, of " + strings.Join(SupportedVideoCodecs, ", "), value: &listValue{p: &VALID_VIDEO_CODECS, allowed: SupportedVideoCodecs}},
+	{Name: "audio_codecs", Usage: "audio codecs jobs can use, of " + strings.Join(SupportedAudioCodecs, ", "), value: &listValue{p: &VALID_AUDIO_CODECS, allowed: SupportedAudioCodecs}},
+	{Name: "default_video_codec", Usage: "video codec of jobs that set none, one of video_codecs", value: &choiceValue{p: &DEFAULT_VIDEO_CODEC, choices: SupportedVideoCodecs}},
+	{Name: "default_audio_codec", Usage: "audio codec of jobs that set none, one of audio_codecs", value: &choiceValue{p: &DEFAULT_AUDIO_CODEC, choices: SupportedAudioCodecs}},
+	{Name: "webhook_urls", Usage: "webhooks receiving the events of every job, comma separated", value: &listValue{p: &WEBHOOK_URLS, empty: true}},
+	{Name: "webhook_secret", Usage: "signs deliveries to the global webhooks", Secret: true, value: &stringValue{p: &WEBHOOK_SECRET, empty: true}},
+	{Name: "webhook_max_attempts", Usage: "deliveries of a webhook event before giving up", value: &intValue{p: &WEBHOOK_MAX_ATTEMPTS, min: 1, max: 20}},
+	{Name: "retention_ttl", Usage: "delete completed and failed jobs and earlier runs this long after they stopped, 0 keeps them", value: &durationValue{p: &RETENTION_TTL}},
+	{Name: "max_media_size", Usage: "delete the oldest completed jobs and earlier runs while the media directory is larger, e.g. 50G, 0 for no limit", value: &sizeValue{p: &MAX_MEDIA_BYTES}},
+	{Name: "retention_interval", Usage: "time between checks of the retention policies", value: &durationValue{p: &RETENTION_INTERVAL, min: time.Second}},
+	{Name: "resource_policy", Usage: "starting a job the host cannot sustain: reject, queue or off", value: &choiceValue{p: &RESOURCE_POLICY, choices: []string{ResourcePolicyReject, ResourcePolicyQueue, ResourcePolicyOff}}},
+	{Name: "min_free_disk", Usage: "free space starts leave in the media directory, e.g. 10G", value: &sizeValue{p: &MIN_FREE_DISK_BYTES}},
+	{Name: "resource_check_interval", Usage: "time between checks of the disk and the queue", value: &durationValue{p: &RESOURCE_CHECK_INTERVAL, min: time.Second}},
+	{Name: "cgroup_root", Usage: "delegated cgroup v2 directory enforcing the CPU and memory limits of jobs", value: &stringValue{p: &CGROUP_ROOT, empty: true}},
+	{Name: "stop_timeout", Usage: "time ffmpeg gets to finalize its output before it is killed", value: &durationValue{p: &STOP_TIMEOUT, min: 100 * time.Millisecond}},
+	{Name: "public_url", Usage: "base URL of the playback URLs, derived from each request if empty", value: &urlValue{p: &PUBLIC_URL}},
+	{Name: "cdn_hosts", Usage: "hosts serving the media besides the public URL, comma separated", value: &listValue{p: &CDN_HOSTS, empty: true, check: checkHost}},
+}
+
+// configFile is the file the configuration was loaded from, if any.
+var configFile string
+
+func init() {
+	for _, s := range settings {
+		s.Source = SourceDefault
+	}
+}
+
+// Settings returns the settings with their effective values.
+func Settings() []Setting {
+	list := make([]Setting, len(settings))
+	for i, s := range settings {
+		list[i] = *s
+	}
+	return list
+}
+
+// File returns the configuration file that was loaded, empty if there was none.
+func File() string {
+	return configFile
+}
+
+// RegisterFlags adds --config and a flag per setting to a flag set, Load
+// applies the flags that were given.
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.String("config", "", "YAML configuration file, defaults to $GOLIVE_CONFIG")
+	for _, s := range settings {
+		fs.Var(&flagValue{s: s}, s.Flag(), s.Usage+" ($"+s.Env()+")")
+	}
+}
+
+// flagValue holds a flag until Load applies it after the file and the
+// environment.
+type flagValue struct {
+	s     *Setting
+	value *string
+}
+
+func (f *flagValue) String() string {
+	if f.s == nil {
+		return ""
+	}
+	return f.s.String()
+}
+
+func (f *flagValue) Set(v string) error {
+	f.value = &v
+	return nil
+}
+
+// Load sets the configuration from, in increasing precedence, the defaults,
+// a YAML file, GOLIVE_ environment variables and the flags of fs added by
+// RegisterFlags, fs may be nil. The file is given by --config or
+// GOLIVE_CONFIG. Invalid values are all reported in the returned error.
+func Load(fs *flag.FlagSet) error {
+	var errs []error
+	path := os.Getenv("GOLIVE_CONFIG")
+	if fs != nil {
+		if f := fs.Lookup("config"); f != nil && f.Value.String() != "" {
+			path = f.Value.String()
+		}
+	}
+	if path != "" {
+		if err := loadFile(path); err != nil {
+			errs = append(errs, err)
+		} else {
+			configFile = path
+		}
+	}
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.Env()); ok && v != "" {
+			errs = append(errs, set(s, v, SourceEnv))
+		}
+	}
+	if fs != nil {
+		fs.Visit(func(f *flag.Flag) {
+			if fv, ok := f.Value.(*flagValue); ok && fv.value != nil {
+				errs = append(errs, set(fv.s, *fv.value, SourceFlag))
+			}
+		})
+	}
+	// Jobs that set no codec must be valid
+	if !slices.Contains(VALID_VIDEO_CODECS, DEFAULT_VIDEO_CODEC) {
+		errs = append(errs, fmt.Errorf("default_video_codec %s is not one of video_codecs %s", DEFAULT_VIDEO_CODEC, strings.Join(VALID_VIDEO_CODECS, ",")))
+	}
+	if !slices.Contains(VALID_AUDIO_CODECS, DEFAULT_AUDIO_CODEC) {
+		errs = append(errs, fmt.Errorf("default_audio_codec %s is not one of audio_codecs %s", DEFAULT_AUDIO_CODEC, strings.Join(VALID_AUDIO_CODECS, ",")))
+	}
+	return errors.Join(errs...)
+}
+
+// loadFile applies a YAML file of settings by their names.
+func loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	var errs []error
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		i := slices.IndexFunc(settings, func(s *Setting) bool { return s.Name == key })
+		if i < 0 {
+			errs = append(errs, fmt.Errorf("config file %s: %s is not a setting", path, key))
+			continue
+		}
+		v, err := fileValue(doc[key])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %s %w", path, key, err))
+			continue
+		}
+		errs = append(errs, set(settings[i], v, SourceFile))
+	}
+	return errors.Join(errs...)
+}
+
+// fileValue formats a value of the file like the environment variables.
+func fileValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case int, float64, bool:
+		return fmt.Sprint(v), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", errors.New("must be a list of strings")
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", errors.New("must be a string, a number or a list")
+}
+
+func set(s *Setting, v, source string) error {
+	if err := s.value.Set(v); err != nil {
+		return fmt.Errorf("%s from %s: %w", s.Name, source, err)
+	}
+	s.Source = source
+	return nil
+}
+
+type intValue struct {
+	p        *int
+	min, max int
+}
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < v.min || n > v.max {
+		return fmt.Errorf("must be a whole number between %d and %d", v.min, v.max)
+	}
+	*v.p = n
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(*v.p) }
+func (v *intValue) Get() any       { return *v.p }
+
+type stringValue struct {
+	p     *string
+	empty bool // The value may be empty
+}
+
+func (v *stringValue) Set(s string) error {
+	if s == "" && !v.empty {
+		return errors.New("must not be empty")
+	}
+	*v.p = s
+	return nil
+}
+
+func (v *stringValue) String() string { return *v.p }
+func (v *stringValue) Get() any       { return *v.p }
+
+type choiceValue struct {
+	p       *string
+	choices []string
+}
+
+func (v *choiceValue) Set(s string) error {
+	if !slices.Contains(v.choices, s) {
+		return fmt.Errorf("must be one of %s", strings.Join(v.choices, ", "))
+	}
+	*v.p = s
+	return nil
+}
+
+func (v *choiceValue) String() string { return *v.p }
+func (v *choiceValue) Get() any       { return *v.p }
+
+// listValue is a comma separated list.
+type listValue struct {
+	p       *[]string
+	allowed []string           // Values the list may hold, any if empty
+	empty   bool               // The list may be empty
+	check   func(string) error // Validates each value, if set
+}
+
+func (v *listValue) Set(s string) error {
+	list := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		if len(v.allowed) > 0 && !slices.Contains(v.allowed, item) {
+			return fmt.Errorf("%q is not one of %s", item, strings.Join(v.allowed, ", "))
+		}
+		if v.check != nil {
+			if err := v.check(item); err != nil {
+				return fmt.Errorf("%q %w", item, err)
+			}
+		}
+		list = append(list, item)
+	}
+	if len(list) == 0 && !v.empty {
+		return errors.New("must not be empty")
+	}
+	*v.p = list
+	return nil
+}
+
+func (v *listValue) String() string { return strings.Join(*v.p, ",") }
+func (v *listValue) Get() any       { return slices.Clone(*v.p) }
+
+// checkHost accepts a host name with an optional port.
+func checkHost(s string) error {
+	u, err := url.Parse("http://" + s)
+	if err != nil || u.Host != s || u.Hostname() == "" {
+		return errors.New("must be a host name like cdn.example.com")
+	}
+	return nil
+}
+
+// urlValue is an absolute http or https URL, or empty. It is kept with a
+// trailing slash so paths resolve below it.
+type urlValue struct {
+	p *string
+}
+
+func (v *urlValue) Set(s string) error {
+	if s == "" {
+		*v.p = ""
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("must be an absolute http or https URL without a query")
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	*v.p = u.String()
+	return nil
+}
+
+func (v *urlValue) String() string { return *v.p }
+func (v *urlValue) Get() any       { return *v.p }
+
+type durationValue struct {
+	p   *time.Duration
+	min time.Duration
+}
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil || d < v.min {
+		if v.min > 0 {
+			return fmt.Errorf("must be a duration like 10s of at least %s", v.min)
+		}
+		return errors.New("must be a duration like 72h, 0 or more")
+	}
+	*v.p = d
+	return nil
+}
+
+func (v *durationValue) String() string { return v.p.String() }
+func (v *durationValue) Get() any       { return v.p.String() }
+
+// sizeValue is a number of bytes, see ParseSize.
+type sizeValue struct {
+	p *int64
+}
+
+func (v *sizeValue) Set(s string) error {
+	n, err := ParseSize(s)
+	if err != nil {
+		return err
+	}
+	*v.p = n
+	return nil
+}
+
+func (v *sizeValue) String() string { return strconv.FormatInt(*v.p, 10) }
+func (v *sizeValue) Get() any       { return *v.p }
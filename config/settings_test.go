@@ -0,0 +1,163 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// restoreSettings puts the settings back as they were when the test ends.
+func restoreSettings(t *testing.T) {
+	t.Helper()
+	values := make([]string, len(settings))
+	sources := make([]string, len(settings))
+	for i, s := range settings {
+		values[i], sources[i] = s.String(), s.Source
+	}
+	file := configFile
+	t.Cleanup(func() {
+		for i, s := range settings {
+			if err := s.value.Set(values[i]); err != nil {
+				t.Errorf("restoring %s: %v", s.Name, err)
+			}
+			s.Source = sources[i]
+		}
+		configFile = file
+	})
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "golive.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func setting(name string) *Setting {
+	for _, s := range settings {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestLoad(t *testing.T) {
+	file := writeConfig(t, "port: 9000\nmax_jobs: 4\nwindow_size: 8\nvideo_codecs: [h264, hevc]\nretention_ttl: 72h\n")
+	tests := []struct {
+		name        string
+		file        string
+		env         map[string]string
+		args        []string
+		want        map[string]string // Setting to its value
+		wantSources map[string]string
+		wantErr     []string // Substrings of the error
+	}{
+		{
+			name:        "Defaults",
+			want:        map[string]string{"port": "9090", "max_jobs": "0"},
+			wantSources: map[string]string{"port": SourceDefault},
+		},
+		{
+			name:        "File",
+			file:        file,
+			want:        map[string]string{"port": "9000", "max_jobs": "4", "video_codecs": "h264,hevc", "retention_ttl": "72h0m0s"},
+			wantSources: map[string]string{"port": SourceFile, "segment_length": SourceDefault},
+		},
+		{
+			name:        "Environment overrides the file",
+			file:        file,
+			env:         map[string]string{"GOLIVE_PORT": "9100", "GOLIVE_STOP_TIMEOUT": "30s"},
+			want:        map[string]string{"port": "9100", "max_jobs": "4", "stop_timeout": "30s"},
+			wantSources: map[string]string{"port": SourceEnv, "max_jobs": SourceFile},
+		},
+		{
+			name:        "Flags override the environment",
+			file:        file,
+			env:         map[string]string{"GOLIVE_PORT": "9100", "GOLIVE_MAX_JOBS": "6"},
+			args:        []string{"--port", "9200", "--window-size=12"},
+			want:        map[string]string{"port": "9200", "max_jobs": "6", "window_size": "12"},
+			wantSources: map[string]string{"port": SourceFlag, "max_jobs": SourceEnv, "window_size": SourceFlag},
+		},
+		{
+			name: "Config flag",
+			args: []string{"--config", file},
+			want: map[string]string{"port": "9000"},
+		},
+		{
+			name: "Public URL and CDN hosts",
+			env:  map[string]string{"GOLIVE_PUBLIC_URL": "https://streams.example.com/golive", "GOLIVE_CDN_HOSTS": "cdn1.example.com, cdn2.example.com:8443"},
+			want: map[string]string{"public_url": "https://streams.example.com/golive/", "cdn_hosts": "cdn1.example.com,cdn2.example.com:8443"},
+		},
+		{
+			name:    "Invalid public URL and CDN host",
+			env:     map[string]string{"GOLIVE_PUBLIC_URL": "streams.example.com", "GOLIVE_CDN_HOSTS": "https://cdn1.example.com"},
+			wantErr: []string{"public_url from env: must be an absolute http or https URL", `cdn_hosts from env: "https://cdn1.example.com" must be a host name`},
+		},
+		{
+			name:    "Invalid values are all reported",
+			env:     map[string]string{"GOLIVE_PORT": "http", "GOLIVE_RESOURCE_POLICY": "maybe"},
+			args:    []string{"--video-codecs", "h264,mpeg2"},
+			want:    map[string]string{"port": "9090"},
+			wantErr: []string{"port from env", "resource_policy from env: must be one of", `video_codecs from flag: "mpeg2"`},
+		},
+		{
+			name: "Default codecs",
+			env:  map[string]string{"GOLIVE_VIDEO_CODECS": "hevc,av1", "GOLIVE_DEFAULT_VIDEO_CODEC": "hevc"},
+			want: map[string]string{"default_video_codec": "hevc", "default_audio_codec": "aac"},
+		},
+		{
+			name:    "Codec list without the default codec",
+			env:     map[string]string{"GOLIVE_VIDEO_CODECS": "hevc,av1", "GOLIVE_AUDIO_CODECS": "mp3"},
+			wantErr: []string{"default_video_codec h264 is not one of video_codecs hevc,av1", "default_audio_codec aac is not one of audio_codecs mp3"},
+		},
+		{
+			name:    "Unknown key in the file",
+			file:    writeConfig(t, "prot: 9000\n"),
+			wantErr: []string{"prot is not a setting"},
+		},
+		{
+			name:    "Missing file",
+			file:    filepath.Join(t.TempDir(), "missing.yaml"),
+			wantErr: []string{"config file"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreSettings(t)
+			t.Setenv("GOLIVE_CONFIG", tt.file)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+			RegisterFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Load(fs)
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			for _, want := range tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %v, want it to contain %q", err, want)
+				}
+			}
+			for name, want := range tt.want {
+				if got := setting(name).String(); got != want {
+					t.Errorf("%s = %s, want %s", name, got, want)
+				}
+			}
+			for name, want := range tt.wantSources {
+				if got := setting(name).Source; got != want {
+					t.Errorf("%s source = %s, want %s", name, got, want)
+				}
+			}
+		})
+	}
+}
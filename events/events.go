@@ -0,0 +1,89 @@
+// Package events fans job events out to subscribers such as the SSE endpoints.
+package events
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// bufferSize is the number of events a subscriber may lag behind before
+// further events are dropped for it.
+const bufferSize = 256
+
+// Subscription receives the events of one or all jobs.
+type Subscription struct {
+	jobID string
+	c     chan models.JobEvent
+	once  sync.Once
+}
+
+var (
+	mu          sync.Mutex
+	lastID      uint64
+	subscribers = make(map[*Subscription]struct{})
+)
+
+// Subscribe returns a subscription to the events of a job, or of all jobs
+// when jobID is empty. Close it when done.
+func Subscribe(jobID string) *Subscription {
+	s := &Subscription{jobID: jobID, c: make(chan models.JobEvent, bufferSize)}
+	mu.Lock()
+	defer mu.Unlock()
+	subscribers[s] = struct{}{}
+	return s
+}
+
+// C returns the channel events are delivered on. It is closed when the
+// subscription or the broker is closed.
+func (s *Subscription) C() <-chan models.JobEvent {
+	return s.c
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	s.close()
+}
+
+// close must be called with mu held.
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		delete(subscribers, s)
+		close(s.c)
+	})
+}
+
+// Publish stamps an event and delivers it to the interested subscribers
+// without blocking. Subscribers that fall behind miss events.
+func Publish(ev models.JobEvent) {
+	mu.Lock()
+	defer mu.Unlock()
+	lastID++
+	ev.ID = lastID
+	if ev.Timestamp == "" {
+		ev.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	for s := range subscribers {
+		if s.jobID != "" && s.jobID != ev.JobID {
+			continue
+		}
+		select {
+		case s.c <- ev:
+		default:
+			slog.Warn("Dropping event for slow subscriber", "jobID", ev.JobID, "type", ev.Type)
+		}
+	}
+}
+
+// CloseAll ends every subscription, e.g. when the server shuts down.
+func CloseAll() {
+	mu.Lock()
+	defer mu.Unlock()
+	for s := range subscribers {
+		s.close()
+	}
+}
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+func TestPublish(t *testing.T) {
+	all := Subscribe("")
+	defer all.Close()
+	one := Subscribe("job-1")
+	defer one.Close()
+
+	Publish(models.JobEvent{Type: models.JobEventState, JobID: "job-1"})
+	Publish(models.JobEvent{Type: models.JobEventSegment, JobID: "job-2"})
+
+	first, second := <-all.C(), <-all.C()
+	if first.JobID != "job-1" || second.JobID != "job-2" {
+		t.Errorf("all jobs subscription got %v, %v, want job-1, job-2", first.JobID, second.JobID)
+	}
+	if second.ID <= first.ID || first.Timestamp == "" {
+		t.Errorf("events are not stamped: %+v, %+v", first, second)
+	}
+	if ev := <-one.C(); ev.JobID != "job-1" {
+		t.Errorf("job subscription got %v, want job-1", ev.JobID)
+	}
+	select {
+	case ev := <-one.C():
+		t.Errorf("job subscription got an event of %v", ev.JobID)
+	default:
+	}
+}
+
+func TestPublish_SlowSubscriber(t *testing.T) {
+	s := Subscribe("job-1")
+	defer s.Close()
+	for i := 0; i < bufferSize+10; i++ {
+		Publish(models.JobEvent{Type: models.JobEventStats, JobID: "job-1"}) // Must not block
+	}
+	if len(s.C()) != bufferSize {
+		t.Errorf("buffered events = %d, want %d", len(s.C()), bufferSize)
+	}
+}
+
+func TestCloseAll(t *testing.T) {
+	s := Subscribe("")
+	CloseAll()
+	if _, ok := <-s.C(); ok {
+		t.Errorf("subscription is still open after CloseAll()")
+	}
+	s.Close() // Closing twice is fine
+	Publish(models.JobEvent{Type: models.JobEventState, JobID: "job-1"})
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/internal/api/postprocessor"
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// redacted replaces the value of secret settings that are set
+const redacted = "********"
+
+func getConfigHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := models.ConfigResponse{File: config.File(), Settings: []models.ConfigSetting{}}
+		for _, s := range config.Settings() {
+			value := s.Value()
+			if s.Secret && s.String() != "" {
+				value = redacted
+			}
+			response.Settings = append(response.Settings, models.ConfigSetting{
+				Name:   s.Name,
+				Value:  value,
+				Source: s.Source,
+				Env:    s.Env(),
+				Flag:   "--" + s.Flag(),
+			})
+		}
+		postprocessor.FormatResponse(w, response, http.StatusOK)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+func TestGetConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string
+		wantSecret any
+	}{
+		{name: "Secret unset", secret: "", wantSecret: ""},
+		{name: "Secret is redacted", secret: "s3cret", wantSecret: redacted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := config.WEBHOOK_SECRET
+			config.WEBHOOK_SECRET = tt.secret
+			t.Cleanup(func() { config.WEBHOOK_SECRET = saved })
+
+			rec := httptest.NewRecorder()
+			getConfigHandler()(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			var response models.ConfigResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			settings := make(map[string]models.ConfigSetting)
+			for _, s := range response.Settings {
+				settings[s.Name] = s
+			}
+			if got := settings["webhook_secret"].Value; got != tt.wantSecret {
+				t.Errorf("webhook_secret = %v, want %v", got, tt.wantSecret)
+			}
+			port := settings["port"]
+			if port.Value != float64(config.DEFAULT_SERVER_PORT) || port.Env != "GOLIVE_PORT" || port.Flag != "--port" || port.Source == "" {
+				t.Errorf("port = %+v", port)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// decodeError describes a request body that could not be decoded, pointing
+// at the field when the JSON was valid.
+func decodeError(err error) (string, []models.FieldError) {
+	var (
+		typeErr   *json.UnmarshalTypeError
+		syntaxErr *json.SyntaxError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return "The request body is empty", nil
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return "The request body has a field of the wrong type", []models.FieldError{{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("%s must be a %s, not a %s", typeErr.Field, jsonType(typeErr.Type.Kind().String()), jsonType(typeErr.Value)),
+		}}
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("The request body is not valid JSON at offset %d: %v", syntaxErr.Offset, err), nil
+	}
+	return fmt.Sprintf("The request body could not be decoded: %v", err), nil
+}
+
+// jsonType names a Go kind the way JSON does.
+func jsonType(kind string) string {
+	switch kind {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+		return "number"
+	case "bool":
+		return "boolean"
+	case "slice", "array":
+		return "array"
+	case "struct", "map", "ptr":
+		return "object"
+	}
+	return kind
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+func TestDecodeError(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantMessage string
+		wantDetails []models.FieldError
+	}{
+		{name: "Empty body", body: "", wantMessage: "The request body is empty"},
+		{name: "Invalid JSON", body: `{"description": }`, wantMessage: "The request body is not valid JSON at offset 17"},
+		{
+			name:        "Wrong type",
+			body:        `{"segment_length": "6"}`,
+			wantMessage: "The request body has a field of the wrong type",
+			wantDetails: []models.FieldError{{Field: "segment_length", Message: "segment_length must be a number, not a string"}},
+		},
+		{
+			name:        "Wrong type in a nested object",
+			body:        `{"event": {"live_seconds": true}}`,
+			wantMessage: "The request body has a field of the wrong type",
+			wantDetails: []models.FieldError{{Field: "event.live_seconds", Message: "event.live_seconds must be a number, not a boolean"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request models.JobCreateRequest
+			err := json.NewDecoder(strings.NewReader(tt.body)).Decode(&request)
+			if err == nil {
+				t.Fatal("Decode() succeeded")
+			}
+			message, details := decodeError(err)
+			if !strings.HasPrefix(message, tt.wantMessage) {
+				t.Errorf("message = %q, want prefix %q", message, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(details, tt.wantDetails) {
+				t.Errorf("details = %+v, want %+v", details, tt.wantDetails)
+			}
+		})
+	}
+}
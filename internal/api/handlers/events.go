@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/events"
+	"github.com/arunjeyaprasad/golive/internal/api/middleware"
+	"github.com/arunjeyaprasad/golive/internal/api/postprocessor"
+	"github.com/arunjeyaprasad/golive/jobs"
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// keepAliveInterval is how often a comment is sent on idle event streams, so
+// proxies do not close them.
+const keepAliveInterval = 15 * time.Second
+
+func getEventsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveEvents(w, r, "", jobs.GetJobs())
+	}
+}
+
+func getJobEventsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobid := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["job_id"]
+		job, ok := jobs.GetJob(jobid)
+		if !ok {
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Job not found")
+			return
+		}
+		serveEvents(w, r, jobid, []models.Job{*job})
+	}
+}
+
+// serveEvents streams job events as Server-Sent Events, starting with a
+// snapshot of the state of the given jobs.
+func serveEvents(w http.ResponseWriter, r *http.Request, jobID string, snapshot []models.Job) {
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("Failed to clear the write deadline of an event stream", "error", err)
+	}
+	sub := events.Subscribe(jobID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, job := range snapshot {
+		writeEvent(w, models.JobEvent{
+			Type:      models.JobEventState,
+			JobID:     job.ID,
+			Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+			State:     &models.JobState{Status: job.Status, Phase: job.Phase},
+		})
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C():
+			if !ok {
+				return // Server shutting down
+			}
+			writeEvent(w, ev)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes a single SSE message, named after the event type so
+// clients can listen for the types they need.
+func writeEvent(w http.ResponseWriter, ev models.JobEvent) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		slog.Error("Failed to encode event", "error", err)
+		return
+	}
+	if ev.ID > 0 {
+		fmt.Fprintf(w, "id: %d\n", ev.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+}
@@ -1,32 +1,64 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/arunjeyaprasad/golive/internal/api/middleware"
 	"github.com/arunjeyaprasad/golive/internal/api/postprocessor"
 	"github.com/arunjeyaprasad/golive/jobs"
 	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/resources"
+	"github.com/arunjeyaprasad/golive/templates"
 )
 
 func createJobHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		var (
+			body    json.RawMessage
+			fields  map[string]json.RawMessage
 			request models.JobCreateRequest
 			job     *models.Job
+			err     error
 		)
-		// Decode the request body into the job struct
-		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-			http.Error(w, "Invalid request payload; Missing Description in Body", http.StatusBadRequest)
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			message, details := decodeError(err)
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeInvalidRequest, message, details...)
+			return
+		}
+		// The body is either a create request or a template with overrides
+		if json.Unmarshal(body, &fields) == nil && fields["template"] != nil {
+			request, err = templateRequest(body)
+		} else {
+			err = json.Unmarshal(body, &request)
+		}
+		var fieldErr *models.FieldError
+		switch {
+		case errors.As(err, &fieldErr):
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeValidation,
+				"The job configuration is invalid", models.FieldErrors(err)...)
+			return
+		case errors.Is(err, errTemplateUnavailable):
+			slog.Error("Failed to load template", "error", err)
+			postprocessor.FormatError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to load the template")
+			return
+		case err != nil:
+			message, details := decodeError(err)
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeInvalidRequest, message, details...)
 			return
 		}
 		// Validate the request
 		if err := request.Validate(); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeValidation,
+				"The job configuration is invalid", models.FieldErrors(err)...)
 			return
 		}
 		job = jobs.CreateJob(request)
@@ -34,10 +66,77 @@ func createJobHandler() http.HandlerFunc {
 	}
 }
 
+// errTemplateUnavailable is returned for a template that could not be read
+var errTemplateUnavailable = errors.New("template unavailable")
+
+// templateRequest returns the create request of a template with the
+// overrides of the body merged in.
+func templateRequest(body json.RawMessage) (models.JobCreateRequest, error) {
+	var fromTemplate models.JobTemplateRequest
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields() // Settings belong in the overrides
+	if err := decoder.Decode(&fromTemplate); err != nil {
+		return models.JobCreateRequest{}, err
+	}
+	t, err := templates.Get(fromTemplate.Template)
+	if errors.Is(err, templates.ErrNotFound) {
+		return models.JobCreateRequest{}, &models.FieldError{Field: "template", Message: fmt.Sprintf("template %q does not exist", fromTemplate.Template)}
+	} else if err != nil {
+		return models.JobCreateRequest{}, fmt.Errorf("%w: %w", errTemplateUnavailable, err)
+	}
+	request, err := t.Request.Merge(fromTemplate.Overrides)
+	if err != nil {
+		return models.JobCreateRequest{}, err
+	}
+	return request, nil
+}
+
 func getJobsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		jobsList := jobs.GetJobs()
-		postprocessor.FormatResponse(w, jobsList, http.StatusOK)
+		params := r.Context().Value(middleware.QueryParamsKey).(map[string][]string)
+		query, err := models.ParseJobListQuery(params)
+		if err != nil {
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeValidation,
+				"The job list query is invalid", models.FieldErrors(err)...)
+			return
+		}
+		list := jobs.ListJobs(query)
+		for i, job := range list.Jobs {
+			list.Jobs[i] = withPlaybackURLs(r, job)
+		}
+		postprocessor.FormatResponse(w, list, http.StatusOK)
+	}
+}
+
+// bulkJobsHandler applies an operation to the jobs matching the filters of
+// the query, which must have some.
+func bulkJobsHandler(apply func(models.JobListQuery) models.BulkJobResponse) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.Context().Value(middleware.QueryParamsKey).(map[string][]string)
+		query, err := models.ParseJobListQuery(params)
+		if err != nil {
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeValidation,
+				"The job list query is invalid", models.FieldErrors(err)...)
+			return
+		}
+		if !query.Filtered() {
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeInvalidRequest,
+				"A filter is required to select the jobs, e.g. ?label=team=player-web")
+			return
+		}
+		postprocessor.FormatResponse(w, apply(query), http.StatusOK)
+	}
+}
+
+// getAllJobsHandler lists every job without an envelope, as the routes
+// predating /v1 did.
+func getAllJobsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list := jobs.ListJobs(models.JobListQuery{Limit: math.MaxInt})
+		for i, job := range list.Jobs {
+			list.Jobs[i] = withPlaybackURLs(r, job)
+		}
+		postprocessor.FormatResponse(w, list.Jobs, http.StatusOK)
 	}
 }
 
@@ -46,11 +145,57 @@ func getJobHandler() http.HandlerFunc {
 		// Get the job id from the context route params
 		jobid := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["job_id"]
 		if job, ok := jobs.GetJob(jobid); !ok {
-			http.Error(w, "Job not found", http.StatusNotFound)
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Job not found")
 			return
 		} else {
-			postprocessor.FormatResponse(w, job, http.StatusOK)
+			postprocessor.FormatResponse(w, withPlaybackURLs(r, *job), http.StatusOK)
+		}
+	}
+}
+
+func getJobHealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobid := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["job_id"]
+		if health, ok := jobs.GetJobHealth(jobid); !ok {
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Job not found")
+			return
+		} else {
+			postprocessor.FormatResponse(w, health, http.StatusOK)
+		}
+	}
+}
+
+func updateJobHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobid := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["job_id"]
+		// The body is a JSON merge patch of the create request
+		var patch map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			message, details := decodeError(err)
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeInvalidRequest, message, details...)
+			return
+		}
+		update, err := jobs.UpdateJob(jobid, patch)
+		var typeErr *json.UnmarshalTypeError
+		var invalid *jobs.InvalidUpdateError
+		if errors.Is(err, jobs.ErrJobNotFound) {
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Job not found")
+			return
+		} else if errors.As(err, &typeErr) {
+			message, details := decodeError(err)
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeInvalidRequest, message, details...)
+			return
+		} else if errors.As(err, &invalid) {
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeValidation,
+				"The job configuration is invalid", models.FieldErrors(invalid.Err)...)
+			return
+		} else if err != nil {
+			slog.Error("Failed to update job", "job_id", jobid, "error", err)
+			postprocessor.FormatError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to restart job")
+			return
 		}
+		update.Job = withPlaybackURLs(r, update.Job)
+		postprocessor.FormatResponse(w, update, http.StatusOK)
 	}
 }
 
@@ -62,12 +207,26 @@ func startJobHandler() http.HandlerFunc {
 			ok  bool
 		)
 		if job, ok = jobs.GetJob(jobid); !ok {
-			http.Error(w, "Job not found", http.StatusNotFound)
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Job not found")
 			return
 		}
-		if err := jobs.StartJob(job); err != nil {
+		if job.Status == string(jobs.JobStatusQueued) {
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeInvalidState, "Job is already queued")
+			return
+		}
+		var insufficient *resources.InsufficientError
+		if err := jobs.StartJob(job); errors.Is(err, jobs.ErrJobQueued) {
+			postprocessor.FormatResponse(w, models.JobResponse{ID: jobid}, http.StatusAccepted)
+			return
+		} else if errors.Is(err, jobs.ErrJobRunning) {
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeInvalidState, "Job is already running")
+			return
+		} else if errors.As(err, &insufficient) {
+			postprocessor.FormatError(w, http.StatusServiceUnavailable, models.ErrorCodeInsufficientResources, insufficient.Error())
+			return
+		} else if err != nil {
 			slog.Error("Failed to start job", "job_id", jobid, "error", err)
-			http.Error(w, "Failed to start job", http.StatusInternalServerError)
+			postprocessor.FormatError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to start job")
 			return
 		}
 
@@ -75,17 +234,58 @@ func startJobHandler() http.HandlerFunc {
 	}
 }
 
+func cloneJobHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobid := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["job_id"]
+		if job, ok := jobs.CloneJob(jobid); !ok {
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Job not found")
+			return
+		} else {
+			postprocessor.FormatResponse(w, job, http.StatusCreated)
+		}
+	}
+}
+
+func getRunsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobid := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["job_id"]
+		if runs, ok := jobs.GetRuns(jobid); !ok {
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Job not found")
+			return
+		} else {
+			postprocessor.FormatResponse(w, runsWithPlaybackURLs(r, runs), http.StatusOK)
+		}
+	}
+}
+
+func getRunHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.Context().Value(middleware.RouteParamsKey).(map[string]string)
+		runs, ok := jobs.GetRuns(params["job_id"])
+		if !ok {
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Job not found")
+			return
+		}
+		run, _ := strconv.Atoi(params["run"])
+		if run < 1 || run > len(runs) {
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Run not found")
+			return
+		}
+		postprocessor.FormatResponse(w, runsWithPlaybackURLs(r, runs)[run-1], http.StatusOK)
+	}
+}
+
 func stopJobHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		jobid := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["job_id"]
 		// Check if the job exists
 		if _, ok := jobs.GetJob(jobid); !ok {
-			http.Error(w, "Job not found", http.StatusNotFound)
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Job not found")
 			return
 		}
 		if err := jobs.StopJob(jobid); err != nil {
 			slog.Error("Failed to stop job", "job_id", jobid, "error", err)
-			http.Error(w, "Failed to stop job", http.StatusInternalServerError)
+			postprocessor.FormatError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to stop job")
 			return
 		}
 		postprocessor.FormatResponse(w, models.JobResponse{ID: jobid}, http.StatusOK)
@@ -100,11 +300,11 @@ func cleanUpJobHandler() http.HandlerFunc {
 			ok  bool
 		)
 		if job, ok = jobs.GetJob(jobid); !ok {
-			http.Error(w, "Job not found", http.StatusNotFound)
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Job not found")
 			return
 		}
-		if job.Status != string(jobs.JobStatusCompleted) {
-			http.Error(w, "Job is not completed", http.StatusBadRequest)
+		if job.Status != string(jobs.JobStatusCompleted) && job.Status != string(jobs.JobStatusFailed) {
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeInvalidState, "Job is not completed")
 			return
 		}
 		jobs.DeleteJob(jobid)
@@ -1,44 +1,102 @@
 package handlers
 
 import (
+	"log/slog"
 	"net/http"
+	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 
-	"github.com/arunjeyaprasad/golive/config"
 	"github.com/arunjeyaprasad/golive/internal/api/middleware"
+	"github.com/arunjeyaprasad/golive/internal/api/postprocessor"
 	"github.com/arunjeyaprasad/golive/jobs"
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/streamer"
+	"github.com/arunjeyaprasad/golive/transform"
 )
 
 func getMediaHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		jobid := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["job_id"]
-		file := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["file"]
+		params := r.Context().Value(middleware.RouteParamsKey).(map[string]string)
+		jobid, file := params["job_id"], params["file"]
 
-		if _, ok := jobs.GetJob(jobid); !ok {
-			http.Error(w, "Job not found", http.StatusNotFound)
+		job, ok := jobs.GetJob(jobid)
+		if !ok {
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Job not found")
+			return
+		}
+
+		// Files of a run, or of the latest run of the job
+		run := job.Run
+		if params["run"] != "" {
+			run, _ = strconv.Atoi(params["run"])
+		}
+		if run < 1 || run > job.Run {
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Run not found")
 			return
 		}
 
 		// Check if file exists
-		fileName := filepath.Join(config.DEFAULT_MEDIA_DIR, jobid, file)
+		fileName := filepath.Join(streamer.RunDir(jobid, run), file)
 		if !FileExists(fileName) {
-			http.Error(w, "File not found", http.StatusNotFound)
+			postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "File not found")
 			return
 		}
 
+		isManifest := false
 		if strings.HasSuffix(fileName, ".mpd") {
 			// For DASH, we need to return the MPD file
 			w.Header().Set("Content-Type", "application/dash+xml")
+			isManifest = true
 		} else if strings.HasSuffix(fileName, ".m3u8") {
 			// For HLS, we need to return the M3U8 file
 			w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+			isManifest = true
 		} else {
 			// For other media files, set the appropriate content type
 			w.Header().Set("Content-Type", "application/octet-stream")
 		}
+
+		// Manifests of jobs with transforms are rewritten on every request,
+		// those of the job continue the earlier runs after a restart
+		startOver := r.URL.Query().Get("startover") == "1"
+		var continuation *transform.Continuation
+		if params["run"] == "" {
+			continuation, _ = jobs.GetContinuation(jobid, run)
+		}
+		if isManifest && (job.Configuration.Transforms != nil || startOver || continuation != nil) {
+			data, err := os.ReadFile(fileName)
+			if err != nil {
+				slog.Error("Failed to read manifest", "file", fileName, "error", err)
+				postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "File not found")
+				return
+			}
+			opts := transform.Options{
+				BaseURL:      jobBaseURL(r, jobid, file),
+				StartOver:    startOver,
+				Continuation: continuation,
+			}
+			data, err = transform.Manifest(fileName, data, job.Configuration.Transforms, opts)
+			if err != nil {
+				slog.Error("Failed to transform manifest", "file", fileName, "error", err)
+				postprocessor.FormatError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to transform manifest")
+				return
+			}
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Write(data)
+			return
+		}
 		// w.WriteHeader(http.StatusOK)
 		// Simulate sending the file content
 		http.ServeFile(w, r, fileName)
 	}
 }
+
+// jobBaseURL returns the absolute URL of the directory a media file is served from.
+func jobBaseURL(r *http.Request, jobid, file string) string {
+	// Keep the path the file was requested under, e.g. v1/jobs/<id>/
+	dir := strings.TrimPrefix(strings.TrimSuffix(r.URL.Path, path.Base(file)), "/")
+	return publicBaseURL(r) + dir
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"embed"
+	"net/http"
+)
+
+// The OpenAPI document is written by hand, TestOpenAPI keeps it in line with
+// the routes and the models.
+//
+//go:embed openapi/openapi.json openapi/docs.html
+var openAPIFiles embed.FS
+
+func getOpenAPIHandler() http.HandlerFunc {
+	return serveEmbedded("openapi/openapi.json", "application/json")
+}
+
+func getDocsHandler() http.HandlerFunc {
+	return serveEmbedded("openapi/docs.html", "text/html; charset=utf-8")
+}
+
+func serveEmbedded(name, contentType string) http.HandlerFunc {
+	data, err := openAPIFiles.ReadFile(name)
+	if err != nil {
+		panic(err) // The file is embedded at build time
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(data)
+	}
+}
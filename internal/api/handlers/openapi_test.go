@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/gorilla/mux"
+)
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref"`
+	Type       string                    `json:"type"`
+	Items      *openAPISchema            `json:"items"`
+	Properties map[string]*openAPISchema `json:"properties"`
+	Required   []string                  `json:"required"`
+	Enum       []string                  `json:"enum"`
+}
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]*openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+// openAPITypes are the models behind the schemas of the document.
+var openAPITypes = map[string]reflect.Type{
+	"Job":                reflect.TypeOf(models.Job{}),
+	"Run":                reflect.TypeOf(models.Run{}),
+	"JobList":            reflect.TypeOf(models.JobList{}),
+	"BulkJobResponse":    reflect.TypeOf(models.BulkJobResponse{}),
+	"SkippedJob":         reflect.TypeOf(models.SkippedJob{}),
+	"JobCreateRequest":   reflect.TypeOf(models.JobCreateRequest{}),
+	"JobTemplateRequest": reflect.TypeOf(models.JobTemplateRequest{}),
+	"Template":           reflect.TypeOf(models.Template{}),
+	"VideoTrack":         reflect.TypeOf(models.VideoTrack{}),
+	"AudioTrack":         reflect.TypeOf(models.AudioTrack{}),
+	"AudioConfig":        reflect.TypeOf(models.AudioConfig{}),
+	"JobLimits":          reflect.TypeOf(models.JobLimits{}),
+	"ManifestTransforms": reflect.TypeOf(models.ManifestTransforms{}),
+	"EventConfig":        reflect.TypeOf(models.EventConfig{}),
+	"Webhook":            reflect.TypeOf(models.Webhook{}),
+	"PlaybackURL":        reflect.TypeOf(models.PlaybackURLs{}),
+	"ScheduledRun":       reflect.TypeOf(models.ScheduledRun{}),
+	"JobResponse":        reflect.TypeOf(models.JobResponse{}),
+	"JobUpdateResponse":  reflect.TypeOf(models.JobUpdateResponse{}),
+	"FieldUpdate":        reflect.TypeOf(models.FieldUpdate{}),
+	"JobHealth":          reflect.TypeOf(models.JobHealth{}),
+	"HealthIssue":        reflect.TypeOf(models.HealthIssue{}),
+	"JobEvent":           reflect.TypeOf(models.JobEvent{}),
+	"JobState":           reflect.TypeOf(models.JobState{}),
+	"SegmentInfo":        reflect.TypeOf(models.SegmentInfo{}),
+	"EncoderStats":       reflect.TypeOf(models.EncoderStats{}),
+	"WebhookPayload":     reflect.TypeOf(models.WebhookPayload{}),
+	"ErrorResponse":      reflect.TypeOf(models.ErrorResponse{}),
+	"FieldError":         reflect.TypeOf(models.FieldError{}),
+	"ConfigResponse":     reflect.TypeOf(models.ConfigResponse{}),
+	"ConfigSetting":      reflect.TypeOf(models.ConfigSetting{}),
+}
+
+func loadOpenAPI(t *testing.T) (*openAPIDocument, []byte) {
+	t.Helper()
+	data, err := openAPIFiles.ReadFile("openapi/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc openAPIDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid: %v", err)
+	}
+	return &doc, data
+}
+
+func TestOpenAPI_Routes(t *testing.T) {
+	doc, _ := loadOpenAPI(t)
+	router := mux.NewRouter()
+	RegisterRoutes(router)
+
+	// Path variables are documented without their patterns
+	pattern := regexp.MustCompile(`\{(\w+):[^}]*\}`)
+	var routes []string
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			routes = append(routes, strings.ToLower(method)+" "+pattern.ReplaceAllString(path, "{$1}"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var documented []string
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method != "parameters" {
+				documented = append(documented, method+" "+path)
+			}
+		}
+	}
+	sort.Strings(routes)
+	sort.Strings(documented)
+	if !reflect.DeepEqual(routes, documented) {
+		t.Errorf("documented operations = %v\nwant the routes %v", documented, routes)
+	}
+}
+
+func TestOpenAPI_Schemas(t *testing.T) {
+	doc, data := loadOpenAPI(t)
+	for name := range doc.Components.Schemas {
+		if _, ok := openAPITypes[name]; !ok {
+			t.Errorf("schema %s has no model in openAPITypes", name)
+		}
+	}
+	for name, typ := range openAPITypes {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s is missing", name)
+			continue
+		}
+		fields := jsonFields(typ)
+		for prop := range schema.Properties {
+			if _, ok := fields[prop]; !ok {
+				t.Errorf("%s.%s is not a field of %s", name, prop, typ)
+			}
+		}
+		for field, fieldType := range fields {
+			prop, ok := schema.Properties[field]
+			if !ok {
+				t.Errorf("%s.%s is not documented", name, field)
+				continue
+			}
+			checkSchemaType(t, name+"."+field, prop, fieldType)
+		}
+		for _, field := range schema.Required {
+			if _, ok := fields[field]; !ok {
+				t.Errorf("%s requires unknown field %s", name, field)
+			}
+		}
+	}
+
+	// Every reference resolves
+	for _, m := range regexp.MustCompile(`"\$ref": "#/components/(\w+)/(\w+)"`).FindAllStringSubmatch(string(data), -1) {
+		if m[1] == "schemas" && doc.Components.Schemas[m[2]] == nil {
+			t.Errorf("reference to unknown schema %s", m[2])
+		}
+	}
+}
+
+func TestOpenAPI_Enums(t *testing.T) {
+	doc, _ := loadOpenAPI(t)
+	schemas := doc.Components.Schemas
+	var webhookEvents []string
+	for _, event := range models.WebhookEvents {
+		webhookEvents = append(webhookEvents, string(event))
+	}
+	tests := []struct {
+		name string
+		enum []string
+		want []string
+	}{
+		{name: "Video codecs", enum: schemas["VideoTrack"].Properties["codec"].Enum, want: config.VALID_VIDEO_CODECS},
+		{name: "Audio codecs", enum: schemas["AudioTrack"].Properties["codec"].Enum, want: config.VALID_AUDIO_CODECS},
+		{name: "Webhook events", enum: schemas["Webhook"].Properties["events"].Items.Enum, want: webhookEvents},
+		{name: "Webhook payload events", enum: schemas["WebhookPayload"].Properties["event"].Enum, want: webhookEvents},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !slices.Equal(tt.enum, tt.want) {
+				t.Errorf("enum = %v, want %v", tt.enum, tt.want)
+			}
+		})
+	}
+}
+
+// jsonFields returns the fields of a struct as encoding/json sees them.
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get("json")
+		if f.Anonymous && tag == "" {
+			for name, t := range jsonFields(f.Type) {
+				fields[name] = t
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// checkSchemaType checks that a schema describes values of a Go type.
+func checkSchemaType(t *testing.T, where string, schema *openAPISchema, typ reflect.Type) {
+	t.Helper()
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if schema.Ref != "" {
+		name := schema.Ref[strings.LastIndex(schema.Ref, "/")+1:]
+		if openAPITypes[name] != typ {
+			t.Errorf("%s refers to %s, want the schema of %s", where, name, typ)
+		}
+		return
+	}
+	var ok bool
+	switch schema.Type {
+	case "string":
+		ok = typ.Kind() == reflect.String
+	case "integer":
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			ok = true
+		}
+	case "number":
+		ok = typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64
+	case "boolean":
+		ok = typ.Kind() == reflect.Bool
+	case "":
+		ok = typ.Kind() == reflect.Interface // Any JSON value
+	case "array":
+		ok = typ.Kind() == reflect.Slice && schema.Items != nil
+		if ok {
+			checkSchemaType(t, where+"[]", schema.Items, typ.Elem())
+		}
+	case "object":
+		ok = typ.Kind() == reflect.Struct || typ.Kind() == reflect.Map
+	}
+	if !ok {
+		t.Errorf("%s is documented as %q, but is a %s", where, schema.Type, typ)
+	}
+}
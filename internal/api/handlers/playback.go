@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/streamer"
+)
+
+// publicBaseURL returns the URL clients reach the server at, ending in a
+// slash. It is the configured public URL, or the URL the request was sent to
+// as the proxies it passed through report it.
+func publicBaseURL(r *http.Request) string {
+	if config.PUBLIC_URL != "" {
+		return config.PUBLIC_URL
+	}
+	u := url.URL{Scheme: "http", Host: r.Host, Path: "/"}
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	if proto := forwardedHeader(r, "X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		u.Scheme = proto
+	}
+	if host := forwardedHeader(r, "X-Forwarded-Host"); host != "" && !strings.ContainsAny(host, "/?#@\\ ") {
+		u.Host = host
+	}
+	if prefix := path.Join("/", forwardedHeader(r, "X-Forwarded-Prefix")); prefix != "/" {
+		u.Path = prefix + "/"
+	}
+	return u.String()
+}
+
+// forwardedHeader returns the value a proxy header has for the client, the
+// first one when proxies were chained.
+func forwardedHeader(r *http.Request, name string) string {
+	value, _, _ := strings.Cut(r.Header.Get(name), ",")
+	return strings.TrimSpace(value)
+}
+
+// withPlaybackURLs returns a job with absolute playback URLs for the client of
+// a request.
+func withPlaybackURLs(r *http.Request, job models.Job) models.Job {
+	job.PlaybackURLs = streamer.ResolvePlaybackURLs(job.PlaybackURLs, publicBaseURL(r))
+	return job
+}
+
+// runsWithPlaybackURLs returns runs with absolute playback URLs for the client
+// of a request.
+func runsWithPlaybackURLs(r *http.Request, runs []models.Run) []models.Run {
+	base := publicBaseURL(r)
+	resolved := make([]models.Run, len(runs))
+	for i, run := range runs {
+		run.PlaybackURLs = streamer.ResolvePlaybackURLs(run.PlaybackURLs, base)
+		resolved[i] = run
+	}
+	return resolved
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/config"
+)
+
+func TestPublicBaseURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		publicURL string
+		tls       bool
+		headers   map[string]string
+		want      string
+	}{
+		{name: "Request host", want: "http://golive.local:9090/"},
+		{name: "TLS", tls: true, want: "https://golive.local:9090/"},
+		{
+			name: "Proxy",
+			headers: map[string]string{
+				"X-Forwarded-Proto":  "https",
+				"X-Forwarded-Host":   "streams.example.com",
+				"X-Forwarded-Prefix": "/golive/",
+			},
+			want: "https://streams.example.com/golive/",
+		},
+		{
+			name:    "Chained proxies",
+			headers: map[string]string{"X-Forwarded-Proto": "https, http", "X-Forwarded-Host": "streams.example.com, 10.0.0.2"},
+			want:    "https://streams.example.com/",
+		},
+		{
+			name:    "Invalid headers are ignored",
+			headers: map[string]string{"X-Forwarded-Proto": "javascript", "X-Forwarded-Host": "evil.example.com/path"},
+			want:    "http://golive.local:9090/",
+		},
+		{
+			name:      "Configured",
+			publicURL: "https://cdn-origin.example.com/live/",
+			headers:   map[string]string{"X-Forwarded-Host": "streams.example.com"},
+			want:      "https://cdn-origin.example.com/live/",
+		},
+	}
+	defer func(publicURL string) { config.PUBLIC_URL = publicURL }(config.PUBLIC_URL)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.PUBLIC_URL = tt.publicURL
+			r := httptest.NewRequest(http.MethodGet, "http://golive.local:9090/v1/jobs/job", nil)
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := publicBaseURL(r); got != tt.want {
+				t.Errorf("publicBaseURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobBaseURL(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "http://golive.local:9090/v1/jobs/job/runs/2/master.m3u8", nil)
+	r.Header.Set("X-Forwarded-Prefix", "/golive")
+	want := "http://golive.local:9090/golive/v1/jobs/job/runs/2/"
+	if got := jobBaseURL(r, "job", "master.m3u8"); got != want {
+		t.Errorf("jobBaseURL() = %s, want %s", got, want)
+	}
+}
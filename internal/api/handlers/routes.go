@@ -3,6 +3,8 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/arunjeyaprasad/golive/jobs"
+
 	"github.com/gorilla/mux"
 )
 
@@ -10,11 +12,36 @@ import (
 func RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/jobs", createJobHandler()).Methods(http.MethodPost)
 	router.HandleFunc("/jobs", getJobsHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/jobs", bulkJobsHandler(jobs.DeleteJobs)).Methods(http.MethodDelete)
+	router.HandleFunc("/jobs/stop", bulkJobsHandler(jobs.StopJobs)).Methods(http.MethodPut)
 	router.HandleFunc("/jobs/{job_id}", getJobHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/jobs/{job_id}", updateJobHandler()).Methods(http.MethodPatch)
 	router.HandleFunc("/jobs/{job_id}", cleanUpJobHandler()).Methods(http.MethodDelete)
 	router.HandleFunc("/jobs/{job_id}/start", startJobHandler()).Methods(http.MethodPut)
 	router.HandleFunc("/jobs/{job_id}/stop", stopJobHandler()).Methods(http.MethodPut)
+	router.HandleFunc("/jobs/{job_id}/clone", cloneJobHandler()).Methods(http.MethodPost)
+	router.HandleFunc("/jobs/{job_id}/runs", getRunsHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/jobs/{job_id}/runs/{run:[0-9]+}", getRunHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/jobs/{job_id}/health", getJobHealthHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/jobs/{job_id}/events", getJobEventsHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/events", getEventsHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/templates", getTemplatesHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/templates", createTemplateHandler()).Methods(http.MethodPost)
+	router.HandleFunc("/templates/{name}", getTemplateHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/templates/{name}", putTemplateHandler()).Methods(http.MethodPut)
+	router.HandleFunc("/templates/{name}", deleteTemplateHandler()).Methods(http.MethodDelete)
+	router.HandleFunc("/config", getConfigHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/openapi.json", getOpenAPIHandler()).Methods(http.MethodGet)
+	router.HandleFunc("/docs", getDocsHandler()).Methods(http.MethodGet)
 
-	// Media Endpoints
+	// Media Endpoints, of a run or of the latest run
+	router.HandleFunc("/jobs/{job_id}/runs/{run:[0-9]+}/{file:.+}", getMediaHandler()).Methods(http.MethodGet)
 	router.HandleFunc("/jobs/{job_id}/{file:.+}", getMediaHandler()).Methods(http.MethodGet)
 }
+
+// RegisterLegacyRoutes registers the unversioned API routes, which keep the
+// responses the clients predating /v1 were written for.
+func RegisterLegacyRoutes(router *mux.Router) {
+	router.HandleFunc("/jobs", getAllJobsHandler()).Methods(http.MethodGet)
+	RegisterRoutes(router)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/arunjeyaprasad/golive/internal/api/middleware"
+	"github.com/arunjeyaprasad/golive/internal/api/postprocessor"
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/templates"
+)
+
+func getTemplatesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := templates.List()
+		if err != nil {
+			slog.Error("Failed to list templates", "error", err)
+			postprocessor.FormatError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list templates")
+			return
+		}
+		postprocessor.FormatResponse(w, list, http.StatusOK)
+	}
+}
+
+func getTemplateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["name"]
+		t, err := templates.Get(name)
+		if err != nil {
+			templateError(w, name, err)
+			return
+		}
+		postprocessor.FormatResponse(w, t, http.StatusOK)
+	}
+}
+
+func createTemplateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := decodeTemplate(w, r)
+		if !ok {
+			return
+		}
+		if err := templates.Create(*t); err != nil {
+			templateError(w, t.Name, err)
+			return
+		}
+		postprocessor.FormatResponse(w, t, http.StatusCreated)
+	}
+}
+
+func putTemplateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["name"]
+		t, ok := decodeTemplate(w, r, name)
+		if !ok {
+			return
+		}
+		created, err := templates.Put(*t)
+		if err != nil {
+			templateError(w, name, err)
+			return
+		}
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		postprocessor.FormatResponse(w, t, status)
+	}
+}
+
+func deleteTemplateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.Context().Value(middleware.RouteParamsKey).(map[string]string)["name"]
+		t, err := templates.Get(name)
+		if err == nil {
+			err = templates.Delete(name)
+		}
+		if err != nil {
+			templateError(w, name, err)
+			return
+		}
+		postprocessor.FormatResponse(w, t, http.StatusOK)
+	}
+}
+
+// decodeTemplate decodes and validates the template in the body, named by the
+// route if a name is given. It writes the error response if it fails.
+func decodeTemplate(w http.ResponseWriter, r *http.Request, name ...string) (*models.Template, bool) {
+	var t models.Template
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		message, details := decodeError(err)
+		postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeInvalidRequest, message, details...)
+		return nil, false
+	}
+	if len(name) > 0 {
+		if t.Name != "" && t.Name != name[0] {
+			postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeValidation, "The template is invalid",
+				models.FieldError{Field: "name", Message: "name must be empty or match the name in the path"})
+			return nil, false
+		}
+		t.Name = name[0]
+	}
+	t.BuiltIn = false
+	if err := t.Validate(); err != nil {
+		postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeValidation,
+			"The template is invalid", models.FieldErrors(err)...)
+		return nil, false
+	}
+	return &t, true
+}
+
+// templateError writes the response for an error of the templates package.
+func templateError(w http.ResponseWriter, name string, err error) {
+	switch {
+	case errors.Is(err, templates.ErrNotFound):
+		postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Template not found")
+	case errors.Is(err, templates.ErrExists):
+		postprocessor.FormatError(w, http.StatusConflict, models.ErrorCodeConflict, "A template with this name already exists")
+	case errors.Is(err, templates.ErrBuiltIn):
+		postprocessor.FormatError(w, http.StatusBadRequest, models.ErrorCodeInvalidState, "Built-in templates cannot be changed")
+	default:
+		slog.Error("Template storage failed", "name", name, "error", err)
+		postprocessor.FormatError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to access the template")
+	}
+}
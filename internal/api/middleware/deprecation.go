@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// Deprecated marks responses of the unversioned routes as deprecated and
+// links to the same route under prefix.
+func Deprecated(prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+prefix+r.URL.Path+">; rel=\"successor-version\"")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"log/slog"
 	"net/http"
 	"runtime/debug"
+
+	"github.com/arunjeyaprasad/golive/internal/api/postprocessor"
+	"github.com/arunjeyaprasad/golive/models"
 )
 
 // RecoverMiddleware wraps an http.Handler and recovers from panics
@@ -17,9 +20,7 @@ func Recovery(next http.Handler) http.Handler {
 					"path", r.URL.Path,
 				)
 
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(`{"error": "internal server error"}`))
+				postprocessor.FormatError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Internal server error")
 			}
 		}()
 
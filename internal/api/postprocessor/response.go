@@ -3,6 +3,8 @@ package postprocessor
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/arunjeyaprasad/golive/models"
 )
 
 // FormatResponse formats the response data before sending it to the client.
@@ -11,3 +13,8 @@ func FormatResponse(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(data)
 }
+
+// FormatError sends an error response in the JSON shape shared by all errors.
+func FormatError(w http.ResponseWriter, statusCode int, code models.ErrorCode, message string, details ...models.FieldError) {
+	FormatResponse(w, models.ErrorResponse{Code: code, Message: message, Details: details}, statusCode)
+}
@@ -0,0 +1,150 @@
+// Package cli implements the golive command line: the server and a client
+// for scripting streams against a running server.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/arunjeyaprasad/golive/client"
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/server"
+)
+
+const usage = `Usage: golive [command] [flags]
+
+Commands:
+  serve [flags]       Run the streaming server, the default without a command
+  create [flags]      Create a stream and print its id
+  ls [flags]          List the streams, --owner and --label filter them
+  get <id>            Show a stream
+  start <id>          Start a stream
+  stop <id>           Stop a stream
+  rm <id>             Delete a stream and its output
+  wait <id> --until   Wait for a stream to reach a status
+
+Server flags:
+  --config FILE       YAML configuration file, defaults to $GOLIVE_CONFIG
+  --<setting> VALUE   Any setting, e.g. --port 8080, see 'golive serve -h'
+
+Client flags:
+  --server URL        golive server, defaults to $GOLIVE_SERVER or http://localhost:%d
+  -o, --output FMT    table or json
+
+Run 'golive <command> -h' for the flags of a command.
+`
+
+// errUsage fails a command without printing anything more, the flag set has
+// already explained the problem.
+var errUsage = errors.New("usage")
+
+// session holds the client flags shared by the subcommands.
+type session struct {
+	server string
+	out    *output
+}
+
+// client returns an API client for the server the flags selected.
+func (s *session) client() *client.Client {
+	return client.New(s.server)
+}
+
+// command runs a client subcommand with its arguments.
+type command func(s *session, args []string) error
+
+var commands = map[string]command{
+	"create": createCommand,
+	"ls":     lsCommand,
+	"get":    getCommand,
+	"start":  startCommand,
+	"stop":   stopCommand,
+	"rm":     rmCommand,
+	"wait":   waitCommand,
+}
+
+// Run runs the command line and returns the process exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "serve" {
+		fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		config.RegisterFlags(fs)
+		if len(args) > 0 {
+			if err := fs.Parse(args[1:]); errors.Is(err, flag.ErrHelp) {
+				return 0
+			} else if err != nil {
+				return 2
+			}
+		}
+		if err := config.Load(fs); err != nil {
+			fmt.Fprintln(stderr, "Invalid configuration:", err)
+			return 1
+		}
+		config.Init()
+		if err := server.StartServer(); err != nil {
+			slog.Error("Failed to start server", "error", err)
+			return 1
+		}
+		return 0
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprintf(stdout, usage, config.DEFAULT_SERVER_PORT)
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+		fmt.Fprintf(stderr, usage, config.DEFAULT_SERVER_PORT)
+		return 2
+	}
+
+	s := &session{
+		server: os.Getenv("GOLIVE_SERVER"),
+		out:    &output{w: stdout, errw: stderr, format: "table"},
+	}
+	if s.server == "" {
+		s.server = fmt.Sprintf("http://localhost:%d", config.DEFAULT_SERVER_PORT)
+	}
+	if err := cmd(s, args[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		fmt.Fprintln(stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+// newFlagSet creates the flag set of a subcommand with the client flags.
+func newFlagSet(name string, s *session) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(s.out.errw)
+	fs.StringVar(&s.server, "server", s.server, "golive server URL")
+	fs.StringVar(&s.out.format, "output", s.out.format, "output format, table or json")
+	fs.StringVar(&s.out.format, "o", s.out.format, "shorthand for --output")
+	return fs
+}
+
+// parse parses args allowing flags after the positional arguments, as in
+// "golive wait <id> --until running", and checks their count.
+func parse(fs *flag.FlagSet, args []string, positional ...string) ([]string, error) {
+	var values []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		values = append(values, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(values) != len(positional) {
+		fmt.Fprintf(fs.Output(), "%s expects %d argument(s): %v\n", fs.Name(), len(positional), positional)
+		return nil, errUsage
+	}
+	return values, nil
+}
@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// fakeAPI answers the job routes from canned jobs and records the requests.
+type fakeAPI struct {
+	mu       sync.Mutex
+	requests []string
+	created  models.JobCreateRequest
+	statuses []string // Statuses returned by successive GET /jobs/{id}
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	r.URL.Path = strings.TrimPrefix(r.URL.Path, "/v1")
+	job := models.Job{ID: "job-1", Status: "created", CreatedAt: "2026-01-02T15:04:05Z"}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/jobs":
+		if err := json.NewDecoder(r.Body).Decode(&f.created); err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{Code: models.ErrorCodeInvalidRequest, Message: err.Error()})
+			return
+		}
+		job.Configuration = f.created
+		writeJSON(w, http.StatusOK, job)
+	case r.Method == http.MethodGet && r.URL.Path == "/jobs":
+		job.Configuration.Description = "CI stream"
+		writeJSON(w, http.StatusOK, models.JobList{Jobs: []models.Job{job}, Total: 1, Limit: models.MaxJobListLimit})
+	case r.Method == http.MethodGet && r.URL.Path == "/jobs/job-1":
+		if len(f.statuses) > 0 {
+			job.Status = f.statuses[0]
+			if len(f.statuses) > 1 {
+				f.statuses = f.statuses[1:]
+			}
+		}
+		writeJSON(w, http.StatusOK, job)
+	case strings.HasPrefix(r.URL.Path, "/jobs/job-1"):
+		writeJSON(w, http.StatusOK, models.JobResponse{ID: "job-1"})
+	default:
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Code: models.ErrorCodeNotFound, Message: "Job not found"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		statuses     []string
+		wantCode     int
+		wantOut      string
+		wantErr      string
+		wantRequests []string
+	}{
+		{
+			name:         "Create prints the id",
+			args:         []string{"create", "--description", "CI", "--resolution", "1920x1080", "--codec", "hevc"},
+			wantOut:      "job-1\n",
+			wantRequests: []string{"POST /v1/jobs"},
+		},
+		{
+			name:         "Create and start",
+			args:         []string{"create", "--start"},
+			wantOut:      "job-1\n",
+			wantRequests: []string{"POST /v1/jobs", "PUT /v1/jobs/job-1/start"},
+		},
+		{
+			name:         "List as a table",
+			args:         []string{"ls"},
+			wantOut:      "ID     STATUS   PHASE  CREATED               DESCRIPTION\njob-1  created  -      2026-01-02T15:04:05Z  CI stream\n",
+			wantRequests: []string{"GET /v1/jobs"},
+		},
+		{
+			name:         "List as JSON",
+			args:         []string{"ls", "-o", "json"},
+			wantOut:      `"description": "CI stream"`,
+			wantRequests: []string{"GET /v1/jobs"},
+		},
+		{
+			name:         "Stop",
+			args:         []string{"stop", "job-1"},
+			wantOut:      "job-1\n",
+			wantRequests: []string{"PUT /v1/jobs/job-1/stop"},
+		},
+		{
+			name:         "Remove",
+			args:         []string{"rm", "job-1"},
+			wantOut:      "job-1\n",
+			wantRequests: []string{"DELETE /v1/jobs/job-1"},
+		},
+		{
+			name:     "Missing job",
+			args:     []string{"start", "job-2"},
+			wantCode: 1,
+			wantErr:  "Job not found (404)",
+		},
+		{
+			name:         "Wait with flags after the id",
+			args:         []string{"wait", "job-1", "--until", "running", "--interval", "1ms"},
+			statuses:     []string{"created", "created", "running"},
+			wantOut:      "Status:       running",
+			wantRequests: []string{"GET /v1/jobs/job-1", "GET /v1/jobs/job-1", "GET /v1/jobs/job-1"},
+		},
+		{
+			name:     "Wait for a failed job",
+			args:     []string{"wait", "job-1", "--until", "running", "--interval", "1ms"},
+			statuses: []string{"created", "error"},
+			wantCode: 1,
+			wantErr:  "waiting for job job-1 to be running: job failed",
+		},
+		{
+			name:     "Wait times out",
+			args:     []string{"wait", "job-1", "--until", "completed", "--interval", "1ms", "--timeout", "20ms"},
+			wantCode: 1,
+			wantErr:  "timed out after 20ms waiting for job job-1 to be completed, it is created",
+		},
+		{
+			name:     "Wait for an unknown status",
+			args:     []string{"wait", "job-1", "--until", "done"},
+			wantCode: 2,
+			wantErr:  "--until must be one of",
+		},
+		{
+			name:     "Missing id",
+			args:     []string{"start"},
+			wantCode: 2,
+			wantErr:  "start expects 1 argument(s)",
+		},
+		{
+			name:     "Unknown output format",
+			args:     []string{"ls", "-o", "yaml"},
+			wantCode: 1,
+			wantErr:  `unknown output format "yaml"`,
+		},
+		{
+			name:     "Unknown command",
+			args:     []string{"restart"},
+			wantCode: 2,
+			wantErr:  `unknown command "restart"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeAPI{statuses: tt.statuses}
+			srv := httptest.NewServer(api)
+			defer srv.Close()
+			t.Setenv("GOLIVE_SERVER", srv.URL)
+
+			var stdout, stderr bytes.Buffer
+			code := Run(tt.args, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("Run() = %d, want %d, stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantOut) {
+				t.Errorf("stdout = %q, want it to contain %q", stdout.String(), tt.wantOut)
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.wantErr)
+			}
+			if tt.wantRequests != nil && strings.Join(api.requests, ", ") != strings.Join(tt.wantRequests, ", ") {
+				t.Errorf("requests = %v, want %v", api.requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestCreateRequest(t *testing.T) {
+	api := &fakeAPI{}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+	t.Setenv("GOLIVE_SERVER", srv.URL)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"create", "--description", "CI", "--resolution", "1920x1080", "--codec", "hevc",
+		"--audio-bitrate", "96k", "--segment-length", "4", "--duration", "60", "--vod-on-stop"}
+	if code := Run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("Run() = %d, stderr: %s", code, stderr.String())
+	}
+	got := api.created
+	if got.Description != "CI" || got.SegmentLength != 4 || got.Duration != 60 || !got.VODOnStop {
+		t.Errorf("request = %+v", got)
+	}
+	if got.VideoTrack == nil || *got.VideoTrack != (models.VideoTrack{Resolution: "1920x1080", Codec: "hevc"}) {
+		t.Errorf("video = %+v, want only the flags that were set", got.VideoTrack)
+	}
+	if got.AudioTrack == nil || *got.AudioTrack != (models.AudioTrack{AudioBitrate: "96k"}) {
+		t.Errorf("audio = %+v, want only the flags that were set", got.AudioTrack)
+	}
+}
@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/client"
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+func createCommand(s *session, args []string) error {
+	var (
+		request            models.JobCreateRequest
+		video              models.VideoTrack
+		audio              models.AudioTrack
+		file               string
+		format             models.JobFormat
+		schedule           models.JobSchedule
+		description, owner string
+		labels             = labelFlag{}
+		vodOnStop, startIt bool
+	)
+	fs := newFlagSet("create", s)
+	fs.StringVar(&file, "file", "", "JSON create request to start from, - reads stdin, flags override it")
+	fs.StringVar(&file, "f", "", "shorthand for --file")
+	fs.StringVar(&description, "description", "", "description of the stream")
+	fs.StringVar(&owner, "owner", "", "team or person responsible for the stream")
+	fs.Var(labels, "label", "key=value label of the stream, can be repeated")
+	fs.StringVar(&video.Resolution, "resolution", "", "video resolution, e.g. 1920x1080")
+	fs.StringVar(&video.BitRate, "bitrate", "", "video bitrate, e.g. 4M")
+	fs.StringVar(&video.Framerate, "framerate", "", "video frame rate")
+	fs.StringVar(&video.Codec, "codec", "", "video codec: h264, hevc, vp9 or av1")
+	fs.StringVar(&audio.AudioCodec, "audio-codec", "", "audio codec: aac or mp3")
+	fs.StringVar(&audio.AudioBitrate, "audio-bitrate", "", "audio bitrate, e.g. 128k")
+	fs.StringVar(&audio.AudioSampleRate, "audio-sample-rate", "", "audio sample rate in Hz")
+	fs.StringVar(&audio.AudioChannels, "audio-channels", "", "number of audio channels")
+	fs.IntVar(&format.SegmentLength, "segment-length", 0, "segment length in seconds")
+	fs.IntVar(&format.WindowSize, "window-size", 0, "number of segments in the playlists")
+	fs.IntVar(&format.DVRWindowSeconds, "dvr-window", 0, "timeshift depth in seconds")
+	fs.BoolVar(&vodOnStop, "vod-on-stop", false, "keep the stream as VOD when it stops")
+	fs.StringVar(&schedule.StartAt, "start-at", "", "RFC3339 time to start the stream at")
+	fs.StringVar(&schedule.StopAt, "stop-at", "", "RFC3339 time to stop the stream at")
+	fs.IntVar(&schedule.Duration, "duration", 0, "stop the stream this many seconds after it started")
+	fs.StringVar(&schedule.Schedule, "schedule", "", "cron expression of recurring starts")
+	fs.BoolVar(&startIt, "start", false, "start the stream right after creating it")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := s.out.check(); err != nil {
+		return err
+	}
+
+	if file != "" {
+		if err := readRequest(file, &request); err != nil {
+			return err
+		}
+	}
+	if description != "" {
+		request.Description = description
+	}
+	merge(&request.Owner, owner)
+	if len(labels) > 0 && request.Labels == nil {
+		request.Labels = make(map[string]string)
+	}
+	for key, value := range labels {
+		request.Labels[key] = value
+	}
+	if video != (models.VideoTrack{}) {
+		if request.VideoTrack == nil {
+			request.VideoTrack = &models.VideoTrack{}
+		}
+		merge(&request.VideoTrack.Resolution, video.Resolution)
+		merge(&request.VideoTrack.BitRate, video.BitRate)
+		merge(&request.VideoTrack.Framerate, video.Framerate)
+		merge(&request.VideoTrack.Codec, video.Codec)
+	}
+	if audio != (models.AudioTrack{}) {
+		if request.AudioTrack == nil {
+			request.AudioTrack = &models.AudioTrack{}
+		}
+		merge(&request.AudioTrack.AudioCodec, audio.AudioCodec)
+		merge(&request.AudioTrack.AudioBitrate, audio.AudioBitrate)
+		merge(&request.AudioTrack.AudioSampleRate, audio.AudioSampleRate)
+		merge(&request.AudioTrack.AudioChannels, audio.AudioChannels)
+	}
+	if format.SegmentLength != 0 {
+		request.SegmentLength = format.SegmentLength
+	}
+	if format.WindowSize != 0 {
+		request.WindowSize = format.WindowSize
+	}
+	if format.DVRWindowSeconds != 0 {
+		request.DVRWindowSeconds = format.DVRWindowSeconds
+	}
+	if vodOnStop {
+		request.VODOnStop = true
+	}
+	merge(&request.StartAt, schedule.StartAt)
+	merge(&request.StopAt, schedule.StopAt)
+	merge(&request.Schedule, schedule.Schedule)
+	if schedule.Duration != 0 {
+		request.Duration = schedule.Duration
+	}
+
+	ctx := context.Background()
+	c := s.client()
+	job, err := c.CreateJob(ctx, request)
+	if err != nil {
+		return err
+	}
+	if startIt {
+		if err := c.StartJob(ctx, job.ID); err != nil {
+			return fmt.Errorf("created job %s but could not start it: %w", job.ID, err)
+		}
+	}
+	return s.out.id(job.ID)
+}
+
+// readRequest decodes a create request from a file or stdin.
+func readRequest(file string, request *models.JobCreateRequest) error {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if err := json.NewDecoder(r).Decode(request); err != nil {
+		return fmt.Errorf("reading %s: %w", file, err)
+	}
+	return nil
+}
+
+// merge overrides a request field with a flag that was set.
+func merge(field *string, flag string) {
+	if flag != "" {
+		*field = flag
+	}
+}
+
+// labelFlag collects repeated key=value flags.
+type labelFlag map[string]string
+
+func (l labelFlag) String() string {
+	return fmt.Sprint(map[string]string(l))
+}
+
+func (l labelFlag) Set(value string) error {
+	key, v, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("label %q must be key=value", value)
+	}
+	l[key] = v
+	return nil
+}
+
+func lsCommand(s *session, args []string) error {
+	var query models.JobListQuery
+	owner, labels := "", labelFlag{}
+	fs := newFlagSet("ls", s)
+	fs.StringVar(&owner, "owner", "", "only streams of this owner")
+	fs.Var(labels, "label", "only streams with this key=value label, can be repeated")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := s.out.check(); err != nil {
+		return err
+	}
+	if owner != "" {
+		query.Owners = []string{owner}
+	}
+	if len(labels) > 0 {
+		query.Labels = labels
+	}
+	list, err := s.client().FindJobs(context.Background(), query)
+	if err != nil {
+		return err
+	}
+	return s.out.jobs(list)
+}
+
+func getCommand(s *session, args []string) error {
+	id, err := parseID("get", s, args)
+	if err != nil {
+		return err
+	}
+	job, err := s.client().GetJob(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	return s.out.job(job)
+}
+
+func startCommand(s *session, args []string) error {
+	return jobAction("start", s, args, (*client.Client).StartJob)
+}
+
+func stopCommand(s *session, args []string) error {
+	return jobAction("stop", s, args, (*client.Client).StopJob)
+}
+
+func rmCommand(s *session, args []string) error {
+	return jobAction("rm", s, args, (*client.Client).DeleteJob)
+}
+
+// parseID parses the arguments of a command that takes a job id.
+func parseID(name string, s *session, args []string) (string, error) {
+	fs := newFlagSet(name, s)
+	values, err := parse(fs, args, "id")
+	if err != nil {
+		return "", err
+	}
+	return values[0], s.out.check()
+}
+
+// jobAction calls the API for one job and prints its id.
+func jobAction(name string, s *session, args []string, call func(*client.Client, context.Context, string) error) error {
+	id, err := parseID(name, s, args)
+	if err != nil {
+		return err
+	}
+	if err := call(s.client(), context.Background(), id); err != nil {
+		return err
+	}
+	return s.out.id(id)
+}
+
+func waitCommand(s *session, args []string) error {
+	var (
+		until             string
+		timeout, interval time.Duration
+	)
+	fs := newFlagSet("wait", s)
+	fs.StringVar(&until, "until", "", "status to wait for: created, queued, running, completed or error")
+	fs.DurationVar(&timeout, "timeout", 5*time.Minute, "give up after this long")
+	fs.DurationVar(&interval, "interval", client.DefaultPollInterval, "time between checks")
+	values, err := parse(fs, args, "id")
+	if err != nil {
+		return err
+	}
+	if err := s.out.check(); err != nil {
+		return err
+	}
+	switch until {
+	case client.StatusCreated, client.StatusQueued, client.StatusRunning, client.StatusCompleted, client.StatusFailed:
+	default:
+		fmt.Fprintln(fs.Output(), "--until must be one of created, queued, running, completed or error")
+		return errUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := s.client()
+	c.PollInterval = interval
+	job, err := c.WaitForState(ctx, values[0], until)
+	if errors.Is(err, context.DeadlineExceeded) {
+		status := "-"
+		if job != nil {
+			status = job.Status
+		}
+		return fmt.Errorf("timed out after %s waiting for job %s to be %s, it is %s", timeout, values[0], until, status)
+	}
+	if err != nil {
+		return err
+	}
+	return s.out.job(job)
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// output prints command results as tables for people or JSON for scripts.
+type output struct {
+	w      io.Writer
+	errw   io.Writer // Usage and errors
+	format string
+}
+
+func (o *output) check() error {
+	if o.format != "table" && o.format != "json" {
+		return fmt.Errorf("unknown output format %q, use table or json", o.format)
+	}
+	return nil
+}
+
+func (o *output) json(v any) error {
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// jobs prints a list of jobs.
+func (o *output) jobs(jobs []models.Job) error {
+	if o.format == "json" {
+		if jobs == nil {
+			jobs = []models.Job{}
+		}
+		return o.json(jobs)
+	}
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tPHASE\tCREATED\tDESCRIPTION")
+	for _, job := range jobs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			job.ID, job.Status, dash(string(job.Phase)), job.CreatedAt, job.Configuration.Description)
+	}
+	return tw.Flush()
+}
+
+// job prints the details of a job.
+func (o *output) job(job *models.Job) error {
+	if o.format == "json" {
+		return o.json(job)
+	}
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", job.ID)
+	fmt.Fprintf(tw, "Description:\t%s\n", job.Configuration.Description)
+	fmt.Fprintf(tw, "Status:\t%s\n", job.Status)
+	if job.Phase != "" {
+		fmt.Fprintf(tw, "Phase:\t%s\n", job.Phase)
+	}
+	fmt.Fprintf(tw, "Created:\t%s\n", job.CreatedAt)
+	if job.StreamingStartedAt != "" {
+		fmt.Fprintf(tw, "Started:\t%s\n", job.StreamingStartedAt)
+	}
+	if job.CompletedAt != "" {
+		fmt.Fprintf(tw, "Completed:\t%s\n", job.CompletedAt)
+	}
+	if run := job.ScheduledRun; run != nil {
+		fmt.Fprintf(tw, "Scheduled:\t%s - %s\n", dash(run.StartAt), dash(run.StopAt))
+	}
+	for _, p := range job.PlaybackURLs {
+		name := strings.ToUpper(string(p.Format))
+		if p.Variant != "" {
+			name += " " + string(p.Variant)
+		}
+		fmt.Fprintf(tw, "%s:\t%s\n", name, p.URL)
+		for _, u := range p.CDNURLs {
+			fmt.Fprintf(tw, "%s CDN:\t%s\n", name, u)
+		}
+	}
+	return tw.Flush()
+}
+
+// id prints the id of the job a command acted on, alone in table output so
+// that scripts can capture it.
+func (o *output) id(id string) error {
+	if o.format == "json" {
+		return o.json(models.JobResponse{ID: id})
+	}
+	_, err := fmt.Fprintln(o.w, id)
+	return err
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
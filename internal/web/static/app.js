@@ -0,0 +1,324 @@
+// golive dashboard, a thin client of the REST API and the /events stream.
+"use strict";
+
+const jobs = new Map();
+const stats = new Map();
+let previewJob = null;
+let player = null;
+
+// api calls the REST API, failures throw with the details of the error body.
+async function api(method, path, body) {
+	const res = await fetch("/v1" + path, {
+		method,
+		headers: body ? { "Content-Type": "application/json" } : {},
+		body: body ? JSON.stringify(body) : undefined,
+	});
+	const text = await res.text();
+	if (!res.ok) {
+		let body = {};
+		try {
+			body = JSON.parse(text);
+		} catch {
+			body.message = text.trim();
+		}
+		const err = new Error(body.message || res.statusText);
+		err.status = res.status;
+		err.details = body.details || [];
+		throw err;
+	}
+	return text ? JSON.parse(text) : null;
+}
+
+function el(tag, attrs, ...children) {
+	const node = document.createElement(tag);
+	for (const [k, v] of Object.entries(attrs || {})) {
+		if (v == null) continue;
+		if (k.startsWith("on")) node.addEventListener(k.slice(2), v);
+		else node.setAttribute(k, v);
+	}
+	for (const child of children) {
+		if (child != null) node.append(child);
+	}
+	return node;
+}
+
+function formatTime(value) {
+	return value ? new Date(value).toLocaleString() : "";
+}
+
+async function loadJobs() {
+	const list = [];
+	for (let offset = 0; ; ) {
+		const page = await api("GET", "/jobs?limit=500&offset=" + offset);
+		list.push(...page.jobs);
+		if (!page.next_offset) break;
+		offset = page.next_offset;
+	}
+	jobs.clear();
+	for (const job of list) jobs.set(job.id, job);
+	renderJobs();
+}
+
+function renderJobs() {
+	const body = document.querySelector("#jobs tbody");
+	const sorted = [...jobs.values()].sort((a, b) => b.created.localeCompare(a.created));
+	body.replaceChildren(...sorted.map(renderJob));
+	document.getElementById("no-jobs").hidden = sorted.length > 0;
+	if (previewJob && !jobs.has(previewJob)) closePreview();
+}
+
+function renderJob(job) {
+	const running = job.status === "running";
+	const queued = job.status === "queued";
+	const status = el("span", { class: "badge status-" + job.status }, job.status);
+	const phase = job.phase ? el("span", { class: "badge" }, job.phase.replace("_", " ")) : null;
+	const next = job.scheduled_run && job.scheduled_run.start_at && !running
+		? el("div", { class: "muted" }, "next run " + formatTime(job.scheduled_run.start_at))
+		: null;
+	return el("tr", { id: "job-" + job.id },
+		el("td", {}, job.config.description || job.id, el("div", { class: "muted mono" }, job.id)),
+		el("td", {}, status, " ", phase, next),
+		el("td", {}, formatTime(job.created)),
+		el("td", { class: "stats mono" }, renderStats(job.id)),
+		el("td", { class: "actions" },
+			running || queued
+				? el("button", { onclick: () => action("PUT", `/jobs/${job.id}/stop`) }, "Stop")
+				: el("button", { onclick: () => action("PUT", `/jobs/${job.id}/start`) }, "Start"),
+			el("button", { onclick: () => openPreview(job.id), disabled: !job.playback_urls ? "" : undefined }, "Preview"),
+			el("button", {
+				class: "danger",
+				onclick: () => confirm("Delete this stream and its output?") && action("DELETE", `/jobs/${job.id}`),
+			}, "Delete"),
+		),
+	);
+}
+
+function renderStats(jobID) {
+	const s = stats.get(jobID);
+	if (!s || jobs.get(jobID)?.status !== "running") return "";
+	const parts = [`${(s.fps || 0).toFixed(1)} fps`];
+	if (s.speed) parts.push(s.speed);
+	if (s.bitrate) parts.push(s.bitrate);
+	if (s.out_time) parts.push(s.out_time.split(".")[0]);
+	if (s.drop_frames) parts.push(`${s.drop_frames} dropped`);
+	const node = el("span", {}, parts.join(" · "));
+	if (s.error) node.append(el("div", { class: "error" }, s.error));
+	return node;
+}
+
+async function action(method, path) {
+	try {
+		await api(method, path);
+	} catch (err) {
+		alert(err.message);
+	}
+	await loadJobs();
+}
+
+// Live updates
+
+function connect() {
+	const badge = document.getElementById("connection");
+	const source = new EventSource("/v1/events");
+	source.onopen = () => {
+		badge.textContent = "live";
+		badge.className = "badge status-running";
+		loadJobs();
+	};
+	source.onerror = () => {
+		badge.textContent = "reconnecting";
+		badge.className = "badge status-error";
+	};
+	source.addEventListener("state", (e) => {
+		const ev = JSON.parse(e.data);
+		const job = jobs.get(ev.job_id);
+		if (!job || ev.state.transition === "job.created" || ev.state.transition === "job.cleaned_up") {
+			loadJobs(); // The list changed, fetch the playback URLs and configuration too
+			return;
+		}
+		if (ev.state.transition === "job.started" || ev.state.transition === "job.stopped") {
+			loadJobs(); // Playback URLs change with the run
+			return;
+		}
+		job.status = ev.state.status;
+		job.phase = ev.state.phase;
+		renderJobs();
+	});
+	source.addEventListener("stats", (e) => {
+		const ev = JSON.parse(e.data);
+		stats.set(ev.job_id, { ...stats.get(ev.job_id), ...ev.stats, error: undefined });
+		updateStats(ev.job_id);
+	});
+	source.addEventListener("error", (e) => {
+		if (!e.data) return; // Connection errors are handled by onerror
+		const ev = JSON.parse(e.data);
+		stats.set(ev.job_id, { ...stats.get(ev.job_id), error: ev.error });
+		updateStats(ev.job_id);
+	});
+	source.addEventListener("segment", (e) => {
+		const ev = JSON.parse(e.data);
+		if (ev.job_id !== previewJob) return;
+		const timeline = document.getElementById("timeline");
+		const seg = ev.segment;
+		timeline.prepend(el("li", { class: "mono" },
+			`${seg.playlist} #${seg.sequence} ${seg.duration.toFixed(2)}s ${(seg.size / 1024).toFixed(0)} KiB`));
+		while (timeline.children.length > 10) timeline.lastChild.remove();
+	});
+}
+
+function updateStats(jobID) {
+	const cell = document.querySelector(`#job-${CSS.escape(jobID)} .stats`);
+	if (cell) cell.replaceChildren(renderStats(jobID));
+}
+
+// Preview
+
+// playbackURL serves the stream from the host the dashboard was loaded from,
+// the API reports URLs relative to its own configured host.
+function playbackURL(url) {
+	const u = new URL(url, location.href);
+	return location.origin + u.pathname;
+}
+
+function openPreview(jobID) {
+	const job = jobs.get(jobID);
+	if (!job || !job.playback_urls) return;
+	previewJob = jobID;
+	document.getElementById("preview-panel").hidden = false;
+	document.getElementById("preview-title").textContent = job.config.description || job.id;
+	document.getElementById("timeline").replaceChildren();
+	const buttons = job.playback_urls.map((p) => el("button", {
+		onclick: () => play(playbackURL(p.url), p.format),
+		title: p.url,
+	}, [p.format, p.variant].filter(Boolean).join(" ")));
+	buttons.push(el("button", { onclick: closePreview }, "Close"));
+	document.getElementById("preview-urls").replaceChildren(...buttons);
+	const first = job.playback_urls[0];
+	play(playbackURL(first.url), first.format);
+}
+
+function play(url, format) {
+	const video = document.getElementById("player");
+	stopPlayer();
+	if (format === "dash") {
+		player = dashjs.MediaPlayer().create();
+		player.initialize(video, url, true);
+	} else if (window.Hls && Hls.isSupported()) {
+		player = new Hls();
+		player.loadSource(url);
+		player.attachMedia(video);
+		video.play().catch(() => {});
+	} else {
+		video.src = url; // Native HLS playback in Safari
+		video.play().catch(() => {});
+	}
+}
+
+function stopPlayer() {
+	const video = document.getElementById("player");
+	if (player) {
+		if (player.reset) player.reset(); // dash.js
+		else player.destroy(); // hls.js
+		player = null;
+	}
+	video.removeAttribute("src");
+	video.load();
+}
+
+function closePreview() {
+	stopPlayer();
+	previewJob = null;
+	document.getElementById("preview-panel").hidden = true;
+}
+
+// Create form
+
+// buildRequest turns the form into a JobCreateRequest, empty fields keep the
+// server defaults.
+function buildRequest(form) {
+	const request = {};
+	for (const input of form.querySelectorAll("input, select, textarea")) {
+		if (!input.name || input.name === "extra" || input.name === "autostart") continue;
+		let value;
+		if (input.type === "checkbox") {
+			if (!input.checked) continue;
+			value = true;
+		} else if (input.value.trim() === "") {
+			continue;
+		} else if (input.type === "number") {
+			value = Number(input.value);
+		} else if (input.type === "datetime-local") {
+			value = new Date(input.value).toISOString();
+		} else {
+			value = input.value.trim();
+		}
+		const path = input.name.split(".");
+		let target = request;
+		for (const key of path.slice(0, -1)) target = target[key] = target[key] || {};
+		target[path[path.length - 1]] = value;
+	}
+	const extra = form.elements.extra.value.trim();
+	if (extra) {
+		let parsed;
+		try {
+			parsed = JSON.parse(extra);
+		} catch (err) {
+			throw Object.assign(new Error("The request could not be built"), {
+				details: [{ field: "extra", message: "extra JSON is invalid: " + err.message }],
+			});
+		}
+		for (const [k, v] of Object.entries(parsed)) {
+			request[k] = v && typeof v === "object" && !Array.isArray(v) ? { ...request[k], ...v } : v;
+		}
+	}
+	return request;
+}
+
+function clearErrors(form) {
+	form.querySelectorAll(".field-error").forEach((n) => n.remove());
+	form.querySelectorAll(".invalid").forEach((n) => n.classList.remove("invalid"));
+	document.getElementById("form-errors").hidden = true;
+}
+
+// showErrors places each validation error next to the input of its field,
+// fields without one are set through the extra JSON, the rest go above the
+// form.
+function showErrors(form, message, details) {
+	const extra = form.elements.extra;
+	const general = details.length ? [] : [message];
+	for (const detail of details) {
+		const field = detail.field || "";
+		let input = field ? form.querySelector(`[name="${CSS.escape(field)}"]`) : null;
+		if (!input && field && extra.dataset.fields.split(",").some((prefix) => field.startsWith(prefix))) {
+			input = extra;
+		}
+		if (!input) {
+			general.push(detail.message);
+			continue;
+		}
+		input.classList.add("invalid");
+		input.closest("label").append(el("span", { class: "field-error" }, detail.message));
+	}
+	const box = document.getElementById("form-errors");
+	box.replaceChildren(...general.map((line) => el("div", {}, line)));
+	box.hidden = general.length === 0;
+}
+
+document.getElementById("create-form").addEventListener("submit", async (e) => {
+	e.preventDefault();
+	const form = e.target;
+	clearErrors(form);
+	try {
+		const job = await api("POST", "/jobs", buildRequest(form));
+		if (form.elements.autostart.checked && !(job.config.start_at || job.config.schedule)) {
+			await api("PUT", `/jobs/${job.id}/start`);
+		}
+		form.reset();
+	} catch (err) {
+		showErrors(form, err.message, err.details || []);
+	}
+	await loadJobs();
+});
+
+loadJobs().catch((err) => console.error(err));
+connect();
@@ -0,0 +1,23 @@
+// Package web serves the dashboard, a static UI on top of the REST API.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Prefix is the path the dashboard is served under.
+const Prefix = "/ui/"
+
+// Handler serves the dashboard files under Prefix.
+func Handler() http.Handler {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err) // The directory is embedded at build time
+	}
+	return http.StripPrefix(Prefix, http.FileServer(http.FS(files)))
+}
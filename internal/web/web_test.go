@@ -0,0 +1,38 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		wantType    string
+		wantContent string
+	}{
+		{name: "Index", path: "/ui/", wantStatus: http.StatusOK, wantType: "text/html", wantContent: `id="create-form"`},
+		{name: "Script", path: "/ui/app.js", wantStatus: http.StatusOK, wantType: "text/javascript", wantContent: "new EventSource"},
+		{name: "Stylesheet", path: "/ui/style.css", wantStatus: http.StatusOK, wantType: "text/css", wantContent: ":root"},
+		{name: "Missing file", path: "/ui/missing.js", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantContent) {
+				t.Errorf("body does not contain %q", tt.wantContent)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/resources"
+)
+
+// ErrJobQueued is returned for a job that waits for resources to start.
+var ErrJobQueued = errors.New("job is queued until the host has the resources to run it")
+
+// queue holds the jobs waiting for resources in the order they asked to
+// start, guarded by mu
+var queue []string
+
+// admit checks that the host can run a job next to the running ones. With
+// the queue policy a job that does not fit, or that would overtake the
+// queue, waits in it. Callers must hold mu and mark an admitted job running
+// before releasing it, so concurrent starts count it.
+func admit(job *models.Job) error {
+	if config.RESOURCE_POLICY == config.ResourcePolicyOff && config.MAX_JOB_COUNT == 0 {
+		return nil
+	}
+	waiting := slices.Contains(queue, job.ID)
+	if waiting && queue[0] != job.ID {
+		return ErrJobQueued
+	}
+	err := fits(job)
+	if config.RESOURCE_POLICY != config.ResourcePolicyQueue {
+		return err
+	}
+	if err == nil && (len(queue) == 0 || waiting) {
+		if waiting {
+			queue = queue[1:]
+		}
+		return nil
+	}
+	if !waiting {
+		slog.Info("Queued job until resources are free", "jobID", job.ID, "reason", err)
+		queue = append(queue, job.ID)
+		job.Status = string(JobStatusQueued)
+		jobs[job.ID] = *job
+		publish(models.WebhookEventQueued, *job)
+	}
+	return ErrJobQueued
+}
+
+// fits returns a *resources.InsufficientError if the host cannot run a job
+// next to the running ones, or config.MAX_JOB_COUNT jobs run. Callers must
+// hold mu.
+func fits(job *models.Job) error {
+	var committed resources.Cost
+	count := 0
+	for _, running := range runningJobs() {
+		if running.ID != job.ID {
+			committed = committed.Add(resources.Estimate(running.Configuration))
+			count++
+		}
+	}
+	if config.MAX_JOB_COUNT > 0 && count >= config.MAX_JOB_COUNT {
+		return &resources.InsufficientError{Shortfalls: []string{
+			fmt.Sprintf("%d jobs are running, the limit is %d", count, config.MAX_JOB_COUNT),
+		}}
+	}
+	if config.RESOURCE_POLICY == config.ResourcePolicyOff {
+		return nil
+	}
+	cost := resources.Estimate(job.Configuration)
+	host := resources.Probe(config.DEFAULT_MEDIA_DIR)
+	return resources.Check(cost, committed, host, config.MIN_FREE_DISK_BYTES)
+}
+
+// runningJobs returns the running jobs, including those admitted whose
+// encoder is starting or restarting. Callers must hold mu.
+func runningJobs() []models.Job {
+	var running []models.Job
+	for _, job := range jobs {
+		if job.Status == string(JobStatusRunning) {
+			running = append(running, job)
+		}
+	}
+	return running
+}
+
+// dequeue takes a queued job out of the queue, it is created again. It
+// reports whether the job was queued.
+func dequeue(id string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	i := slices.Index(queue, id)
+	if i < 0 {
+		return false
+	}
+	queue = slices.Delete(queue, i, i+1)
+	if job, exists := jobs[id]; exists && job.Status == string(JobStatusQueued) {
+		job.Status = string(JobStatusCreated)
+		jobs[id] = job
+		publish(models.WebhookEventStopped, job)
+	}
+	return true
+}
+
+// StartGuard checks the free disk space and starts queued jobs that fit
+// every interval, until the returned function is called.
+func StartGuard(interval time.Duration) (stop func()) {
+	if config.RESOURCE_POLICY == config.ResourcePolicyOff && config.MAX_JOB_COUNT == 0 {
+		return func() {}
+	}
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				checkDisk()
+				drainQueue()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// drainQueue starts the queued jobs in order, as long as they fit.
+func drainQueue() {
+	for {
+		mu.RLock()
+		if len(queue) == 0 {
+			mu.RUnlock()
+			return
+		}
+		id := queue[0]
+		job, exists := jobs[id]
+		mu.RUnlock()
+		if !exists {
+			dequeue(id)
+			continue
+		}
+		err := StartJob(&job)
+		if errors.Is(err, ErrJobQueued) {
+			return // Still does not fit
+		}
+		if err != nil {
+			slog.Error("Failed to start queued job", "jobID", job.ID, "error", err)
+			mu.Lock()
+			if current, exists := jobs[job.ID]; exists && current.Status == string(JobStatusQueued) {
+				current.Status = string(JobStatusFailed)
+				current.CompletedAt = time.Now().Format(time.RFC3339)
+				jobs[job.ID] = current
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+// checkDisk stops the running jobs when the media directory runs low on
+// space, before ffmpeg fails to write. The jobs finalize their output and
+// are marked failed.
+func checkDisk() {
+	free := resources.DiskFree(config.DEFAULT_MEDIA_DIR)
+	floor := config.MIN_FREE_DISK_BYTES / 4
+	if free < 0 || free >= floor {
+		return
+	}
+	cause := fmt.Errorf("stopped as the media directory has %s free, below %s",
+		resources.FormatBytes(free), resources.FormatBytes(floor))
+	mu.RLock()
+	running := runningJobs()
+	mu.RUnlock()
+	for _, job := range running {
+		slog.Error("Stopping job, the disk is almost full", "jobID", job.ID, "free", free, "floor", floor)
+		if err := stopJob(job.ID, cause); err != nil {
+			slog.Error("Failed to stop job", "jobID", job.ID, "error", err)
+		}
+	}
+}
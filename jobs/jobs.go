@@ -1,26 +1,41 @@
 package jobs
 
 import (
+	"errors"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/events"
 	"github.com/arunjeyaprasad/golive/models"
 	"github.com/arunjeyaprasad/golive/streamer"
+	"github.com/arunjeyaprasad/golive/transform"
+	"github.com/arunjeyaprasad/golive/validate"
+	"github.com/arunjeyaprasad/golive/vod"
+	"github.com/arunjeyaprasad/golive/webhook"
 	"github.com/google/uuid"
 )
 
 var (
+	// mu guards the maps below, scheduled starts and stops access them from timer goroutines
+	mu            sync.RWMutex
 	jobs          = make(map[string]models.Job)
 	jobProcessMap = make(map[string]*streamer.StreamingProcess)
+	validators    = make(map[string]*validate.Validator)
+	runs          = make(map[string][]models.Run)           // Every start of a job, in order
+	restarts      = make(map[string]chan struct{})          // Closed once the encoder of a job is restarted, see restartJob
+	continuations = make(map[string]transform.Continuation) // Of the latest run of a job, if it restarted an earlier one
 )
 
 type JobStatus string
 
 const (
 	JobStatusCreated   JobStatus = "created"
+	JobStatusQueued    JobStatus = "queued" // Waiting for resources, see config.RESOURCE_POLICY
 	JobStatusRunning   JobStatus = "running"
 	JobStatusCompleted JobStatus = "completed"
 	JobStatusFailed    JobStatus = "error"
@@ -34,85 +49,499 @@ func generateJobID() string {
 
 // createJob creates a new job with the given description and adds it to the jobs map.
 func CreateJob(request models.JobCreateRequest) *models.Job {
+	return createJob(request, "")
+}
+
+// CloneJob creates a new job with the configuration of an existing one.
+func CloneJob(id string) (*models.Job, bool) {
+	source, exists := GetJob(id)
+	if !exists {
+		return nil, false
+	}
+	// A copy, the configuration holds pointers
+	request, err := source.Configuration.Merge(nil)
+	if err != nil {
+		slog.Error("Failed to copy job configuration", "jobID", id, "error", err)
+		return nil, false
+	}
+	return createJob(request, id), true
+}
+
+func createJob(request models.JobCreateRequest, clonedFrom string) *models.Job {
 	job := models.Job{
 		ID:            generateJobID(),
 		Status:        string(JobStatusCreated),
 		CreatedAt:     time.Now().Format(time.RFC3339),
+		ClonedFrom:    clonedFrom,
 		Configuration: request,
 	}
+	mu.Lock()
+	defer mu.Unlock()
+	scheduleStart(&job)
 	jobs[job.ID] = job
+	publish(models.WebhookEventCreated, job)
 	return &job
 }
 
 func GetJobs() []models.Job {
+	mu.RLock()
+	defer mu.RUnlock()
 	var jobList []models.Job
 	for _, job := range jobs {
+		setPhase(&job)
 		jobList = append(jobList, job)
 	}
 	return jobList
 }
 
 func GetJob(id string) (*models.Job, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
 	job, exists := jobs[id]
 	if !exists {
 		return nil, false
 	}
+	setPhase(&job)
 	return &job, true
 }
 
+// setPhase fills in the current phase of an event job.
+func setPhase(job *models.Job) {
+	job.Phase = ""
+	event := job.Configuration.Event
+	if event == nil {
+		return
+	}
+	switch job.Status {
+	case string(JobStatusRunning):
+		startedAt, err := time.Parse(time.RFC3339, job.StreamingStartedAt)
+		if err == nil {
+			job.Phase = event.PhaseAt(time.Since(startedAt))
+		}
+	case string(JobStatusCompleted):
+		job.Phase = models.EventPhaseEnded
+	}
+}
+
+// DeleteJob stops a job and deletes it with the output of all its runs.
 func DeleteJob(id string) {
+	deleteJob(id, false)
+}
+
+// deleteJob deletes a job, if it is retainable when stoppedOnly is set. It
+// reports whether it did.
+func deleteJob(id string, stoppedOnly bool) bool {
+	mu.Lock()
+	awaitRestart(id)
+	if stoppedOnly && !retainable(id) {
+		mu.Unlock()
+		return false // Started or scheduled again
+	}
+	cancelTimer(id)
+	sp, running := jobProcessMap[id]
+	delete(jobProcessMap, id)
+	v, validating := validators[id]
+	delete(validators, id)
+	delete(runs, id)
+	delete(continuations, id)
+	mu.Unlock()
+
 	// Stop the job if it's running
-	if sp, exists := jobProcessMap[id]; exists {
+	if running {
 		sp.StopJob()
-		delete(jobProcessMap, id)
 	}
-	// Clean up the job's output directory to reclaim space
+	if validating {
+		v.Stop()
+	}
+	// Clean up the output directories of all runs to reclaim space
 	path := filepath.Join(config.DEFAULT_MEDIA_DIR, id)
 	if err := os.RemoveAll(path); err != nil {
 		slog.Error("Failed to remove job directory", "path", path, "error", err)
 	}
 	// Remove the job from the jobs map
-	if _, exists := jobs[id]; !exists {
-		return // Job not found
+	mu.Lock()
+	defer mu.Unlock()
+	if job, exists := jobs[id]; exists {
+		delete(jobs, id)
+		publish(models.WebhookEventCleanedUp, job)
 	}
-	delete(jobs, id)
+	return true
 }
 
+// ErrJobRunning is returned for starting a job that is already running.
+var ErrJobRunning = errors.New("job is already running")
+
+// StartJob starts a new run of a job, if the host can sustain it. Otherwise
+// it returns a *resources.InsufficientError, or queues the job and returns
+// ErrJobQueued, see config.RESOURCE_POLICY. A running job returns
+// ErrJobRunning.
 func StartJob(job *models.Job) error {
+	return startJob(job, nil)
+}
+
+// startJob starts the encoder of a job for a new run. A restart continues the
+// latest run of the job, which is running and admitted.
+func startJob(job *models.Job, restart *transform.Continuation) error {
+	mu.Lock()
+	if restart == nil {
+		// Claim the job as it is admitted, concurrent starts must neither
+		// spawn a second encoder nor overcommit the host
+		if current, exists := jobs[job.ID]; exists && current.Status == string(JobStatusRunning) {
+			mu.Unlock()
+			return ErrJobRunning
+		}
+		if err := admit(job); err != nil {
+			mu.Unlock()
+			return err
+		}
+	}
+	record := models.Run{
+		Number:    len(runs[job.ID]) + 1,
+		Status:    string(JobStatusRunning),
+		StartedAt: time.Now().Format(time.RFC3339),
+	}
+	delete(continuations, job.ID)
+	if restart != nil {
+		record.RestartOf = job.Run
+		restart.Dir = "runs/" + strconv.Itoa(record.Number) + "/"
+		restart.Period = strconv.Itoa(record.Number)
+		continuations[job.ID] = *restart
+	}
+	job.Status = string(JobStatusRunning)
+	job.Run = record.Number
+	record.PlaybackURLs = streamer.PlaybackURLs(job, job.Run)
+	runs[job.ID] = append(runs[job.ID], record)
+	if _, exists := jobs[job.ID]; exists {
+		jobs[job.ID] = *job
+	}
+	run := job.Run
+	mu.Unlock()
+
 	sp := streamer.NewStreamingProcess(job)
+	var firstSegment sync.Once
+	sp.OnSegment = func(seg models.SegmentInfo) {
+		firstSegment.Do(func() { notify(job.ID, models.WebhookEventFirstSegment) })
+		updateRun(job.ID, run, func(r *models.Run) { r.Segments++ })
+		events.Publish(models.JobEvent{Type: models.JobEventSegment, JobID: job.ID, Segment: &seg})
+	}
+	sp.OnStall = func() { notify(job.ID, models.WebhookEventStalled) }
+	sp.OnStats = func(stats models.EncoderStats) {
+		updateRun(job.ID, run, func(r *models.Run) { r.Stats = &stats })
+		events.Publish(models.JobEvent{Type: models.JobEventStats, JobID: job.ID, Stats: &stats})
+	}
+	sp.OnError = func(line string) {
+		events.Publish(models.JobEvent{Type: models.JobEventError, JobID: job.ID, Error: line})
+	}
+	sp.OnExit = func(err error) {
+		if err != nil {
+			failJob(job.ID, sp, err)
+		}
+	}
+	// Register the process first, ffmpeg may exit before StartJob returns
+	mu.Lock()
+	jobProcessMap[job.ID] = sp
+	mu.Unlock()
 	if err := sp.StartJob(); err != nil {
+		mu.Lock()
+		delete(jobProcessMap, job.ID)
+		endRun(job.ID, run, JobStatusFailed, err)
 		job.Status = string(JobStatusFailed)
+		job.CompletedAt = time.Now().Format(time.RFC3339)
+		if _, exists := jobs[job.ID]; exists {
+			jobs[job.ID] = *job
+		}
+		mu.Unlock()
+		publish(models.WebhookEventFailed, *job)
 		return err
 	}
-	jobProcessMap[job.ID] = sp
+	mu.Lock()
+	defer mu.Unlock()
+	if jobProcessMap[job.ID] != sp {
+		// failJob recorded the failure
+		return errors.New("streaming process exited right after starting")
+	}
 
 	// Update job status to running
+	startedAt := time.Now()
 	job.Status = string(JobStatusRunning)
-	job.StreamingStartedAt = time.Now().Format(time.RFC3339)
+	job.StreamingStartedAt = startedAt.Format(time.RFC3339)
+	scheduleStop(job, startedAt)
 	jobs[job.ID] = *job
 
+	// Continuously check the manifests ffmpeg produces for spec violations
+	if v, exists := validators[job.ID]; exists {
+		v.Stop()
+	}
+	v := validate.NewValidator(job.ID, sp.OutDir, job.Configuration.SegmentLength, startedAt)
+	v.Start(time.Duration(job.Configuration.SegmentLength) * time.Second)
+	validators[job.ID] = v
+
+	publish(models.WebhookEventStarted, *job)
+	if event := job.Configuration.Event; event != nil {
+		// Announce the phase changes, they happen inside ffmpeg
+		for _, at := range []int{event.PreRollSeconds, event.PreRollSeconds + event.LiveSeconds} {
+			if at > 0 {
+				time.AfterFunc(time.Until(startedAt.Add(time.Duration(at)*time.Second)), func() {
+					publishPhase(job.ID, job.StreamingStartedAt)
+				})
+			}
+		}
+	}
 	return nil
 }
 
+// failJob marks a job whose ffmpeg exited unexpectedly as failed.
+func failJob(jobID string, sp *streamer.StreamingProcess, err error) {
+	mu.Lock()
+	if jobProcessMap[jobID] != sp {
+		mu.Unlock()
+		return // An earlier run of the job
+	}
+	delete(jobProcessMap, jobID)
+	v, validating := validators[jobID]
+	job, exists := jobs[jobID]
+	if exists {
+		job.Status = string(JobStatusFailed)
+		job.CompletedAt = time.Now().Format(time.RFC3339)
+		endRun(jobID, job.Run, JobStatusFailed, err)
+		// Recurring jobs try again on their next run
+		scheduleStart(&job)
+		jobs[jobID] = job
+	}
+	mu.Unlock()
+
+	slog.Error("Streaming process failed", "jobID", jobID, "error", err)
+	sp.StopJob() // Stops monitoring the output directory
+	if validating {
+		v.Stop()
+	}
+	if exists {
+		publish(models.WebhookEventFailed, job)
+	}
+}
+
+// notify announces a transition with the current state of a job.
+func notify(jobID string, event models.WebhookEvent) {
+	if job, exists := GetJob(jobID); exists {
+		publish(event, *job)
+	}
+}
+
+// publishPhase announces the phase change of an event job, unless the run
+// that started at startedAt is over.
+func publishPhase(jobID, startedAt string) {
+	job, exists := GetJob(jobID)
+	if !exists || job.Status != string(JobStatusRunning) || job.StreamingStartedAt != startedAt {
+		return
+	}
+	publish(models.WebhookEventPhaseChanged, *job)
+}
+
+// publish announces a transition of a job to its webhooks and event streams.
+func publish(transition models.WebhookEvent, job models.Job) {
+	setPhase(&job)
+	// Webhooks have no request to derive the public URL from
+	job.PlaybackURLs = streamer.ResolvePlaybackURLs(job.PlaybackURLs, streamer.BaseURL())
+	webhook.Notify(transition, job)
+	events.Publish(models.JobEvent{
+		Type:  models.JobEventState,
+		JobID: job.ID,
+		State: &models.JobState{Transition: transition, Status: job.Status, Phase: job.Phase},
+	})
+}
+
+// StopAll stops the running jobs and returns once their encoders exited.
+func StopAll() {
+	mu.RLock()
+	running := runningJobs()
+	mu.RUnlock()
+	var wg sync.WaitGroup
+	for _, job := range running {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := StopJob(job.ID); err != nil {
+				slog.Error("Failed to stop job", "jobID", job.ID, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// StopJob stops a running job, or takes a queued job out of the queue.
 func StopJob(jobID string) error {
+	return stopJob(jobID, nil)
+}
+
+// stopJob stops the encoder of a job so it finalizes its output. A cause
+// marks the job failed, as it was stopped before the end of its run. Jobs
+// that are not running are left as they are.
+func stopJob(jobID string, cause error) error {
+	if dequeue(jobID) {
+		return nil
+	}
+	mu.Lock()
+	awaitRestart(jobID) // The restarted encoder is stopped below
 	sp, exists := jobProcessMap[jobID]
-	if !exists {
-		return nil // Job not found
+	job, found := jobs[jobID]
+	mu.Unlock()
+	if !exists || !found || job.Status != string(JobStatusRunning) {
+		return nil // Not running
 	}
 
 	// Terminate the streaming process
 	if err := sp.StopJob(); err != nil {
 		return err
 	}
+	mu.Lock()
+	if jobProcessMap[jobID] != sp {
+		_, restarting := restarts[jobID]
+		mu.Unlock()
+		if restarting {
+			// An update took the encoder over, stop the one it restarts
+			return stopJob(jobID, cause)
+		}
+		return nil // Stopped concurrently, or deleted
+	}
+	delete(jobProcessMap, jobID)
+	v, validating := validators[jobID]
+	job, exists = jobs[jobID]
+	mu.Unlock()
+	if validating {
+		v.Stop()
+	}
 
 	// Update job status to completed
-	job, exists := jobs[jobID]
 	if !exists {
 		return nil // Job not found
 	}
-	job.Status = string(JobStatusCompleted)
+	var vodURLs []models.PlaybackURLs
+	if job.Configuration.VODOnStop {
+		vodURLs = finalizeVOD(&job, sp.OutDir)
+	}
+	status, transition := JobStatusCompleted, models.WebhookEventStopped
+	if cause != nil {
+		status, transition = JobStatusFailed, models.WebhookEventFailed
+	}
+	job.Status = string(status)
 	job.CompletedAt = time.Now().Format(time.RFC3339)
+	mu.Lock()
+	defer mu.Unlock()
+	endRun(jobID, job.Run, status, cause)
+	if r := runRecord(jobID, job.Run); r != nil {
+		r.PlaybackURLs = append(r.PlaybackURLs, vodURLs...)
+	}
+	// Recurring jobs wait for their next run
+	scheduleStart(&job)
 	jobs[jobID] = job
+	publish(transition, job)
 
 	return nil
 }
+
+// finalizeVOD writes the VOD manifests for a stopped job and adds their
+// playback URLs. It returns the URLs of the VOD in the directory of the run. A
+// failure leaves the live recording as it is.
+func finalizeVOD(job *models.Job, dir string) []models.PlaybackURLs {
+	// Segments written before this run started belong to an earlier run
+	since, _ := time.Parse(time.RFC3339, job.StreamingStartedAt)
+	if err := vod.Finalize(dir, job.Configuration.SegmentLength, since); err != nil {
+		slog.Error("Failed to finalize VOD", "jobID", job.ID, "error", err)
+		return nil
+	}
+	files := map[models.JobOutputFormat]string{models.JobOutputFormatDASH: vod.MPDFile}
+	if _, err := os.Stat(filepath.Join(dir, vod.MasterFile)); err == nil {
+		files[models.JobOutputFormatHLS] = vod.MasterFile
+	}
+	var runURLs []models.PlaybackURLs
+	for _, format := range []models.JobOutputFormat{models.JobOutputFormatDASH, models.JobOutputFormatHLS} {
+		file, ok := files[format]
+		if !ok {
+			continue
+		}
+		job.PlaybackURLs = append(job.PlaybackURLs, models.PlaybackURLs{
+			Format:  format,
+			Variant: models.PlaybackVariantVOD,
+			URL:     streamer.PlaybackURL(job.ID, 0, file),
+		})
+		runURLs = append(runURLs, models.PlaybackURLs{
+			Format:  format,
+			Variant: models.PlaybackVariantVOD,
+			URL:     streamer.PlaybackURL(job.ID, job.Run, file),
+		})
+	}
+	slog.Info("Finalized VOD", "jobID", job.ID, "urls", job.PlaybackURLs)
+	return runURLs
+}
+
+// GetJobHealth returns the latest manifest conformance report for a job.
+func GetJobHealth(id string) (*models.JobHealth, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if _, exists := jobs[id]; !exists {
+		return nil, false
+	}
+	v, exists := validators[id]
+	if !exists {
+		// The job has not been started yet, nothing to validate
+		return &models.JobHealth{
+			ID:     id,
+			Status: models.HealthStatusPending,
+			Issues: []models.HealthIssue{},
+		}, true
+	}
+	health := v.Health()
+	return &health, true
+}
+
+// GetContinuation returns how the manifests of a run of a job continue those
+// of the earlier runs, if it is the latest run and restarted an earlier one.
+func GetContinuation(id string, run int) (*transform.Continuation, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, exists := continuations[id]
+	if !exists || jobs[id].Run != run {
+		return nil, false
+	}
+	return &c, true
+}
+
+// GetRuns returns the runs of a job, oldest first.
+func GetRuns(id string) ([]models.Run, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if _, exists := jobs[id]; !exists {
+		return nil, false
+	}
+	return append([]models.Run{}, runs[id]...), true
+}
+
+// runRecord returns the record of a run, if there is one. Callers must hold mu.
+func runRecord(id string, run int) *models.Run {
+	if run <= 0 || run > len(runs[id]) {
+		return nil
+	}
+	return &runs[id][run-1]
+}
+
+// updateRun changes the record of a run. Callers must not hold mu.
+func updateRun(id string, run int, fn func(*models.Run)) {
+	mu.Lock()
+	defer mu.Unlock()
+	if r := runRecord(id, run); r != nil {
+		fn(r)
+	}
+}
+
+// endRun records the end of a run, err is why it failed. Callers must hold mu.
+func endRun(id string, run int, status JobStatus, err error) {
+	r := runRecord(id, run)
+	if r == nil {
+		return
+	}
+	r.Status = string(status)
+	r.CompletedAt = time.Now().Format(time.RFC3339)
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
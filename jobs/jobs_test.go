@@ -1,12 +1,23 @@
 package jobs
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/arunjeyaprasad/golive/config"
 	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/resources"
 	"github.com/arunjeyaprasad/golive/streamer"
+	"github.com/arunjeyaprasad/golive/transform"
+	"github.com/arunjeyaprasad/golive/validate"
 )
 
 func setup() {
@@ -14,6 +25,19 @@ func setup() {
 	jobs = make(map[string]models.Job)
 	// Clear the jobProcessMap before each test
 	jobProcessMap = make(map[string]*streamer.StreamingProcess)
+	// Clear the validators map before each test
+	validators = make(map[string]*validate.Validator)
+	// Clear the run records before each test
+	runs = make(map[string][]models.Run)
+	// Clear the restarts before each test
+	restarts = make(map[string]chan struct{})
+	continuations = make(map[string]transform.Continuation)
+	// Clear the resource queue before each test
+	queue = nil
+	// Clear the scheduled timers before each test
+	for id := range timers {
+		cancelTimer(id)
+	}
 }
 
 func TestStopJob(t *testing.T) {
@@ -260,3 +284,652 @@ func TestDeleteJob(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateJob_Scheduled(t *testing.T) {
+	startAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name    string
+		request models.JobCreateRequest
+		want    *models.ScheduledRun
+	}{
+		{
+			name:    "Unscheduled job",
+			request: models.JobCreateRequest{Description: "Test job"},
+		},
+		{
+			name: "Job with start time and duration",
+			request: models.JobCreateRequest{
+				Description: "Test scheduled job",
+				JobSchedule: models.JobSchedule{StartAt: startAt.Format(time.RFC3339), Duration: 600},
+			},
+			want: &models.ScheduledRun{
+				StartAt: startAt.Format(time.RFC3339),
+				StopAt:  startAt.Add(10 * time.Minute).Format(time.RFC3339),
+			},
+		},
+	}
+	setup()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CreateJob(tt.request)
+			if !reflect.DeepEqual(got.ScheduledRun, tt.want) {
+				t.Errorf("CreateJob() scheduled run = %+v, want %+v", got.ScheduledRun, tt.want)
+			}
+			if _, armed := timers[got.ID]; armed != (tt.want != nil) {
+				t.Errorf("CreateJob() armed a timer = %v, want %v", armed, tt.want != nil)
+			}
+			DeleteJob(got.ID)
+			if _, armed := timers[got.ID]; armed {
+				t.Errorf("DeleteJob() left the scheduled start armed")
+			}
+		})
+	}
+}
+
+func TestSetPhase(t *testing.T) {
+	event := &models.EventConfig{PreRollSeconds: 60, LiveSeconds: 600, PostRollSeconds: 30}
+	tests := []struct {
+		name string
+		job  models.Job
+		want models.EventPhase
+	}{
+		{
+			name: "Not an event",
+			job:  models.Job{Status: string(JobStatusRunning), StreamingStartedAt: time.Now().Format(time.RFC3339)},
+		},
+		{
+			name: "Event not started",
+			job:  models.Job{Status: string(JobStatusCreated), Configuration: models.JobCreateRequest{Event: event}},
+		},
+		{
+			name: "Event in pre-roll",
+			job: models.Job{
+				Status:             string(JobStatusRunning),
+				StreamingStartedAt: time.Now().Add(-10 * time.Second).Format(time.RFC3339),
+				Configuration:      models.JobCreateRequest{Event: event},
+			},
+			want: models.EventPhasePreEvent,
+		},
+		{
+			name: "Event live",
+			job: models.Job{
+				Status:             string(JobStatusRunning),
+				StreamingStartedAt: time.Now().Add(-2 * time.Minute).Format(time.RFC3339),
+				Configuration:      models.JobCreateRequest{Event: event},
+			},
+			want: models.EventPhaseLive,
+		},
+		{
+			name: "Event stopped",
+			job:  models.Job{Status: string(JobStatusCompleted), Configuration: models.JobCreateRequest{Event: event}},
+			want: models.EventPhaseEnded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPhase(&tt.job)
+			if tt.job.Phase != tt.want {
+				t.Errorf("setPhase() phase = %v, want %v", tt.job.Phase, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateJob(t *testing.T) {
+	setup()
+	job := CreateJob(models.JobCreateRequest{Description: "Test job"})
+	defer DeleteJob(job.ID)
+
+	startAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	configuration := job.Configuration
+	configuration.StartAt = startAt.Format(time.RFC3339)
+	got, err := UpdateJob(job.ID, map[string]json.RawMessage{"start_at": json.RawMessage(`"` + configuration.StartAt + `"`)})
+	if err != nil {
+		t.Fatalf("UpdateJob() error = %v", err)
+	}
+	want := []models.FieldUpdate{{Field: "start_at", Mode: models.UpdateModeHot}}
+	if !reflect.DeepEqual(got.Changes, want) || got.Restarted {
+		t.Errorf("UpdateJob() changes = %+v, restarted = %v, want %+v without a restart", got.Changes, got.Restarted, want)
+	}
+	if got.Job.ScheduledRun == nil || got.Job.ScheduledRun.StartAt != configuration.StartAt {
+		t.Errorf("UpdateJob() scheduled run = %+v, want a start at %s", got.Job.ScheduledRun, configuration.StartAt)
+	}
+	if _, armed := timers[job.ID]; !armed {
+		t.Errorf("UpdateJob() did not arm the scheduled start")
+	}
+	if stored, _ := GetJob(job.ID); stored.Configuration.StartAt != configuration.StartAt {
+		t.Errorf("GetJob() start_at = %q, want the updated one", stored.Configuration.StartAt)
+	}
+
+	var invalid *InvalidUpdateError
+	if _, err := UpdateJob(job.ID, map[string]json.RawMessage{"window_size": json.RawMessage("-1")}); !errors.As(err, &invalid) {
+		t.Errorf("UpdateJob() of an invalid patch error = %v, want *InvalidUpdateError", err)
+	}
+	if _, err := UpdateJob("missing", nil); err != ErrJobNotFound {
+		t.Errorf("UpdateJob() of a missing job error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestUpdateJob_Concurrent(t *testing.T) {
+	setup()
+	job := CreateJob(models.JobCreateRequest{Description: "Test job"})
+	defer DeleteJob(job.ID)
+
+	// Each update merges into the configuration the others left
+	labels := []string{"a", "b", "c", "d"}
+	var wg sync.WaitGroup
+	for _, label := range labels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			patch := map[string]json.RawMessage{"labels": json.RawMessage(`{"` + label + `": "set"}`)}
+			if _, err := UpdateJob(job.ID, patch); err != nil {
+				t.Errorf("UpdateJob() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	stored, _ := GetJob(job.ID)
+	for _, label := range labels {
+		if stored.Configuration.Labels[label] != "set" {
+			t.Errorf("labels = %v, want %s kept", stored.Configuration.Labels, label)
+		}
+	}
+}
+
+func TestCloneJob(t *testing.T) {
+	setup()
+	source := CreateJob(models.JobCreateRequest{
+		Description: "Source",
+		VideoTrack:  &models.VideoTrack{BitRate: "2M"},
+	})
+	defer DeleteJob(source.ID)
+
+	clone, ok := CloneJob(source.ID)
+	if !ok {
+		t.Fatal("CloneJob() did not find the job")
+	}
+	defer DeleteJob(clone.ID)
+	if clone.ID == source.ID || clone.ClonedFrom != source.ID || clone.Status != string(JobStatusCreated) {
+		t.Errorf("CloneJob() = %+v, want a new created job cloned from %s", clone, source.ID)
+	}
+	if !reflect.DeepEqual(clone.Configuration, source.Configuration) {
+		t.Errorf("CloneJob() config = %+v, want %+v", clone.Configuration, source.Configuration)
+	}
+	// The copy does not share the tracks of the source
+	clone.Configuration.VideoTrack.BitRate = "4M"
+	if stored, _ := GetJob(source.ID); stored.Configuration.VideoTrack.BitRate != "2M" {
+		t.Errorf("changing the clone changed the source bitrate to %s", stored.Configuration.VideoTrack.BitRate)
+	}
+
+	if _, ok := CloneJob("missing"); ok {
+		t.Error("CloneJob() of a missing job succeeded")
+	}
+}
+
+func TestRuns(t *testing.T) {
+	setup()
+	job := CreateJob(models.JobCreateRequest{Description: "Test job"})
+	defer DeleteJob(job.ID)
+	runs[job.ID] = []models.Run{
+		{Number: 1, Status: string(JobStatusRunning)},
+		{Number: 2, Status: string(JobStatusRunning)},
+	}
+
+	updateRun(job.ID, 2, func(r *models.Run) { r.Segments++ })
+	updateRun(job.ID, 3, func(r *models.Run) { t.Error("updated a missing run") })
+	mu.Lock()
+	endRun(job.ID, 1, JobStatusCompleted, nil)
+	endRun(job.ID, 2, JobStatusFailed, errors.New("ffmpeg exited"))
+	mu.Unlock()
+
+	got, ok := GetRuns(job.ID)
+	if !ok || len(got) != 2 {
+		t.Fatalf("GetRuns() = %+v, %v, want 2 runs", got, ok)
+	}
+	if got[0].Status != string(JobStatusCompleted) || got[0].CompletedAt == "" || got[0].Error != "" {
+		t.Errorf("run 1 = %+v, want completed", got[0])
+	}
+	if got[1].Status != string(JobStatusFailed) || got[1].Error != "ffmpeg exited" || got[1].Segments != 1 {
+		t.Errorf("run 2 = %+v, want failed with its error and segment", got[1])
+	}
+	if _, ok := GetRuns("missing"); ok {
+		t.Error("GetRuns() of a missing job succeeded")
+	}
+}
+
+func TestListJobs(t *testing.T) {
+	setup()
+	addListJobs()
+
+	tests := []struct {
+		name           string
+		query          models.JobListQuery
+		wantIDs        []string
+		wantTotal      int
+		wantNextOffset int
+	}{
+		{
+			name:      "All by creation",
+			query:     models.JobListQuery{Limit: 10},
+			wantIDs:   []string{"a", "b", "c", "d"},
+			wantTotal: 4,
+		},
+		{
+			name:      "Newest first",
+			query:     models.JobListQuery{Sort: "-created", Limit: 10},
+			wantIDs:   []string{"c", "d", "b", "a"},
+			wantTotal: 4,
+		},
+		{
+			name:      "Status and codec",
+			query:     models.JobListQuery{Statuses: []string{"running", "error"}, Codecs: []string{"HEVC"}, Limit: 10},
+			wantIDs:   []string{"a", "c"},
+			wantTotal: 2,
+		},
+		{
+			name:      "Audio codec",
+			query:     models.JobListQuery{Codecs: []string{"aac"}, Limit: 10},
+			wantIDs:   []string{"a", "b", "c", "d"},
+			wantTotal: 4,
+		},
+		{
+			name:      "Description and creation",
+			query:     models.JobListQuery{Query: "LAB", CreatedAfter: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), Sort: "description", Limit: 10},
+			wantIDs:   []string{"b", "d"},
+			wantTotal: 2,
+		},
+		{
+			name:      "Owner",
+			query:     models.JobListQuery{Owners: []string{"player-web"}, Limit: 10},
+			wantIDs:   []string{"b", "c"},
+			wantTotal: 2,
+		},
+		{
+			name:      "Labels",
+			query:     models.JobListQuery{Labels: map[string]string{"team": "player-web", "ci_run": ""}, Limit: 10},
+			wantIDs:   []string{"c"},
+			wantTotal: 1,
+		},
+		{
+			name:           "First page",
+			query:          models.JobListQuery{Limit: 3},
+			wantIDs:        []string{"a", "b", "c"},
+			wantTotal:      4,
+			wantNextOffset: 3,
+		},
+		{
+			name:      "Last page",
+			query:     models.JobListQuery{Limit: 3, Offset: 3},
+			wantIDs:   []string{"d"},
+			wantTotal: 4,
+		},
+		{
+			name:      "Past the end",
+			query:     models.JobListQuery{Limit: 3, Offset: 10},
+			wantIDs:   []string{},
+			wantTotal: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ListJobs(tt.query)
+			ids := []string{}
+			for _, job := range got.Jobs {
+				ids = append(ids, job.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || got.Total != tt.wantTotal || got.NextOffset != tt.wantNextOffset {
+				t.Errorf("ListJobs() ids = %v, total = %d, next = %d, want %v, %d, %d", ids, got.Total, got.NextOffset, tt.wantIDs, tt.wantTotal, tt.wantNextOffset)
+			}
+		})
+	}
+}
+
+func TestBulkJobs(t *testing.T) {
+	setup()
+	addListJobs()
+
+	query := models.JobListQuery{Owners: []string{"player-web"}}
+	got := DeleteJobs(query)
+	want := models.BulkJobResponse{
+		Jobs:    []string{"c"},
+		Skipped: []models.SkippedJob{{ID: "b", Reason: "Job is not completed"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DeleteJobs() = %+v, want %+v", got, want)
+	}
+	if _, exists := GetJob("c"); exists {
+		t.Error("DeleteJobs() kept the failed job")
+	}
+
+	// Running jobs without an encoder, StopJob has nothing to stop
+	got = StopJobs(models.JobListQuery{Codecs: []string{"av1", "h264"}})
+	want = models.BulkJobResponse{
+		Jobs:    []string{"d"},
+		Skipped: []models.SkippedJob{{ID: "b", Reason: "Job is not running"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StopJobs() = %+v, want %+v", got, want)
+	}
+}
+
+// addListJobs adds jobs with the fields job lists filter by.
+func addListJobs() {
+	add := func(id, status, created, description, codec, owner string, labels map[string]string) {
+		jobs[id] = models.Job{ID: id, Status: status, CreatedAt: created, Configuration: models.JobCreateRequest{
+			Description: description,
+			VideoTrack:  &models.VideoTrack{Codec: codec},
+			AudioTrack:  &models.AudioTrack{AudioCodec: "aac"},
+			Owner:       owner,
+			Labels:      labels,
+		}}
+	}
+	add("a", "running", "2026-01-01T10:00:00Z", "Lab A soak", "hevc", "lab", nil)
+	add("b", "created", "2026-01-02T10:00:00Z", "Lab B", "h264", "player-web", map[string]string{"team": "player-web"})
+	add("c", "error", "2026-01-03T10:00:00Z", "Player test", "hevc", "player-web", map[string]string{"team": "player-web", "ci_run": "8812"})
+	add("d", "running", "2026-01-03T10:00:00Z", "lab D", "av1", "", map[string]string{"ci_run": "8813"})
+}
+
+func TestSweepOrphans(t *testing.T) {
+	setup()
+	mediaDir := config.DEFAULT_MEDIA_DIR
+	config.DEFAULT_MEDIA_DIR = t.TempDir()
+	defer func() { config.DEFAULT_MEDIA_DIR = mediaDir }()
+
+	job := CreateJob(models.JobCreateRequest{Description: "Test job"})
+	defer DeleteJob(job.ID)
+	orphan := generateJobID()
+	for _, dir := range []string{job.ID, orphan, "not-a-job"} {
+		if err := os.MkdirAll(filepath.Join(config.DEFAULT_MEDIA_DIR, dir, "runs", "1"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := SweepOrphans(); !reflect.DeepEqual(got, []string{orphan}) {
+		t.Errorf("SweepOrphans() = %v, want %v", got, []string{orphan})
+	}
+	for dir, want := range map[string]bool{job.ID: true, orphan: false, "not-a-job": true} {
+		if _, err := os.Stat(filepath.Join(config.DEFAULT_MEDIA_DIR, dir)); (err == nil) != want {
+			t.Errorf("directory %s exists = %v, want %v", dir, err == nil, want)
+		}
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		maxBytes    int64
+		wantDeleted []string
+	}{
+		{
+			name: "No policy",
+		},
+		{
+			name:        "TTL",
+			ttl:         48 * time.Hour,
+			wantDeleted: []string{"old"},
+		},
+		{
+			name:        "Size limit evicts the oldest first",
+			maxBytes:    3500,
+			wantDeleted: []string{"old", "recent"},
+		},
+		{
+			name:        "TTL and size limit",
+			ttl:         48 * time.Hour,
+			maxBytes:    4500,
+			wantDeleted: []string{"old"},
+		},
+		{
+			name:        "Running and created jobs are kept",
+			maxBytes:    1,
+			wantDeleted: []string{"old", "recent", "failed"},
+		},
+	}
+	defer func(ttl time.Duration, maxBytes int64, mediaDir string) {
+		config.RETENTION_TTL, config.MAX_MEDIA_BYTES, config.DEFAULT_MEDIA_DIR = ttl, maxBytes, mediaDir
+	}(config.RETENTION_TTL, config.MAX_MEDIA_BYTES, config.DEFAULT_MEDIA_DIR)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup()
+			config.RETENTION_TTL, config.MAX_MEDIA_BYTES = tt.ttl, tt.maxBytes
+			config.DEFAULT_MEDIA_DIR = t.TempDir()
+			add := func(id, status string, stopped time.Time) {
+				jobs[id] = models.Job{ID: id, Status: status, CompletedAt: stopped.Format(time.RFC3339)}
+				dir := filepath.Join(config.DEFAULT_MEDIA_DIR, id, "runs", "1")
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "chunk.m4s"), make([]byte, 1000), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			add("old", "completed", now.Add(-72*time.Hour))
+			add("recent", "completed", now.Add(-time.Hour))
+			add("failed", "error", now.Add(-time.Minute))
+			add("live", "running", time.Time{})
+			add("new", "created", time.Time{})
+
+			if got := ApplyRetention(now); !reflect.DeepEqual(got, tt.wantDeleted) {
+				t.Errorf("ApplyRetention() = %v, want %v", got, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestApplyRetention_Runs(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		maxBytes    int64
+		wantExpired []int
+	}{
+		{name: "TTL per run", ttl: 48 * time.Hour, wantExpired: []int{1}},
+		{name: "Size limit evicts the oldest runs first", maxBytes: 2500, wantExpired: []int{1, 2}},
+		{name: "The current run is kept", maxBytes: 1, wantExpired: []int{1, 2, 3}},
+	}
+	defer func(ttl time.Duration, maxBytes int64, mediaDir string) {
+		config.RETENTION_TTL, config.MAX_MEDIA_BYTES, config.DEFAULT_MEDIA_DIR = ttl, maxBytes, mediaDir
+	}(config.RETENTION_TTL, config.MAX_MEDIA_BYTES, config.DEFAULT_MEDIA_DIR)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup()
+			config.RETENTION_TTL, config.MAX_MEDIA_BYTES = tt.ttl, tt.maxBytes
+			config.DEFAULT_MEDIA_DIR = t.TempDir()
+			// A recurring job waiting for its fifth run
+			const id = "recurring"
+			jobs[id] = models.Job{ID: id, Status: string(JobStatusCompleted), Run: 4, CompletedAt: now.Add(-time.Minute).Format(time.RFC3339)}
+			mu.Lock()
+			armTimer(id, now.Add(24*time.Hour), func(string) {})
+			mu.Unlock()
+			for run, stopped := range []time.Duration{72 * time.Hour, 24 * time.Hour, time.Hour, time.Minute} {
+				runs[id] = append(runs[id], models.Run{
+					Number:       run + 1,
+					Status:       string(JobStatusCompleted),
+					CompletedAt:  now.Add(-stopped).Format(time.RFC3339),
+					PlaybackURLs: streamer.PlaybackURLs(&models.Job{ID: id}, run+1),
+				})
+				dir := streamer.RunDir(id, run+1)
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "chunk.m4s"), make([]byte, 1000), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := ApplyRetention(now); len(got) != 0 {
+				t.Errorf("ApplyRetention() = %v, want the scheduled job kept", got)
+			}
+			var expired []int
+			for _, r := range runs[id] {
+				_, err := os.Stat(streamer.RunDir(id, r.Number))
+				if r.Expired {
+					expired = append(expired, r.Number)
+					if err == nil || r.PlaybackURLs != nil {
+						t.Errorf("expired run %d still has its output or playback URLs", r.Number)
+					}
+				} else if err != nil {
+					t.Errorf("run %d lost its output: %v", r.Number, err)
+				}
+			}
+			if !reflect.DeepEqual(expired, tt.wantExpired) {
+				t.Errorf("expired runs = %v, want %v", expired, tt.wantExpired)
+			}
+		})
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	// Nothing can run 4K60 AV1 in real time, an empty job takes nothing
+	huge := models.JobCreateRequest{
+		VideoTrack: &models.VideoTrack{BitRate: "20M", Resolution: "3840x2160", Framerate: "60", Codec: "av1"},
+	}
+	tests := []struct {
+		name       string
+		policy     string
+		maxJobs    int
+		running    int // Jobs already running
+		starting   int // Jobs admitted whose encoder is not registered yet
+		first      models.JobCreateRequest
+		second     models.JobCreateRequest
+		wantFirst  error
+		wantSecond error
+		wantQueue  []string
+	}{
+		{
+			name:   "Off",
+			policy: config.ResourcePolicyOff,
+			first:  huge,
+		},
+		{
+			name:      "Reject",
+			policy:    config.ResourcePolicyReject,
+			first:     huge,
+			wantFirst: &resources.InsufficientError{},
+		},
+		{
+			name:       "Queue keeps the order",
+			policy:     config.ResourcePolicyQueue,
+			first:      huge,
+			wantFirst:  ErrJobQueued,
+			wantSecond: ErrJobQueued,
+			wantQueue:  []string{"first", "second"},
+		},
+		{
+			name:       "Job limit applies with the policy off",
+			policy:     config.ResourcePolicyOff,
+			maxJobs:    2,
+			running:    2,
+			wantFirst:  &resources.InsufficientError{},
+			wantSecond: &resources.InsufficientError{},
+		},
+		{
+			name:       "Admitted jobs count before their encoder runs",
+			policy:     config.ResourcePolicyReject,
+			maxJobs:    1,
+			starting:   1,
+			wantFirst:  &resources.InsufficientError{},
+			wantSecond: &resources.InsufficientError{},
+		},
+		{
+			name:       "Job limit queues",
+			policy:     config.ResourcePolicyQueue,
+			maxJobs:    1,
+			running:    1,
+			wantFirst:  ErrJobQueued,
+			wantSecond: ErrJobQueued,
+			wantQueue:  []string{"first", "second"},
+		},
+	}
+	defer func(policy string, reserve int64, maxJobs int) {
+		config.RESOURCE_POLICY, config.MIN_FREE_DISK_BYTES, config.MAX_JOB_COUNT = policy, reserve, maxJobs
+	}(config.RESOURCE_POLICY, config.MIN_FREE_DISK_BYTES, config.MAX_JOB_COUNT)
+	config.MIN_FREE_DISK_BYTES = 0
+	check := func(t *testing.T, name string, err, want error) {
+		t.Helper()
+		var insufficient *resources.InsufficientError
+		switch want.(type) {
+		case nil:
+			if err != nil {
+				t.Errorf("admit(%s) error = %v, want nil", name, err)
+			}
+		case *resources.InsufficientError:
+			if !errors.As(err, &insufficient) {
+				t.Errorf("admit(%s) error = %v, want *resources.InsufficientError", name, err)
+			}
+		default:
+			if !errors.Is(err, want) {
+				t.Errorf("admit(%s) error = %v, want %v", name, err, want)
+			}
+		}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup()
+			config.RESOURCE_POLICY, config.MAX_JOB_COUNT = tt.policy, tt.maxJobs
+			for i := range tt.running {
+				id := fmt.Sprintf("running-%d", i)
+				jobs[id] = models.Job{ID: id, Status: string(JobStatusRunning)}
+				jobProcessMap[id] = &streamer.StreamingProcess{}
+			}
+			for i := range tt.starting {
+				id := fmt.Sprintf("starting-%d", i)
+				jobs[id] = models.Job{ID: id, Status: string(JobStatusRunning)}
+			}
+			first := models.Job{ID: "first", Status: string(JobStatusCreated), Configuration: tt.first}
+			second := models.Job{ID: "second", Status: string(JobStatusCreated), Configuration: tt.second}
+			jobs[first.ID], jobs[second.ID] = first, second
+			mu.Lock()
+			check(t, first.ID, admit(&first), tt.wantFirst)
+			check(t, second.ID, admit(&second), tt.wantSecond)
+			mu.Unlock()
+			if !reflect.DeepEqual(queue, tt.wantQueue) {
+				t.Errorf("queue = %v, want %v", queue, tt.wantQueue)
+			}
+			for _, id := range tt.wantQueue {
+				if jobs[id].Status != string(JobStatusQueued) {
+					t.Errorf("Status of %s = %s, want %s", id, jobs[id].Status, JobStatusQueued)
+				}
+			}
+		})
+	}
+}
+
+func TestStopQueuedJob(t *testing.T) {
+	setup()
+	queue = []string{"queued"}
+	jobs["queued"] = models.Job{ID: "queued", Status: string(JobStatusQueued)}
+	if err := StopJob("queued"); err != nil {
+		t.Fatalf("StopJob() error = %v", err)
+	}
+	if len(queue) != 0 {
+		t.Errorf("queue = %v, want empty", queue)
+	}
+	if got := jobs["queued"].Status; got != string(JobStatusCreated) {
+		t.Errorf("Status = %s, want %s", got, JobStatusCreated)
+	}
+}
+
+func TestDeleteJob_StoppedOnly(t *testing.T) {
+	setup()
+	jobs["scheduled"] = models.Job{ID: "scheduled", Status: string(JobStatusCompleted)}
+	mu.Lock()
+	armTimer("scheduled", time.Now().Add(time.Hour), func(string) {})
+	mu.Unlock()
+	if deleteJob("scheduled", true) {
+		t.Error("deleteJob() deleted a job waiting for a scheduled run")
+	}
+	mu.Lock()
+	cancelTimer("scheduled")
+	mu.Unlock()
+	if !deleteJob("scheduled", true) {
+		t.Error("deleteJob() kept a stopped job")
+	}
+	if _, exists := GetJob("scheduled"); exists {
+		t.Error("deleted job still exists")
+	}
+}
@@ -0,0 +1,148 @@
+package jobs
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// ListJobs returns the page of the jobs matching a query.
+func ListJobs(q models.JobListQuery) models.JobList {
+	matching := find(q)
+	list := models.JobList{Jobs: []models.Job{}, Total: len(matching), Offset: q.Offset, Limit: q.Limit}
+	if q.Offset < len(matching) {
+		end := min(q.Offset+q.Limit, len(matching))
+		list.Jobs = matching[q.Offset:end]
+		if end < len(matching) {
+			list.NextOffset = end
+		}
+	}
+	return list
+}
+
+// StopJobs stops the running and queued jobs matching a query, regardless
+// of its page.
+func StopJobs(q models.JobListQuery) models.BulkJobResponse {
+	response := models.BulkJobResponse{Jobs: []string{}, Skipped: []models.SkippedJob{}}
+	for _, job := range find(q) {
+		if job.Status != string(JobStatusRunning) && job.Status != string(JobStatusQueued) {
+			response.Skipped = append(response.Skipped, models.SkippedJob{ID: job.ID, Reason: "Job is not running"})
+			continue
+		}
+		if err := StopJob(job.ID); err != nil {
+			slog.Error("Failed to stop job", "jobID", job.ID, "error", err)
+			response.Skipped = append(response.Skipped, models.SkippedJob{ID: job.ID, Reason: "Failed to stop job"})
+			continue
+		}
+		response.Jobs = append(response.Jobs, job.ID)
+	}
+	return response
+}
+
+// DeleteJobs deletes the completed and failed jobs matching a query,
+// regardless of its page. Like a single delete it leaves the others.
+func DeleteJobs(q models.JobListQuery) models.BulkJobResponse {
+	response := models.BulkJobResponse{Jobs: []string{}, Skipped: []models.SkippedJob{}}
+	for _, job := range find(q) {
+		if job.Status != string(JobStatusCompleted) && job.Status != string(JobStatusFailed) {
+			response.Skipped = append(response.Skipped, models.SkippedJob{ID: job.ID, Reason: "Job is not completed"})
+			continue
+		}
+		DeleteJob(job.ID)
+		response.Jobs = append(response.Jobs, job.ID)
+	}
+	return response
+}
+
+// find returns the jobs matching the filters of a query, sorted.
+func find(q models.JobListQuery) []models.Job {
+	matching := []models.Job{}
+	for _, job := range GetJobs() {
+		if matches(q, job) {
+			matching = append(matching, job)
+		}
+	}
+	sortJobs(matching, q.Sort)
+	return matching
+}
+
+// matches reports whether a job passes the filters of a query.
+func matches(q models.JobListQuery, job models.Job) bool {
+	if len(q.Statuses) > 0 && !containsFold(q.Statuses, job.Status) {
+		return false
+	}
+	if len(q.Codecs) > 0 {
+		var codecs []string
+		if v := job.Configuration.VideoTrack; v != nil {
+			codecs = append(codecs, v.Codec)
+		}
+		if a := job.Configuration.AudioTrack; a != nil {
+			codecs = append(codecs, a.AudioCodec)
+		}
+		found := false
+		for _, codec := range codecs {
+			found = found || containsFold(q.Codecs, codec)
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(q.Owners) > 0 && !containsFold(q.Owners, job.Configuration.Owner) {
+		return false
+	}
+	if !models.MatchLabels(job.Configuration.Labels, q.Labels) {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() || !q.CreatedBefore.IsZero() {
+		created, err := time.Parse(time.RFC3339, job.CreatedAt)
+		if err != nil {
+			return false
+		}
+		if !q.CreatedAfter.IsZero() && !created.After(q.CreatedAfter) {
+			return false
+		}
+		if !q.CreatedBefore.IsZero() && !created.Before(q.CreatedBefore) {
+			return false
+		}
+	}
+	if q.Query != "" && !strings.Contains(strings.ToLower(job.Configuration.Description), strings.ToLower(q.Query)) {
+		return false
+	}
+	return true
+}
+
+// sortJobs sorts jobs by a field of models.JobSortFields, by creation if it
+// is empty. Ties keep the order of the ids so pages are stable.
+func sortJobs(list []models.Job, by string) {
+	field, descending := strings.CutPrefix(by, "-")
+	key := func(job models.Job) string {
+		switch field {
+		case "status":
+			return job.Status
+		case "description":
+			return strings.ToLower(job.Configuration.Description)
+		default:
+			// Sorts like the times, all are formatted in the zone of the server
+			return job.CreatedAt
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := key(list[i]), key(list[j])
+		if a == b {
+			return list[i].ID < list[j].ID
+		}
+		return (a < b) != descending
+	})
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
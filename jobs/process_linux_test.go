@@ -0,0 +1,290 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/resources"
+	"github.com/arunjeyaprasad/golive/streamer"
+	"github.com/arunjeyaprasad/golive/transform"
+)
+
+// fakeFFmpeg puts an ffmpeg on PATH that runs until it is interrupted, and
+// gives the jobs an empty media directory.
+func fakeFFmpeg(t *testing.T) {
+	t.Helper()
+	setup()
+	bin := t.TempDir()
+	script := "#!/bin/sh\ntrap 'exit 0' INT TERM\nwhile :; do sleep 0.1; done\n"
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	mediaDir, policy, maxJobs := config.DEFAULT_MEDIA_DIR, config.RESOURCE_POLICY, config.MAX_JOB_COUNT
+	config.DEFAULT_MEDIA_DIR = t.TempDir()
+	config.RESOURCE_POLICY = config.ResourcePolicyOff
+	t.Cleanup(func() {
+		StopAll()
+		config.DEFAULT_MEDIA_DIR, config.RESOURCE_POLICY, config.MAX_JOB_COUNT = mediaDir, policy, maxJobs
+	})
+}
+
+// newJob creates a job with the default configuration.
+func newJob(t *testing.T) *models.Job {
+	t.Helper()
+	var request models.JobCreateRequest
+	if err := request.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return CreateJob(request)
+}
+
+func TestStopJob_Running(t *testing.T) {
+	fakeFFmpeg(t)
+	job := newJob(t)
+	if err := StartJob(job); err != nil {
+		t.Fatalf("StartJob() error = %v", err)
+	}
+	if err := StopJob(job.ID); err != nil {
+		t.Fatalf("StopJob() error = %v", err)
+	}
+	if _, running := jobProcessMap[job.ID]; running {
+		t.Error("stopped job is still in jobProcessMap")
+	}
+	stopped, _ := GetJob(job.ID)
+	if stopped.Status != string(JobStatusCompleted) {
+		t.Errorf("Status = %s, want %s", stopped.Status, JobStatusCompleted)
+	}
+
+	// Stopping again, or at shutdown, leaves the stopped job as it is
+	stopped.CompletedAt = "stopped"
+	jobs[job.ID] = *stopped
+	runs[job.ID][0].CompletedAt = "stopped"
+	if err := StopJob(job.ID); err != nil {
+		t.Fatalf("StopJob() of a stopped job error = %v", err)
+	}
+	StopAll()
+	if got, _ := GetJob(job.ID); got.Status != string(JobStatusCompleted) || got.CompletedAt != "stopped" {
+		t.Errorf("job = %s completed at %s, want it left as it was", got.Status, got.CompletedAt)
+	}
+	if got := runs[job.ID][0].CompletedAt; got != "stopped" {
+		t.Errorf("run completed at %s, want it left as it was", got)
+	}
+}
+
+func TestStartJob_Concurrent(t *testing.T) {
+	fakeFFmpeg(t)
+	job := newJob(t)
+	const starts = 4
+	errs := make([]error, starts)
+	var wg sync.WaitGroup
+	for i := range starts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			copied := *job
+			errs[i] = StartJob(&copied)
+		}()
+	}
+	wg.Wait()
+	started := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			started++
+		case !errors.Is(err, ErrJobRunning):
+			t.Errorf("StartJob() error = %v, want nil or ErrJobRunning", err)
+		}
+	}
+	if started != 1 {
+		t.Errorf("%d starts succeeded, want 1", started)
+	}
+	if got := len(runs[job.ID]); got != 1 {
+		t.Errorf("%d runs, want 1", got)
+	}
+}
+
+func TestApplyRetention_StoppedJob(t *testing.T) {
+	fakeFFmpeg(t)
+	ttl := config.RETENTION_TTL
+	config.RETENTION_TTL = time.Hour
+	t.Cleanup(func() { config.RETENTION_TTL = ttl })
+	job := newJob(t)
+	if err := StartJob(job); err != nil {
+		t.Fatalf("StartJob() error = %v", err)
+	}
+	if err := StopJob(job.ID); err != nil {
+		t.Fatalf("StopJob() error = %v", err)
+	}
+	if got := ApplyRetention(time.Now().Add(2 * time.Hour)); !reflect.DeepEqual(got, []string{job.ID}) {
+		t.Errorf("ApplyRetention() = %v, want the stopped job %s", got, job.ID)
+	}
+	if _, exists := GetJob(job.ID); exists {
+		t.Error("stopped job past its retention still exists")
+	}
+}
+
+func TestCheckDisk(t *testing.T) {
+	fakeFFmpeg(t)
+	reserve := config.MIN_FREE_DISK_BYTES
+	t.Cleanup(func() { config.MIN_FREE_DISK_BYTES = reserve })
+	stopped, running := newJob(t), newJob(t)
+	for _, job := range []*models.Job{stopped, running} {
+		if err := StartJob(job); err != nil {
+			t.Fatalf("StartJob() error = %v", err)
+		}
+	}
+	if err := StopJob(stopped.ID); err != nil {
+		t.Fatalf("StopJob() error = %v", err)
+	}
+	mu.Lock()
+	job := jobs[stopped.ID]
+	job.CompletedAt = "stopped"
+	jobs[stopped.ID] = job
+	mu.Unlock()
+
+	// No disk has this much free
+	config.MIN_FREE_DISK_BYTES = 1 << 62
+	checkDisk()
+	if got, _ := GetJob(stopped.ID); got.Status != string(JobStatusCompleted) || got.CompletedAt != "stopped" {
+		t.Errorf("stopped job = %s completed at %s, want it left as it was", got.Status, got.CompletedAt)
+	}
+	if got, _ := GetJob(running.ID); got.Status != string(JobStatusFailed) {
+		t.Errorf("running job = %s, want %s", got.Status, JobStatusFailed)
+	}
+}
+
+func TestStartJob_MaxJobCount(t *testing.T) {
+	fakeFFmpeg(t)
+	config.MAX_JOB_COUNT = 1
+	job := newJob(t)
+	if err := StartJob(job); err != nil {
+		t.Fatalf("StartJob() error = %v", err)
+	}
+	if err := StartJob(newJob(t)); err == nil {
+		t.Error("StartJob() of a second job succeeded, want the limit to be reached")
+	}
+	if err := StopJob(job.ID); err != nil {
+		t.Fatalf("StopJob() error = %v", err)
+	}
+	// The stopped job no longer counts against the limit
+	if err := StartJob(job); err != nil {
+		t.Errorf("StartJob() after stopping error = %v", err)
+	}
+}
+
+func TestUpdateJob_Restart(t *testing.T) {
+	fakeFFmpeg(t)
+	job := newJob(t)
+	if err := StartJob(job); err != nil {
+		t.Fatalf("StartJob() error = %v", err)
+	}
+	// What ffmpeg wrote before the restart
+	dir := streamer.RunDir(job.ID, 1)
+	playlist := "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:3\n#EXTINF:6.0,\nchunk-stream0-00003.m4s\n#EXTINF:6.0,\nchunk-stream0-00004.m4s\n"
+	mpd := `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="dynamic" availabilityStartTime="2025-06-07T14:30:00Z"><Period id="0" start="PT0.0S"></Period></MPD>`
+	for name, content := range map[string]string{"media_0.m3u8": playlist, "manifest.mpd": mpd} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for restart := 1; restart <= 2; restart++ {
+		patch := map[string]json.RawMessage{"video": json.RawMessage(fmt.Sprintf(`{"bitrate": "%dM"}`, restart+1))}
+		response, err := UpdateJob(job.ID, patch)
+		if err != nil {
+			t.Fatalf("UpdateJob() error = %v", err)
+		}
+		if !response.Restarted || response.Job.Run != restart+1 || response.Job.Status != string(JobStatusRunning) {
+			t.Fatalf("UpdateJob() = run %d %s restarted %v, want run %d running", response.Job.Run, response.Job.Status, response.Restarted, restart+1)
+		}
+	}
+	got, _ := GetRuns(job.ID)
+	for i, want := range []models.Run{
+		{Number: 1, Status: string(JobStatusCompleted)},
+		{Number: 2, Status: string(JobStatusCompleted), RestartOf: 1},
+		{Number: 3, Status: string(JobStatusRunning), RestartOf: 2},
+	} {
+		if got[i].Number != want.Number || got[i].Status != want.Status || got[i].RestartOf != want.RestartOf {
+			t.Errorf("run %d = %+v, want %+v", i+1, got[i], want)
+		}
+	}
+	want := transform.Continuation{
+		Dir:                   "runs/3/",
+		Period:                "3",
+		AvailabilityStartTime: "2025-06-07T14:30:00Z",
+		MediaSequence:         4, // The second run wrote no segments
+		Discontinuities:       2,
+	}
+	if c, ok := GetContinuation(job.ID, 3); !ok || *c != want {
+		t.Errorf("GetContinuation() = %+v, %v, want %+v", c, ok, want)
+	}
+	if _, ok := GetContinuation(job.ID, 2); ok {
+		t.Error("GetContinuation() of an earlier run, want none")
+	}
+
+	// A stop during a restart stops the restarted encoder
+	done := make(chan struct{})
+	mu.Lock()
+	restarts[job.ID] = done
+	mu.Unlock()
+	stopped := make(chan error)
+	go func() { stopped <- StopJob(job.ID) }()
+	select {
+	case <-stopped:
+		t.Fatal("StopJob() returned during the restart")
+	case <-time.After(50 * time.Millisecond):
+	}
+	mu.Lock()
+	delete(restarts, job.ID)
+	mu.Unlock()
+	close(done)
+	if err := <-stopped; err != nil {
+		t.Fatalf("StopJob() error = %v", err)
+	}
+	if got, _ := GetJob(job.ID); got.Status != string(JobStatusCompleted) {
+		t.Errorf("Status = %s, want %s", got.Status, JobStatusCompleted)
+	}
+}
+
+func TestStartJob_ConcurrentLimit(t *testing.T) {
+	fakeFFmpeg(t)
+	const limit = 3
+	config.MAX_JOB_COUNT = limit
+	starts := make([]*models.Job, limit+1)
+	for i := range starts {
+		starts[i] = newJob(t)
+	}
+	errs := make([]error, len(starts))
+	var wg sync.WaitGroup
+	for i, job := range starts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = StartJob(job)
+		}()
+	}
+	wg.Wait()
+	started := 0
+	for _, err := range errs {
+		var insufficient *resources.InsufficientError
+		switch {
+		case err == nil:
+			started++
+		case !errors.As(err, &insufficient):
+			t.Errorf("StartJob() error = %v, want nil or *resources.InsufficientError", err)
+		}
+	}
+	if started != limit {
+		t.Errorf("%d starts succeeded, want %d", started, limit)
+	}
+}
@@ -0,0 +1,203 @@
+package jobs
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/streamer"
+	"github.com/google/uuid"
+)
+
+// SweepOrphans removes the output directories of jobs that do not exist, like
+// those left by an earlier server process. Only directories named by a job id
+// are touched. It returns the ids of the removed directories.
+func SweepOrphans() []string {
+	entries, err := os.ReadDir(config.DEFAULT_MEDIA_DIR)
+	if err != nil {
+		slog.Error("Failed to read media directory", "path", config.DEFAULT_MEDIA_DIR, "error", err)
+		return nil
+	}
+	var removed []string
+	for _, entry := range entries {
+		id := entry.Name()
+		if !entry.IsDir() || uuid.Validate(id) != nil {
+			continue
+		}
+		mu.RLock()
+		_, exists := jobs[id]
+		mu.RUnlock()
+		if exists {
+			continue
+		}
+		path := filepath.Join(config.DEFAULT_MEDIA_DIR, id)
+		if err := os.RemoveAll(path); err != nil {
+			slog.Error("Failed to remove orphaned job directory", "path", path, "error", err)
+			continue
+		}
+		slog.Info("Removed orphaned job directory", "path", path)
+		removed = append(removed, id)
+	}
+	return removed
+}
+
+// StartRetention applies the retention policies every interval until the
+// returned function is called. Without a policy it does nothing.
+func StartRetention(interval time.Duration) (stop func()) {
+	if config.RETENTION_TTL <= 0 && config.MAX_MEDIA_BYTES <= 0 {
+		return func() {}
+	}
+	slog.Info("Applying retention policies", "ttl", config.RETENTION_TTL, "maxMediaBytes", config.MAX_MEDIA_BYTES, "interval", interval)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case now := <-ticker.C:
+				ApplyRetention(now)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// ApplyRetention deletes the output of the earlier runs of every job and the
+// completed and failed jobs that stopped more than config.RETENTION_TTL ago,
+// then the oldest of the others while the media directory is larger than
+// config.MAX_MEDIA_BYTES. The current run of a job is kept while the job runs
+// or waits for a scheduled run. It returns the deleted jobs.
+func ApplyRetention(now time.Time) []string {
+	type candidate struct {
+		id      string
+		run     int // An earlier run of the job, or 0 for the whole job
+		stopped time.Time
+	}
+	var candidates []candidate
+	mu.RLock()
+	for id, job := range jobs {
+		for _, r := range runs[id] {
+			if r.Number < job.Run && !r.Expired {
+				candidates = append(candidates, candidate{id: id, run: r.Number, stopped: stoppedAt(r.CompletedAt, r.StartedAt)})
+			}
+		}
+		if retainable(id) {
+			candidates = append(candidates, candidate{id: id, stopped: stoppedAt(job.CompletedAt, job.CreatedAt)})
+		}
+	}
+	mu.RUnlock()
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		switch {
+		case !a.stopped.Equal(b.stopped):
+			return a.stopped.Before(b.stopped)
+		case a.id != b.id:
+			return a.id < b.id
+		}
+		return a.run < b.run
+	})
+
+	// evict deletes a candidate unless it started running again, and returns
+	// the bytes it freed
+	var deleted []string
+	evict := func(c candidate, reason string) int64 {
+		if c.run > 0 {
+			dir := streamer.RunDir(c.id, c.run)
+			if !expireRun(c.id, c.run) {
+				return 0
+			}
+			size := dirSize(dir)
+			slog.Info(reason, "jobID", c.id, "run", c.run, "stopped", c.stopped, "bytes", size)
+			if err := os.RemoveAll(dir); err != nil {
+				slog.Error("Failed to remove run directory", "path", dir, "error", err)
+			}
+			return size
+		}
+		size := dirSize(filepath.Join(config.DEFAULT_MEDIA_DIR, c.id))
+		if !deleteJob(c.id, true) {
+			return 0
+		}
+		slog.Info(reason, "jobID", c.id, "stopped", c.stopped, "bytes", size)
+		deleted = append(deleted, c.id)
+		return size
+	}
+	if ttl := config.RETENTION_TTL; ttl > 0 {
+		for len(candidates) > 0 && now.Sub(candidates[0].stopped) > ttl {
+			evict(candidates[0], "Deleting output past its retention")
+			candidates = candidates[1:]
+		}
+	}
+	if limit := config.MAX_MEDIA_BYTES; limit > 0 {
+		size := dirSize(config.DEFAULT_MEDIA_DIR)
+		for size > limit && len(candidates) > 0 {
+			size -= evict(candidates[0], "Deleting output to free disk space")
+			candidates = candidates[1:]
+		}
+		if size > limit {
+			slog.Warn("Media directory is over its size limit, only the current runs of running and scheduled jobs are left", "mediaBytes", size, "maxMediaBytes", limit)
+		}
+	}
+	return deleted
+}
+
+// stoppedAt returns when a job or run stopped, or when it began if that is
+// not known.
+func stoppedAt(completed, began string) time.Time {
+	stopped, err := time.Parse(time.RFC3339, completed)
+	if err != nil {
+		stopped, _ = time.Parse(time.RFC3339, began)
+	}
+	return stopped
+}
+
+// retainable reports whether a job is completed or failed and waits for no
+// run, so the retention policies may delete it. Callers must hold mu.
+func retainable(id string) bool {
+	job, exists := jobs[id]
+	if !exists || (job.Status != string(JobStatusCompleted) && job.Status != string(JobStatusFailed)) {
+		return false
+	}
+	_, scheduled := timers[id]
+	_, running := jobProcessMap[id]
+	_, restarting := restarts[id]
+	return !scheduled && !running && !restarting
+}
+
+// expireRun marks an earlier run of a job as deleted by the retention
+// policies, unless it is the current run of the job by now.
+func expireRun(id string, run int) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	r := runRecord(id, run)
+	if r == nil || r.Expired || run >= jobs[id].Run {
+		return false
+	}
+	r.Expired = true
+	r.PlaybackURLs = nil
+	return true
+}
+
+// dirSize returns the total size of the files under a directory.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Files of a running job come and go
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
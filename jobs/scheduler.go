@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// timers holds the pending scheduled start or stop of each job, guarded by mu
+var timers = make(map[string]*time.Timer)
+
+// armTimer replaces the pending timer of a job. Callers must hold mu.
+func armTimer(id string, at time.Time, fn func(string)) {
+	cancelTimer(id)
+	timers[id] = time.AfterFunc(time.Until(at), func() { fn(id) })
+}
+
+// cancelTimer drops the pending timer of a job. Callers must hold mu.
+func cancelTimer(id string) {
+	if t, exists := timers[id]; exists {
+		t.Stop()
+		delete(timers, id)
+	}
+}
+
+// scheduleStart arms the next scheduled start of a job, if any, and records
+// it on the job. Callers must hold mu.
+func scheduleStart(job *models.Job) {
+	cancelTimer(job.ID)
+	job.ScheduledRun = nil
+	start, ok := job.Configuration.NextStart(time.Now())
+	if !ok {
+		return
+	}
+	job.ScheduledRun = &models.ScheduledRun{StartAt: start.Format(time.RFC3339)}
+	if stop, ok := job.Configuration.RunEnd(start); ok {
+		job.ScheduledRun.StopAt = stop.Format(time.RFC3339)
+	}
+	slog.Info("Scheduled job start", "jobID", job.ID, "start_at", job.ScheduledRun.StartAt)
+	armTimer(job.ID, start, startScheduledJob)
+}
+
+// scheduleStop arms the scheduled stop of a job that started at startedAt, if
+// any, and records the run on the job. Callers must hold mu.
+func scheduleStop(job *models.Job, startedAt time.Time) {
+	cancelTimer(job.ID)
+	job.ScheduledRun = nil
+	stop, ok := job.Configuration.RunEnd(startedAt)
+	if !ok || !stop.After(time.Now()) {
+		return
+	}
+	job.ScheduledRun = &models.ScheduledRun{
+		StartAt: startedAt.Format(time.RFC3339),
+		StopAt:  stop.Format(time.RFC3339),
+	}
+	slog.Info("Scheduled job stop", "jobID", job.ID, "stop_at", job.ScheduledRun.StopAt)
+	armTimer(job.ID, stop, stopScheduledJob)
+}
+
+func startScheduledJob(id string) {
+	job, exists := GetJob(id)
+	if !exists {
+		return
+	}
+	if job.Status == string(JobStatusRunning) {
+		// Started by hand in the meantime, wait for the next run
+		slog.Warn("Scheduled job is already running", "jobID", id)
+		rescheduleStart(id)
+		return
+	}
+	slog.Info("Starting scheduled job", "jobID", id)
+	if err := StartJob(job); errors.Is(err, ErrJobQueued) {
+		slog.Info("Scheduled job is waiting for resources", "jobID", id)
+	} else if err != nil {
+		slog.Error("Failed to start scheduled job", "jobID", id, "error", err)
+		rescheduleStart(id)
+	}
+}
+
+// rescheduleStart arms the next run of a recurring job whose current run was skipped.
+func rescheduleStart(id string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if job, exists := jobs[id]; exists && job.Configuration.Schedule != "" {
+		scheduleStart(&job)
+		jobs[id] = job
+	}
+}
+
+func stopScheduledJob(id string) {
+	slog.Info("Stopping scheduled job", "jobID", id)
+	if err := StopJob(id); err != nil {
+		slog.Error("Failed to stop scheduled job", "jobID", id, "error", err)
+	}
+}
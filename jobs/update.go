@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/pkg/dash"
+	"github.com/arunjeyaprasad/golive/pkg/hls"
+	"github.com/arunjeyaprasad/golive/streamer"
+	"github.com/arunjeyaprasad/golive/transform"
+	"github.com/arunjeyaprasad/golive/validate"
+)
+
+// ErrJobNotFound is returned for a job that does not exist (anymore).
+var ErrJobNotFound = errors.New("job not found")
+
+// InvalidUpdateError is returned for a patch that does not make a valid
+// configuration of a job, see models.FieldErrors for its problems.
+type InvalidUpdateError struct {
+	Err error
+}
+
+func (e *InvalidUpdateError) Error() string { return e.Err.Error() }
+func (e *InvalidUpdateError) Unwrap() error { return e.Err }
+
+// UpdateJob merges a JSON merge patch into the current configuration of a
+// job, see models.JobCreateRequest.Update. A job that is not running keeps it
+// for its next run. A running job applies the changed settings that are hot
+// right away and restarts its encoder for the others.
+func UpdateJob(id string, patch map[string]json.RawMessage) (*models.JobUpdateResponse, error) {
+	mu.Lock()
+	awaitRestart(id)
+	job, exists := jobs[id]
+	if !exists {
+		mu.Unlock()
+		return nil, ErrJobNotFound
+	}
+	// Concurrent updates apply one after the other
+	configuration, changed, err := job.Configuration.Update(patch)
+	if err != nil {
+		mu.Unlock()
+		return nil, &InvalidUpdateError{Err: err}
+	}
+	previous := job
+	job.Configuration = configuration
+	response := &models.JobUpdateResponse{Changes: []models.FieldUpdate{}}
+	sp, running := jobProcessMap[id]
+	running = running && job.Status == string(JobStatusRunning)
+	rescheduled := false
+	for _, field := range changed {
+		mode := models.UpdateModes[field]
+		response.Changes = append(response.Changes, models.FieldUpdate{Field: field, Mode: mode})
+		if !running {
+			continue
+		}
+		switch {
+		case mode == models.UpdateModeRestart:
+			response.Restarted = true
+		case field == "description":
+			if err := sp.UpdateOverlay(configuration.Description); err != nil {
+				slog.Error("Failed to update overlay text", "jobID", id, "error", err)
+			}
+		case field == "start_at" || field == "stop_at" || field == "duration" || field == "schedule":
+			rescheduled = true
+		}
+	}
+	if !running {
+		scheduleStart(&job)
+	} else if rescheduled {
+		if startedAt, err := time.Parse(time.RFC3339, job.StreamingStartedAt); err == nil {
+			scheduleStop(&job, startedAt)
+		}
+	}
+	var v *validate.Validator
+	var done chan struct{}
+	if response.Restarted {
+		// Take the encoder over, stops and updates wait for the restart
+		delete(jobProcessMap, id) // The exit of the old encoder is not a failure
+		v = validators[id]
+		delete(validators, id)
+		done = make(chan struct{})
+		restarts[id] = done
+	}
+	jobs[id] = job
+	publish(models.WebhookEventUpdated, job)
+	mu.Unlock()
+
+	if response.Restarted {
+		slog.Info("Restarting encoder to apply the update", "jobID", id, "changes", changed)
+		restarted, err := restartJob(previous, sp, v, done)
+		if err != nil {
+			return nil, err
+		}
+		job = *restarted
+	}
+	setPhase(&job)
+	response.Job = job
+	return response, nil
+}
+
+// awaitRestart waits until the encoder of a job is no longer restarting.
+// Callers must hold mu, it is released while waiting.
+func awaitRestart(id string) {
+	for {
+		done, restarting := restarts[id]
+		if !restarting {
+			return
+		}
+		mu.Unlock()
+		<-done
+		mu.Lock()
+	}
+}
+
+// restartJob replaces the stopped encoder sp of the previous run of a job
+// with one built from its current configuration. The encoder writes a new
+// run, the old one is complete and keeps its VOD if it records one. The
+// manifests of the job carry on from those of the old run, see
+// transform.Continuation. The new run keeps the start time of the old one
+// unless it is an event, whose phases are timed by the encoder. done is
+// closed once the job runs again, or failed to.
+func restartJob(previous models.Job, sp *streamer.StreamingProcess, v *validate.Validator, done chan struct{}) (*models.Job, error) {
+	id := previous.ID
+	defer func() {
+		mu.Lock()
+		delete(restarts, id)
+		mu.Unlock()
+		close(done)
+	}()
+	if err := sp.StopJob(); err != nil {
+		slog.Error("Failed to stop encoder", "jobID", id, "error", err)
+	}
+	if v != nil {
+		v.Stop()
+	}
+
+	var vodURLs []models.PlaybackURLs
+	if previous.Configuration.VODOnStop {
+		vodURLs = finalizeVOD(&previous, sp.OutDir)
+	}
+	mu.RLock()
+	c := continuation(id, previous.Run, continuations[id])
+	mu.RUnlock()
+	mu.Lock()
+	endRun(id, previous.Run, JobStatusCompleted, nil)
+	if r := runRecord(id, previous.Run); r != nil {
+		r.PlaybackURLs = append(r.PlaybackURLs, vodURLs...)
+	}
+	job, exists := jobs[id]
+	mu.Unlock()
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+
+	err := startJob(&job, &c)
+	mu.Lock()
+	defer mu.Unlock()
+	current, exists := jobs[id]
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		scheduleStart(&current)
+		jobs[id] = current
+		return nil, err
+	}
+	if current.Configuration.Event == nil {
+		if t, perr := time.Parse(time.RFC3339, previous.StreamingStartedAt); perr == nil {
+			current.StreamingStartedAt = previous.StreamingStartedAt
+			scheduleStop(&current, t)
+			jobs[id] = current
+		}
+	}
+	return &current, nil
+}
+
+// continuation returns how the manifests of the run that restarts a job carry
+// on from those of its stopped run, whose own continuation is previous if it
+// restarted an earlier one.
+func continuation(id string, run int, previous transform.Continuation) transform.Continuation {
+	dir := streamer.RunDir(id, run)
+	c := transform.Continuation{
+		AvailabilityStartTime: previous.AvailabilityStartTime,
+		MediaSequence:         previous.MediaSequence,
+		Discontinuities:       previous.Discontinuities + 1,
+	}
+	if c.AvailabilityStartTime == "" {
+		if data, err := os.ReadFile(filepath.Join(dir, "manifest.mpd")); err == nil {
+			if mpd, err := dash.Decode(data); err == nil {
+				c.AvailabilityStartTime = mpd.AvailabilityStartTime
+			}
+		}
+	}
+	// Number the segments on from the last one of the stopped run
+	last := int64(transform.FirstSegmentNumber - 1)
+	playlists, _ := filepath.Glob(filepath.Join(dir, "media_*.m3u8"))
+	for _, name := range playlists {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		if pl, err := hls.DecodeMedia(data); err == nil {
+			last = max(last, pl.MediaSequence+int64(len(pl.Segments))-1)
+		}
+	}
+	c.MediaSequence += last - (transform.FirstSegmentNumber - 1)
+	return c
+}
@@ -1,21 +1,14 @@
 package main
 
 import (
-	"log/slog"
 	"os"
 
-	"github.com/arunjeyaprasad/golive/config"
-	"github.com/arunjeyaprasad/golive/server"
+	"github.com/arunjeyaprasad/golive/internal/cli"
 )
 
-// main function initializes the application and starts the server.
-// It sets up the necessary configurations, routes, and middleware.
+// main runs the server, or a client command against a running server.
+// Without arguments it starts the server, see cli.Run for the commands.
 
 func main() {
-	config.Init()
-	// Start the Server
-	if err := server.StartServer(); err != nil {
-		slog.Error("Failed to start server", "error", err)
-		os.Exit(-1)
-	}
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
 }
@@ -0,0 +1,16 @@
+package models
+
+// ConfigResponse is the effective configuration of the server.
+type ConfigResponse struct {
+	File     string          `json:"file,omitempty"` // Configuration file that was loaded
+	Settings []ConfigSetting `json:"settings"`
+}
+
+// ConfigSetting is a setting with its effective value.
+type ConfigSetting struct {
+	Name   string `json:"name"`   // Key in the configuration file
+	Value  any    `json:"value"`  // Secrets are redacted
+	Source string `json:"source"` // Where the value came from: default, file, env or flag
+	Env    string `json:"env"`    // Environment variable setting it
+	Flag   string `json:"flag"`   // Flag of serve setting it
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidRequest   ErrorCode = "invalid_request"   // The body could not be decoded
+	ErrorCodeValidation       ErrorCode = "validation_failed" // The body decoded but failed Validate, see the details
+	ErrorCodeNotFound         ErrorCode = "not_found"
+	ErrorCodeInvalidState     ErrorCode = "invalid_state" // The job is not in a state that allows the request
+	ErrorCodeConflict         ErrorCode = "conflict"      // A resource with the name already exists
+	ErrorCodeMethodNotAllowed ErrorCode = "method_not_allowed"
+	ErrorCodeInternal         ErrorCode = "internal_error"
+	// The host cannot run the job in real time next to the running ones
+	ErrorCodeInsufficientResources ErrorCode = "insufficient_resources"
+)
+
+// ErrorResponse is the body of every error response of the API.
+type ErrorResponse struct {
+	Code    ErrorCode    `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"` // One entry per validation failure
+}
+
+// FieldError is a validation failure of one field of a request.
+type FieldError struct {
+	Field   string `json:"field,omitempty"` // JSON path of the field, e.g. video.bitrate or webhooks[0].url
+	Message string `json:"message"`
+}
+
+func (fe *FieldError) Error() string {
+	return fe.Message
+}
+
+func fieldError(field, format string, args ...any) error {
+	return &FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// inField places the validation errors of a nested configuration under its
+// field in the request.
+func inField(prefix string, errs []error) []error {
+	for _, err := range errs {
+		var fe *FieldError
+		if errors.As(err, &fe) {
+			fe.Field = prefix + "." + fe.Field
+		}
+	}
+	return errs
+}
+
+// FieldErrors lists the validation failures of an error returned by Validate.
+func FieldErrors(err error) []FieldError {
+	var errs []error
+	if err != nil {
+		errs = unwrap(err)
+	}
+	details := make([]FieldError, 0, len(errs))
+	for _, err := range errs {
+		var fe *FieldError
+		if errors.As(err, &fe) {
+			details = append(details, *fe)
+		} else {
+			details = append(details, FieldError{Message: err.Error()})
+		}
+	}
+	return details
+}
+
+// unwrap lists the errors joined in err.
+func unwrap(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type EventPhase string
+
+const (
+	EventPhasePreEvent  EventPhase = "pre_event"
+	EventPhaseLive      EventPhase = "live"
+	EventPhasePostEvent EventPhase = "post_event"
+	EventPhaseEnded     EventPhase = "ended"
+)
+
+// EventConfig turns a job into an event: a "starting soon" slate with a
+// countdown, the live test pattern and an "event ended" slate, after which
+// the stream ends. The phases are timed in the encoder, so switching between
+// them does not restart ffmpeg.
+type EventConfig struct {
+	PreRollSeconds  int    `json:"pre_roll_seconds"`         // Length of the starting soon slate
+	LiveSeconds     int    `json:"live_seconds"`             // Length of the live part
+	PostRollSeconds int    `json:"post_roll_seconds"`        // Length of the event ended slate
+	PreRollText     string `json:"pre_roll_text,omitempty"`  // Defaults to "Starting soon"
+	PostRollText    string `json:"post_roll_text,omitempty"` // Defaults to "Event ended"
+}
+
+// Length returns the length of the whole event.
+func (ec *EventConfig) Length() time.Duration {
+	return time.Duration(ec.PreRollSeconds+ec.LiveSeconds+ec.PostRollSeconds) * time.Second
+}
+
+// PhaseAt returns the phase of the event elapsed after the stream started.
+func (ec *EventConfig) PhaseAt(elapsed time.Duration) EventPhase {
+	pre := time.Duration(ec.PreRollSeconds) * time.Second
+	live := pre + time.Duration(ec.LiveSeconds)*time.Second
+	switch {
+	case elapsed < pre:
+		return EventPhasePreEvent
+	case elapsed < live:
+		return EventPhaseLive
+	case elapsed < ec.Length():
+		return EventPhasePostEvent
+	}
+	return EventPhaseEnded
+}
+
+func (ec *EventConfig) validate() []error {
+	var errs []error
+	if ec.PreRollSeconds < 0 {
+		errs = append(errs, fieldError("pre_roll_seconds", "pre_roll_seconds must not be negative"))
+	}
+	if ec.PostRollSeconds < 0 {
+		errs = append(errs, fieldError("post_roll_seconds", "post_roll_seconds must not be negative"))
+	}
+	if ec.LiveSeconds <= 0 {
+		errs = append(errs, fieldError("live_seconds", "live_seconds must be greater than 0"))
+	}
+	// The texts are drawn by ffmpeg, keep out its escape characters
+	if !validSlateText(ec.PreRollText) {
+		errs = append(errs, fieldError("pre_roll_text", "event slate texts must be at most 50 characters without ' \\ : or %%"))
+	}
+	if !validSlateText(ec.PostRollText) {
+		errs = append(errs, fieldError("post_roll_text", "event slate texts must be at most 50 characters without ' \\ : or %%"))
+	}
+	return errs
+}
+
+func validSlateText(text string) bool {
+	return len(text) <= 50 && !strings.ContainsAny(text, `'\:%`)
+}
@@ -0,0 +1,52 @@
+package models
+
+type JobEventType string
+
+const (
+	JobEventState   JobEventType = "state"   // The job changed state
+	JobEventSegment JobEventType = "segment" // A new segment was added to a playlist
+	JobEventStats   JobEventType = "stats"   // Encoder progress
+	JobEventError   JobEventType = "error"   // The encoder reported an error
+)
+
+// JobEvent is pushed to event stream clients as it happens. Only the field
+// matching the type is set.
+type JobEvent struct {
+	ID        uint64        `json:"id"` // Increases with every event
+	Type      JobEventType  `json:"type"`
+	JobID     string        `json:"job_id"`
+	Timestamp string        `json:"timestamp"`
+	State     *JobState     `json:"state,omitempty"`
+	Segment   *SegmentInfo  `json:"segment,omitempty"`
+	Stats     *EncoderStats `json:"stats,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// JobState is the state of a job after a transition. Transition is empty for
+// the snapshot sent when a client connects.
+type JobState struct {
+	Transition WebhookEvent `json:"transition,omitempty"`
+	Status     string       `json:"status"`
+	Phase      EventPhase   `json:"phase,omitempty"`
+}
+
+// SegmentInfo describes a media segment as listed in an HLS media playlist.
+type SegmentInfo struct {
+	File     string  `json:"file"`
+	Playlist string  `json:"playlist"`
+	Sequence int64   `json:"sequence"`
+	Duration float64 `json:"duration"` // Seconds
+	Size     int64   `json:"size"`     // Bytes
+}
+
+// EncoderStats is a progress report of ffmpeg.
+type EncoderStats struct {
+	Frame      int64   `json:"frame"`
+	FPS        float64 `json:"fps"`
+	Bitrate    string  `json:"bitrate"` // e.g. 1021.3kbits/s
+	TotalSize  int64   `json:"total_size"`
+	OutTime    string  `json:"out_time"` // Encoded media time, e.g. 00:01:30.000000
+	DupFrames  int64   `json:"dup_frames"`
+	DropFrames int64   `json:"drop_frames"`
+	Speed      string  `json:"speed"` // e.g. 1.01x, below 1x the encoder falls behind real time
+}
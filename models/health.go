@@ -0,0 +1,33 @@
+package models
+
+type HealthStatus string
+
+const (
+	HealthStatusPending   HealthStatus = "pending"
+	HealthStatusHealthy   HealthStatus = "healthy"
+	HealthStatusDegraded  HealthStatus = "degraded"
+	HealthStatusUnhealthy HealthStatus = "unhealthy"
+)
+
+type HealthSeverity string
+
+const (
+	HealthSeverityWarning HealthSeverity = "warning"
+	HealthSeverityError   HealthSeverity = "error"
+)
+
+// JobHealth is the latest conformance report for the manifests a job produces.
+type JobHealth struct {
+	ID        string        `json:"id"`
+	Status    HealthStatus  `json:"status"`
+	CheckedAt string        `json:"checked_at,omitempty"`
+	Passes    int           `json:"passes"` // Number of validation passes run so far
+	Issues    []HealthIssue `json:"issues"`
+}
+
+type HealthIssue struct {
+	Check    string         `json:"check"`
+	Severity HealthSeverity `json:"severity"`
+	File     string         `json:"file,omitempty"`
+	Message  string         `json:"message"`
+}
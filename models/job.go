@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/arunjeyaprasad/golive/config"
 )
@@ -16,15 +17,35 @@ type Job struct {
 	StreamingStartedAt string           `json:"streamed_from,omitempty"`
 	CompletedAt        string           `json:"completed,omitempty"`
 	PlaybackURLs       []PlaybackURLs   `json:"playback_urls,omitempty"`
-	Configuration      JobCreateRequest `json:"config"` // Original request that created this job
+	ScheduledRun       *ScheduledRun    `json:"scheduled_run,omitempty"`
+	Phase              EventPhase       `json:"phase,omitempty"`       // Current phase of event jobs
+	Run                int              `json:"run,omitempty"`         // Number of the current or last run
+	ClonedFrom         string           `json:"cloned_from,omitempty"` // Job this one is a copy of
+	Configuration      JobCreateRequest `json:"config"`                // Original request that created this job
 }
 
 type JobCreateRequest struct {
-	Description string       `json:"description"`
-	VideoTrack  *VideoTrack  `json:"video,omitempty"`
-	AudioTrack  *AudioTrack  `json:"audio,omitempty"`
-	AudioConfig *AudioConfig `json:"audio_config,omitempty"`
+	Description string              `json:"description"`
+	VideoTrack  *VideoTrack         `json:"video,omitempty"`
+	AudioTrack  *AudioTrack         `json:"audio,omitempty"`
+	AudioConfig *AudioConfig        `json:"audio_config,omitempty"`
+	Transforms  *ManifestTransforms `json:"manifest_transforms,omitempty"`
+	Event       *EventConfig        `json:"event,omitempty"`
+	Webhooks    []Webhook           `json:"webhooks,omitempty"`
+	Owner       string              `json:"owner,omitempty"`  // Team or person responsible for the job
+	Labels      map[string]string   `json:"labels,omitempty"` // Free-form metadata jobs can be selected by
+	Limits      *JobLimits          `json:"limits,omitempty"` // Resource limits of the ffmpeg process
 	JobFormat
+	JobSchedule
+}
+
+// RunEnd returns when a run that started at startedAt should be stopped.
+func (jcr JobCreateRequest) RunEnd(startedAt time.Time) (time.Time, bool) {
+	if jcr.Event != nil {
+		// ffmpeg ends the event itself, stop once its last segment is out
+		return startedAt.Add(jcr.Event.Length() + time.Duration(jcr.SegmentLength)*time.Second), true
+	}
+	return jcr.StopTime(startedAt)
 }
 
 type JobResponse struct {
@@ -59,14 +80,40 @@ const (
 )
 
 type JobFormat struct {
-	OutputFormat  []JobOutputFormat `json:"output_format,omitempty"`
-	SegmentLength int               `json:"segment_length,omitempty"` // Length of each segment in seconds
-	WindowSize    int               `json:"window_size,omitempty"`    // Number of segments to keep in the playlist
+	OutputFormat     []JobOutputFormat `json:"output_format,omitempty"`
+	SegmentLength    int               `json:"segment_length,omitempty"`     // Length of each segment in seconds
+	WindowSize       int               `json:"window_size,omitempty"`        // Number of segments to keep in the playlist
+	DVRWindowSeconds int               `json:"dvr_window_seconds,omitempty"` // Timeshift depth in seconds, overrides window_size
+	VODOnStop        bool              `json:"vod_on_stop,omitempty"`        // Keep all segments and finalize them as VOD when the job stops
 }
 
+// MaxWindowSize is the most segments a playlist advertises. It bounds
+// window_size, the deepest dvr_window_seconds config allows stays below it even
+// with one second segments.
+const MaxWindowSize = 1 << 20
+
+// PlaylistWindow returns the number of segments advertised in the manifests.
+func (jf JobFormat) PlaylistWindow() int {
+	if jf.DVRWindowSeconds > 0 && jf.SegmentLength > 0 {
+		return (jf.DVRWindowSeconds + jf.SegmentLength - 1) / jf.SegmentLength
+	}
+	return jf.WindowSize
+}
+
+type PlaybackVariant string
+
+const (
+	// PlaybackVariantStartOver starts playback at the beginning of the DVR window
+	PlaybackVariantStartOver PlaybackVariant = "startover"
+	// PlaybackVariantVOD is the recording of a stopped job
+	PlaybackVariantVOD PlaybackVariant = "vod"
+)
+
 type PlaybackURLs struct {
-	Format JobOutputFormat `json:"format"`
-	URL    string          `json:"url"`
+	Format  JobOutputFormat `json:"format"`
+	Variant PlaybackVariant `json:"variant,omitempty"`
+	URL     string          `json:"url"`
+	CDNURLs []string        `json:"cdn_urls,omitempty"` // The URL on each of the CDN hosts
 }
 
 func (jcr *JobCreateRequest) Validate() error {
@@ -87,19 +134,19 @@ func (jcr *JobCreateRequest) Validate() error {
 			jcr.VideoTrack.Framerate = "30" // Default framerate
 		}
 		if jcr.VideoTrack.Codec == "" {
-			jcr.VideoTrack.Codec = "h264" // Default codec
+			jcr.VideoTrack.Codec = config.DEFAULT_VIDEO_CODEC
 		}
 	} else {
 		jcr.VideoTrack = &VideoTrack{
 			BitRate:    "1M",       // Default bitrate
 			Resolution: "1280x720", // Default resolution
 			Framerate:  "30",       // Default framerate
-			Codec:      "h264",     // Default codec
+			Codec:      config.DEFAULT_VIDEO_CODEC,
 		}
 	}
 	if jcr.AudioTrack != nil {
 		if jcr.AudioTrack.AudioCodec == "" {
-			jcr.AudioTrack.AudioCodec = "aac" // Default audio codec
+			jcr.AudioTrack.AudioCodec = config.DEFAULT_AUDIO_CODEC
 		}
 		if jcr.AudioTrack.AudioBitrate == "" {
 			jcr.AudioTrack.AudioBitrate = "128k" // Default audio bitrate
@@ -112,7 +159,7 @@ func (jcr *JobCreateRequest) Validate() error {
 		}
 	} else {
 		jcr.AudioTrack = &AudioTrack{
-			AudioCodec:      "aac",   // Default audio codec
+			AudioCodec:      config.DEFAULT_AUDIO_CODEC,
 			AudioBitrate:    "128k",  // Default audio bitrate
 			AudioSampleRate: "44100", // Default sample rate
 			AudioChannels:   "2",     // Default channels
@@ -120,10 +167,10 @@ func (jcr *JobCreateRequest) Validate() error {
 	}
 	if jcr.AudioConfig != nil {
 		if jcr.AudioConfig.AudioTracks <= 0 || jcr.AudioConfig.AudioTracks > config.MAX_AUDIO_LANGUAGES {
-			errs = append(errs, fmt.Errorf("audio_tracks must be greater than 0"))
+			errs = append(errs, fieldError("audio_config.audio_tracks", "audio_tracks must be greater than 0"))
 		}
 		if len(jcr.AudioConfig.AudioLanguages) != jcr.AudioConfig.AudioTracks {
-			errs = append(errs, fmt.Errorf("audio_languages must match the number of audio_tracks"))
+			errs = append(errs, fieldError("audio_config.audio_languages", "audio_languages must match the number of audio_tracks"))
 		}
 		if jcr.AudioConfig.AudioDefaultLanguage != "" {
 			found := false
@@ -134,61 +181,92 @@ func (jcr *JobCreateRequest) Validate() error {
 				}
 			}
 			if !found {
-				errs = append(errs, fmt.Errorf("audio_default_language must be one of the audio_languages"))
+				errs = append(errs, fieldError("audio_config.audio_default_language", "audio_default_language must be one of the audio_languages"))
 			}
 		}
 	}
 	if jcr.JobFormat.SegmentLength == 0 {
 		jcr.JobFormat.SegmentLength = config.DEFAULT_SEGMENT_LENGTH // Default segment length in seconds
 	} else if jcr.JobFormat.SegmentLength < 0 {
-		errs = append(errs, fmt.Errorf("segment_length must be greater than 0"))
+		errs = append(errs, fieldError("segment_length", "segment_length must be greater than 0"))
 	}
 	if jcr.JobFormat.WindowSize == 0 {
 		jcr.JobFormat.WindowSize = config.DEFAULT_WINDOW_SIZE // Default window size
+	} else if jcr.JobFormat.WindowSize < 0 {
+		errs = append(errs, fieldError("window_size", "window_size must be greater than 0"))
+	} else if jcr.JobFormat.WindowSize > MaxWindowSize {
+		errs = append(errs, fieldError("window_size", "window_size must not exceed %d", MaxWindowSize))
+	}
+	if jcr.JobFormat.DVRWindowSeconds < 0 || jcr.JobFormat.DVRWindowSeconds > config.MAX_DVR_WINDOW_SECONDS {
+		errs = append(errs, fieldError("dvr_window_seconds", "dvr_window_seconds must be between 0 and %d", config.MAX_DVR_WINDOW_SECONDS))
+	} else if jcr.JobFormat.DVRWindowSeconds > 0 && jcr.JobFormat.DVRWindowSeconds < jcr.JobFormat.SegmentLength {
+		errs = append(errs, fieldError("dvr_window_seconds", "dvr_window_seconds must be at least one segment_length"))
+	}
+	if jcr.Transforms != nil {
+		errs = append(errs, inField("manifest_transforms", jcr.Transforms.validate())...)
+	}
+	errs = append(errs, jcr.JobSchedule.validate(time.Now())...)
+	for i, wh := range jcr.Webhooks {
+		errs = append(errs, inField(fmt.Sprintf("webhooks[%d]", i), wh.validate())...)
+	}
+	if len(jcr.Owner) > maxOwnerLength {
+		errs = append(errs, fieldError("owner", "owner must be at most %d characters", maxOwnerLength))
+	}
+	errs = append(errs, validateLabels(jcr.Labels)...)
+	if jcr.Limits != nil {
+		errs = append(errs, inField("limits", jcr.Limits.validate())...)
+	}
+	if jcr.Event != nil {
+		errs = append(errs, inField("event", jcr.Event.validate())...)
+		if jcr.StopAt != "" || jcr.Duration > 0 {
+			errs = append(errs, fieldError("event", "event cannot be combined with stop_at or duration"))
+		}
+	} else if jcr.Schedule != "" && jcr.Duration == 0 {
+		errs = append(errs, fieldError("schedule", "schedule requires a duration or an event"))
 	}
 	// Step 2: Now validate the Video and Audio Params
 	if jcr.VideoTrack != nil {
 		// Validate the bitrate
 		if len(jcr.VideoTrack.BitRate) < 2 {
-			errs = append(errs, fmt.Errorf("video bitrate must be at least 2 characters long"))
+			errs = append(errs, fieldError("video.bitrate", "video bitrate must be at least 2 characters long"))
 		}
 		// Check if ends with k or M
 		unit := jcr.VideoTrack.BitRate[len(jcr.VideoTrack.BitRate)-1]
 		if !(len(jcr.VideoTrack.BitRate) > 1 && (unit == 'k' || unit == 'M')) {
-			errs = append(errs, fmt.Errorf("video bitrate must end with k or M"))
+			errs = append(errs, fieldError("video.bitrate", "video bitrate must end with k or M"))
 		}
 		// Extract the numeric part
 		numericPart := jcr.VideoTrack.BitRate[:len(jcr.VideoTrack.BitRate)-1]
 		videoBitRate, nerr := strconv.ParseFloat(numericPart, 64)
 		if nerr != nil {
-			errs = append(errs, fmt.Errorf("video bitrate must be a valid number"))
+			errs = append(errs, fieldError("video.bitrate", "video bitrate must be a valid number"))
 		}
 		if unit == 'k' {
 			if videoBitRate < 10 || videoBitRate > float64(config.MAX_VIDEO_BITRATE_MBPS*1000) {
-				errs = append(errs, fmt.Errorf("video bitrate must be between 10k and %dk", config.MAX_VIDEO_BITRATE_MBPS*1000))
+				errs = append(errs, fieldError("video.bitrate", "video bitrate must be between 10k and %dk", config.MAX_VIDEO_BITRATE_MBPS*1000))
 			}
 		}
 		if unit == 'M' {
 			if videoBitRate < 0.01 || videoBitRate > float64(config.MAX_VIDEO_BITRATE_MBPS) {
-				errs = append(errs, fmt.Errorf("video bitrate must be between 0.01M and %dM", config.MAX_VIDEO_BITRATE_MBPS))
+				errs = append(errs, fieldError("video.bitrate", "video bitrate must be between 0.01M and %dM", config.MAX_VIDEO_BITRATE_MBPS))
 			}
 		}
 
 		// Validate resolution
 		resParts := strings.Split(jcr.VideoTrack.Resolution, "x")
 		if len(resParts) != 2 {
-			errs = append(errs, fmt.Errorf("video resolution must be in the format WxH (e.g., 1280x720)"))
+			errs = append(errs, fieldError("video.resolution", "video resolution must be in the format WxH (e.g., 1280x720)"))
 		} else {
 			width, werr := strconv.Atoi(resParts[0])
 			height, herr := strconv.Atoi(resParts[1])
 			if werr != nil || herr != nil {
-				errs = append(errs, fmt.Errorf("video resolution must be valid integers"))
+				errs = append(errs, fieldError("video.resolution", "video resolution must be valid integers"))
 			} else {
 				if width <= 0 || height <= 0 {
-					errs = append(errs, fmt.Errorf("video resolution must be greater than 0"))
+					errs = append(errs, fieldError("video.resolution", "video resolution must be greater than 0"))
 				}
 				if width > config.MAX_VIDEO_WIDTH || height > config.MAX_VIDEO_HEIGHT {
-					errs = append(errs, fmt.Errorf("video resolution must not exceed 3840x2160 (4K)"))
+					errs = append(errs, fieldError("video.resolution", "video resolution must not exceed %dx%d", config.MAX_VIDEO_WIDTH, config.MAX_VIDEO_HEIGHT))
 				}
 			}
 		}
@@ -196,10 +274,10 @@ func (jcr *JobCreateRequest) Validate() error {
 		// Validate framerate
 		framerate, ferr := strconv.Atoi(jcr.VideoTrack.Framerate)
 		if ferr != nil {
-			errs = append(errs, fmt.Errorf("video framerate must be a valid integer"))
+			errs = append(errs, fieldError("video.framerate", "video framerate must be a valid integer"))
 		} else {
 			if framerate <= 0 || framerate > config.MAX_VIDEO_FPS {
-				errs = append(errs, fmt.Errorf("video framerate must be between 1 and %d", config.MAX_VIDEO_FPS))
+				errs = append(errs, fieldError("video.framerate", "video framerate must be between 1 and %d", config.MAX_VIDEO_FPS))
 			}
 		}
 
@@ -212,7 +290,7 @@ func (jcr *JobCreateRequest) Validate() error {
 			}
 		}
 		if !validCodec {
-			errs = append(errs, fmt.Errorf("video codec must be one of: %v", config.VALID_VIDEO_CODECS))
+			errs = append(errs, fieldError("video.codec", "video codec must be one of: %v", config.VALID_VIDEO_CODECS))
 		}
 	}
 
@@ -226,48 +304,48 @@ func (jcr *JobCreateRequest) Validate() error {
 			}
 		}
 		if !validAudioCodec {
-			errs = append(errs, fmt.Errorf("audio codec must be one of: %v", config.VALID_AUDIO_CODECS))
+			errs = append(errs, fieldError("audio.codec", "audio codec must be one of: %v", config.VALID_AUDIO_CODECS))
 		}
 		// Validate audio bitrate
 		if len(jcr.AudioTrack.AudioBitrate) < 3 {
-			errs = append(errs, fmt.Errorf("audio bitrate must be at least 3 characters long"))
+			errs = append(errs, fieldError("audio.bitrate", "audio bitrate must be at least 3 characters long"))
 		}
 		// Check if ends with k
 		unit := jcr.AudioTrack.AudioBitrate[len(jcr.AudioTrack.AudioBitrate)-1]
 		numericPart := jcr.AudioTrack.AudioBitrate[:len(jcr.AudioTrack.AudioBitrate)-1]
 
 		if !(unit == 'k') {
-			errs = append(errs, fmt.Errorf("audio bitrate must end with k"))
+			errs = append(errs, fieldError("audio.bitrate", "audio bitrate must end with k"))
 		}
 		audioBitRate, nerr := strconv.ParseInt(numericPart, 10, 16)
 		if nerr != nil {
-			errs = append(errs, fmt.Errorf("audio bitrate must be a valid number"))
+			errs = append(errs, fieldError("audio.bitrate", "audio bitrate must be a valid number"))
 		}
 		if audioBitRate < 32 || audioBitRate > int64(config.MAX_AUDIO_BITRATE_KBPS) {
-			errs = append(errs, fmt.Errorf("audio bitrate must be between 32k and %dk", config.MAX_AUDIO_BITRATE_KBPS))
+			errs = append(errs, fieldError("audio.bitrate", "audio bitrate must be between 32k and %dk", config.MAX_AUDIO_BITRATE_KBPS))
 		}
 		// Validate audio sample rate
 		audioSampleRate, aerr := strconv.Atoi(jcr.AudioTrack.AudioSampleRate)
 		if aerr != nil {
-			errs = append(errs, fmt.Errorf("audio sample rate must be a valid integer"))
+			errs = append(errs, fieldError("audio.sample_rate", "audio sample rate must be a valid integer"))
 		} else {
 			if audioSampleRate <= 0 {
-				errs = append(errs, fmt.Errorf("audio sample rate must be greater than 0"))
+				errs = append(errs, fieldError("audio.sample_rate", "audio sample rate must be greater than 0"))
 			}
 			if audioSampleRate < 8000 || audioSampleRate > 192000 {
-				errs = append(errs, fmt.Errorf("audio sample rate must be between 8000 and 192000 Hz"))
+				errs = append(errs, fieldError("audio.sample_rate", "audio sample rate must be between 8000 and 192000 Hz"))
 			}
 		}
 		// Validate audio channels
 		audioChannels, cerr := strconv.Atoi(jcr.AudioTrack.AudioChannels)
 		if cerr != nil {
-			errs = append(errs, fmt.Errorf("audio channels must be a valid integer"))
+			errs = append(errs, fieldError("audio.channels", "audio channels must be a valid integer"))
 		} else {
 			if audioChannels <= 0 {
-				errs = append(errs, fmt.Errorf("audio channels must be greater than 0"))
+				errs = append(errs, fieldError("audio.channels", "audio channels must be greater than 0"))
 			}
 			if audioChannels > 8 {
-				errs = append(errs, fmt.Errorf("audio channels must not exceed 8"))
+				errs = append(errs, fieldError("audio.channels", "audio channels must not exceed 8"))
 			}
 		}
 	}
@@ -1,6 +1,34 @@
 package models
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/config"
+)
+
+func TestJobFormat_PlaylistWindow(t *testing.T) {
+	tests := []struct {
+		name string
+		jf   JobFormat
+		want int
+	}{
+		{name: "Window size only", jf: JobFormat{SegmentLength: 6, WindowSize: 6}, want: 6},
+		{name: "DVR window in whole segments", jf: JobFormat{SegmentLength: 6, WindowSize: 6, DVRWindowSeconds: 3600}, want: 600},
+		{name: "DVR window rounded up", jf: JobFormat{SegmentLength: 4, WindowSize: 6, DVRWindowSeconds: 10}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.jf.PlaylistWindow(); got != tt.want {
+				t.Errorf("JobFormat.PlaylistWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
 
 func TestJobCreateRequest_Validate(t *testing.T) {
 	type fields struct {
@@ -8,7 +36,14 @@ func TestJobCreateRequest_Validate(t *testing.T) {
 		VideoTrack  *VideoTrack
 		AudioTrack  *AudioTrack
 		AudioConfig *AudioConfig
+		Transforms  *ManifestTransforms
+		Event       *EventConfig
+		Webhooks    []Webhook
+		Owner       string
+		Labels      map[string]string
+		Limits      *JobLimits
 		JobFormat   JobFormat
+		JobSchedule JobSchedule
 	}
 	tests := []struct {
 		name    string
@@ -316,6 +351,319 @@ func TestJobCreateRequest_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Valid job with DVR window",
+			fields: fields{
+				Description: "Test job with DVR window",
+				JobFormat: JobFormat{
+					SegmentLength:    4,
+					DVRWindowSeconds: 7200,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid job with negative window size",
+			fields: fields{
+				Description: "Test job with negative window size",
+				JobFormat: JobFormat{
+					WindowSize: -1,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with DVR window shorter than a segment",
+			fields: fields{
+				Description: "Test job with short DVR window",
+				JobFormat: JobFormat{
+					SegmentLength:    6,
+					DVRWindowSeconds: 4,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with DVR window exceeding the maximum",
+			fields: fields{
+				Description: "Test job with long DVR window",
+				JobFormat: JobFormat{
+					DVRWindowSeconds: 86400,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Valid job with manifest transforms",
+			fields: fields{
+				Description: "Test job with manifest transforms",
+				Transforms: &ManifestTransforms{
+					DropRenditions:    []string{"1"},
+					ReorderVariants:   VariantOrderBandwidthDesc,
+					URLPrefix:         "https://cdn.example.com/live/",
+					InjectMediaTags:   []string{"#EXT-X-CUSTOM:VALUE=1"},
+					BandwidthScale:    1.5,
+					DVRWindowSegments: 3,
+					TargetDuration:    4,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid job with unknown variant order",
+			fields: fields{
+				Description: "Test job with manifest transforms",
+				Transforms: &ManifestTransforms{
+					ReorderVariants: "random",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with relative url prefix",
+			fields: fields{
+				Description: "Test job with manifest transforms",
+				Transforms: &ManifestTransforms{
+					URLPrefix: "/cdn/live",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with injected tag that is not a tag",
+			fields: fields{
+				Description: "Test job with manifest transforms",
+				Transforms: &ManifestTransforms{
+					InjectMasterTags: []string{"segment.ts"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Valid job with start time and duration",
+			fields: fields{
+				Description: "Test scheduled job",
+				JobSchedule: JobSchedule{
+					StartAt:  time.Now().Add(time.Hour).Format(time.RFC3339),
+					Duration: 600,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Valid job with recurring schedule",
+			fields: fields{
+				Description: "Test recurring job",
+				JobSchedule: JobSchedule{
+					Schedule: "0 9 * * mon-fri",
+					Duration: 3600,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid job with start time in the past",
+			fields: fields{
+				Description: "Test scheduled job",
+				JobSchedule: JobSchedule{
+					StartAt: time.Now().Add(-time.Hour).Format(time.RFC3339),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with stop time before start time",
+			fields: fields{
+				Description: "Test scheduled job",
+				JobSchedule: JobSchedule{
+					StartAt: time.Now().Add(2 * time.Hour).Format(time.RFC3339),
+					StopAt:  time.Now().Add(time.Hour).Format(time.RFC3339),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with both stop time and duration",
+			fields: fields{
+				Description: "Test scheduled job",
+				JobSchedule: JobSchedule{
+					StopAt:   time.Now().Add(time.Hour).Format(time.RFC3339),
+					Duration: 600,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with recurring schedule without duration",
+			fields: fields{
+				Description: "Test recurring job",
+				JobSchedule: JobSchedule{
+					Schedule: "@hourly",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with malformed cron expression",
+			fields: fields{
+				Description: "Test recurring job",
+				JobSchedule: JobSchedule{
+					Schedule: "every monday",
+					Duration: 600,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Valid recurring event",
+			fields: fields{
+				Description: "Test event",
+				Event:       &EventConfig{PreRollSeconds: 60, LiveSeconds: 600, PostRollSeconds: 30},
+				JobSchedule: JobSchedule{Schedule: "@hourly"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid event without live part",
+			fields: fields{
+				Description: "Test event",
+				Event:       &EventConfig{PreRollSeconds: 60},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid event with duration",
+			fields: fields{
+				Description: "Test event",
+				Event:       &EventConfig{LiveSeconds: 600},
+				JobSchedule: JobSchedule{Duration: 600},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid event with slate text ffmpeg would interpret",
+			fields: fields{
+				Description: "Test event",
+				Event:       &EventConfig{LiveSeconds: 600, PreRollText: "Kick-off: 20%"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Valid job with webhooks",
+			fields: fields{
+				Description: "Test job with webhooks",
+				Webhooks: []Webhook{
+					{URL: "http://localhost:8080/hooks", Secret: "s3cret"},
+					{URL: "https://ci.example.com/golive", Events: []WebhookEvent{WebhookEventFirstSegment, WebhookEventFailed}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid job with relative webhook url",
+			fields: fields{
+				Description: "Test job with webhooks",
+				Webhooks:    []Webhook{{URL: "/hooks"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with unknown webhook event",
+			fields: fields{
+				Description: "Test job with webhooks",
+				Webhooks:    []Webhook{{URL: "http://localhost:8080/hooks", Events: []WebhookEvent{"job.exploded"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Valid job with owner and labels",
+			fields: fields{
+				Description: "Test job with labels",
+				Owner:       "player-web@example.com",
+				Labels:      map[string]string{"team": "player-web", "ci/run": "8812"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid job with a label key",
+			fields: fields{
+				Description: "Test job with labels",
+				Labels:      map[string]string{"-team": "player-web"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with a label value",
+			fields: fields{
+				Description: "Test job with labels",
+				Labels:      map[string]string{"team": "player web,ios"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with a long owner",
+			fields: fields{
+				Description: "Test job with owner",
+				Owner:       strings.Repeat("x", 129),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Valid job with limits",
+			fields: fields{
+				Description: "Test job with limits",
+				Limits:      &JobLimits{Threads: 2, Nice: 10, CPUs: 1.5, Memory: "512M"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid job with a negative nice",
+			fields: fields{
+				Description: "Test job with limits",
+				Limits:      &JobLimits{Nice: -5},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with too many threads",
+			fields: fields{
+				Description: "Test job with limits",
+				Limits:      &JobLimits{Threads: 65},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with a tiny CPU limit",
+			fields: fields{
+				Description: "Test job with limits",
+				Limits:      &JobLimits{CPUs: 0.001},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with too many CPUs",
+			fields: fields{
+				Description: "Test job with limits",
+				Limits:      &JobLimits{CPUs: 1e12},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with a memory limit",
+			fields: fields{
+				Description: "Test job with limits",
+				Limits:      &JobLimits{Memory: "lots"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid job with a small memory limit",
+			fields: fields{
+				Description: "Test job with limits",
+				Limits:      &JobLimits{Memory: "16M"},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -324,7 +672,14 @@ func TestJobCreateRequest_Validate(t *testing.T) {
 				VideoTrack:  tt.fields.VideoTrack,
 				AudioTrack:  tt.fields.AudioTrack,
 				AudioConfig: tt.fields.AudioConfig,
+				Transforms:  tt.fields.Transforms,
+				Event:       tt.fields.Event,
+				Webhooks:    tt.fields.Webhooks,
+				Owner:       tt.fields.Owner,
+				Labels:      tt.fields.Labels,
+				Limits:      tt.fields.Limits,
 				JobFormat:   tt.fields.JobFormat,
+				JobSchedule: tt.fields.JobSchedule,
 			}
 			if err := jcr.Validate(); (err != nil) != tt.wantErr {
 				t.Errorf("JobCreateRequest.Validate() error = %v, wantErr %v", err, tt.wantErr)
@@ -332,3 +687,328 @@ func TestJobCreateRequest_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestJobSchedule(t *testing.T) {
+	now := time.Date(2025, 6, 7, 14, 33, 20, 0, time.UTC)
+	tests := []struct {
+		name      string
+		js        JobSchedule
+		wantStart time.Time
+		wantStop  time.Time
+	}{
+		{name: "Unscheduled", js: JobSchedule{}},
+		{
+			name:      "One-off with stop time",
+			js:        JobSchedule{StartAt: "2025-06-07T15:00:00Z", StopAt: "2025-06-07T16:00:00Z"},
+			wantStart: time.Date(2025, 6, 7, 15, 0, 0, 0, time.UTC),
+			wantStop:  time.Date(2025, 6, 7, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Start time already passed",
+			js:       JobSchedule{StartAt: "2025-06-07T14:00:00Z", Duration: 60},
+			wantStop: now.Add(time.Minute),
+		},
+		{
+			name:      "Recurring",
+			js:        JobSchedule{Schedule: "*/15 * * * *", Duration: 300},
+			wantStart: time.Date(2025, 6, 7, 14, 45, 0, 0, time.UTC),
+			wantStop:  now.Add(5 * time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, ok := tt.js.NextStart(now)
+			if ok != !tt.wantStart.IsZero() || !start.Equal(tt.wantStart) {
+				t.Errorf("JobSchedule.NextStart() = %v, %v, want %v", start, ok, tt.wantStart)
+			}
+			stop, ok := tt.js.StopTime(now)
+			if ok != !tt.wantStop.IsZero() || !stop.Equal(tt.wantStop) {
+				t.Errorf("JobSchedule.StopTime() = %v, %v, want %v", stop, ok, tt.wantStop)
+			}
+		})
+	}
+}
+
+func TestEventConfig_PhaseAt(t *testing.T) {
+	ec := &EventConfig{PreRollSeconds: 60, LiveSeconds: 600, PostRollSeconds: 30}
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    EventPhase
+	}{
+		{name: "Just started", elapsed: 0, want: EventPhasePreEvent},
+		{name: "Going live", elapsed: time.Minute, want: EventPhaseLive},
+		{name: "Live", elapsed: 5 * time.Minute, want: EventPhaseLive},
+		{name: "Post event", elapsed: 11 * time.Minute, want: EventPhasePostEvent},
+		{name: "Ended", elapsed: 11*time.Minute + 30*time.Second, want: EventPhaseEnded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ec.PhaseAt(tt.elapsed); got != tt.want {
+				t.Errorf("EventConfig.PhaseAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhook_MarshalJSON(t *testing.T) {
+	wh := Webhook{URL: "http://localhost:8080/hooks", Secret: "s3cret"}
+	data, err := json.Marshal(wh)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Errorf("json.Marshal() = %s, leaks the secret", data)
+	}
+	var decoded Webhook
+	if err := json.Unmarshal([]byte(`{"url":"http://localhost:8080/hooks","secret":"s3cret"}`), &decoded); err != nil || decoded.Secret != "s3cret" {
+		t.Errorf("json.Unmarshal() = %+v, %v, want the secret accepted", decoded, err)
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		request JobCreateRequest
+		want    []FieldError
+	}{
+		{name: "Valid", request: JobCreateRequest{Description: "Test job"}, want: []FieldError{}},
+		{
+			name: "Top level and track fields",
+			request: JobCreateRequest{
+				VideoTrack: &VideoTrack{Codec: "mpeg2"},
+				AudioTrack: &AudioTrack{AudioChannels: "9"},
+				JobFormat:  JobFormat{WindowSize: -1},
+			},
+			want: []FieldError{
+				{Field: "window_size", Message: "window_size must be greater than 0"},
+				{Field: "video.codec", Message: "video codec must be one of: [h264 hevc vp9 av1]"},
+				{Field: "audio.channels", Message: "audio channels must not exceed 8"},
+			},
+		},
+		{
+			name:    "Window too large for ffmpeg",
+			request: JobCreateRequest{JobFormat: JobFormat{WindowSize: MaxWindowSize + 1, VODOnStop: true}},
+			want:    []FieldError{{Field: "window_size", Message: "window_size must not exceed 1048576"}},
+		},
+		{
+			name: "Nested configurations",
+			request: JobCreateRequest{
+				Transforms: &ManifestTransforms{InjectMediaTags: []string{"EXT-X-FOO"}},
+				Webhooks:   []Webhook{{URL: "https://example.com/hook"}, {URL: "example.com"}},
+				Event:      &EventConfig{LiveSeconds: 60, PostRollText: "50% off"},
+			},
+			want: []FieldError{
+				{Field: "manifest_transforms.inject_media_tags", Message: `injected tag "EXT-X-FOO" must be a single line starting with #EXT`},
+				{Field: "webhooks[1].url", Message: "webhook url must be an absolute http or https URL"},
+				{Field: "event.post_roll_text", Message: `event slate texts must be at most 50 characters without ' \ : or %`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FieldErrors(tt.request.Validate())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FieldErrors() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobCreateRequest_Validate_MaxResolution(t *testing.T) {
+	width, height := config.MAX_VIDEO_WIDTH, config.MAX_VIDEO_HEIGHT
+	t.Cleanup(func() { config.MAX_VIDEO_WIDTH, config.MAX_VIDEO_HEIGHT = width, height })
+	config.MAX_VIDEO_WIDTH, config.MAX_VIDEO_HEIGHT = 1920, 1080
+
+	tests := []struct {
+		name       string
+		resolution string
+		want       []FieldError
+	}{
+		{name: "Within the limits", resolution: "1920x1080", want: []FieldError{}},
+		{
+			name:       "Wider than the limit",
+			resolution: "2560x1080",
+			want:       []FieldError{{Field: "video.resolution", Message: "video resolution must not exceed 1920x1080"}},
+		},
+		{
+			name:       "Taller than the limit",
+			resolution: "1920x1440",
+			want:       []FieldError{{Field: "video.resolution", Message: "video resolution must not exceed 1920x1080"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jcr := JobCreateRequest{VideoTrack: &VideoTrack{Resolution: tt.resolution}}
+			got := FieldErrors(jcr.Validate())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FieldErrors() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobCreateRequest_Update(t *testing.T) {
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	current := func() JobCreateRequest {
+		jcr := JobCreateRequest{
+			Description: "Test job",
+			VideoTrack:  &VideoTrack{BitRate: "2M", Resolution: "640x360"},
+			Webhooks:    []Webhook{{URL: "https://example.com/hook", Secret: "s3cret"}},
+			Labels:      map[string]string{"team": "player-web", "ci_run": "8812"},
+		}
+		if err := jcr.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		// Valid when the job was created
+		jcr.StartAt = past
+		return jcr
+	}
+	tests := []struct {
+		name        string
+		patch       string
+		check       func(JobCreateRequest) bool
+		wantChanged []string
+		wantErr     []FieldError
+	}{
+		{
+			name:  "Objects are merged",
+			patch: `{"video": {"bitrate": "3M"}, "description": "Updated"}`,
+			check: func(u JobCreateRequest) bool {
+				return u.VideoTrack.BitRate == "3M" && u.VideoTrack.Resolution == "640x360"
+			},
+			wantChanged: []string{"description", "video"},
+		},
+		{
+			name:  "Null resets to the default",
+			patch: `{"video": {"resolution": null}}`,
+			check: func(u JobCreateRequest) bool {
+				return u.VideoTrack.Resolution == "1280x720" && u.VideoTrack.BitRate == "2M"
+			},
+			wantChanged: []string{"video"},
+		},
+		{
+			name:  "Labels are merged by key",
+			patch: `{"labels": {"ci_run": null, "env": "lab"}}`,
+			check: func(u JobCreateRequest) bool {
+				return reflect.DeepEqual(u.Labels, map[string]string{"team": "player-web", "env": "lab"})
+			},
+			wantChanged: []string{"labels"},
+		},
+		{
+			name:  "Unchanged values",
+			patch: `{"description": "Test job", "segment_length": 6, "vod_on_stop": false}`,
+			check: func(u JobCreateRequest) bool {
+				return u.Webhooks[0].Secret == "s3cret" && u.StartAt == past
+			},
+		},
+		{
+			name:    "Unknown settings",
+			patch:   `{"status": "running", "id": "x"}`,
+			wantErr: []FieldError{{Field: "id", Message: "id is not a setting of the job"}, {Field: "status", Message: "status is not a setting of the job"}},
+		},
+		{
+			name:    "Invalid values",
+			patch:   `{"video": {"bitrate": "3x"}, "start_at": "` + past + `x"}`,
+			wantErr: []FieldError{{Field: "start_at", Message: "start_at must be an RFC3339 time"}, {Field: "video.bitrate", Message: "video bitrate must end with k or M"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatal(err)
+			}
+			jcr := current()
+			got, changed, err := jcr.Update(patch)
+			if tt.wantErr != nil {
+				if fe := FieldErrors(err); !reflect.DeepEqual(fe, tt.wantErr) {
+					t.Errorf("Update() errors = %+v, want %+v", fe, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if !reflect.DeepEqual(changed, tt.wantChanged) {
+				t.Errorf("Update() changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if !tt.check(got) {
+				t.Errorf("Update() = %+v", got)
+			}
+			if !reflect.DeepEqual(jcr, current()) {
+				t.Errorf("Update() modified the request it was called on")
+			}
+		})
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	jcr := current()
+	if _, _, err := jcr.Update(map[string]json.RawMessage{"segment_length": json.RawMessage(`"6"`)}); !errors.As(err, &typeErr) {
+		t.Errorf("Update() of a string segment_length error = %v, want an UnmarshalTypeError", err)
+	}
+}
+
+func TestUpdateModes(t *testing.T) {
+	// Every setting of the create request can be updated
+	var fields []string
+	var collect func(reflect.Type)
+	collect = func(typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if f.Anonymous {
+				collect(f.Type)
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			fields = append(fields, name)
+		}
+	}
+	collect(reflect.TypeOf(JobCreateRequest{}))
+	for _, field := range fields {
+		if _, ok := UpdateModes[field]; !ok {
+			t.Errorf("UpdateModes has no mode for %s", field)
+		}
+	}
+	if len(UpdateModes) != len(fields) {
+		t.Errorf("UpdateModes has %d settings, want the %d fields of JobCreateRequest", len(UpdateModes), len(fields))
+	}
+}
+
+func TestTemplate_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template Template
+		want     []FieldError
+	}{
+		{name: "Valid", template: Template{Name: "team-1080p", Request: JobCreateRequest{VideoTrack: &VideoTrack{Resolution: "1920x1080"}}}, want: []FieldError{}},
+		{
+			name:     "Invalid name",
+			template: Template{Name: "../Team"},
+			want:     []FieldError{{Field: "name", Message: "template name must be 1 to 64 lowercase letters, digits, '.', '_' or '-', starting with a letter or digit"}},
+		},
+		{
+			name: "Invalid request",
+			template: Template{Name: "team", Request: JobCreateRequest{
+				VideoTrack: &VideoTrack{Codec: "mpeg2"},
+				Webhooks:   []Webhook{{URL: "https://example.com/hook", Secret: "s3cret"}},
+			}},
+			want: []FieldError{
+				{Field: "request.webhooks[0].secret", Message: "templates cannot keep webhook secrets, set the webhook in the overrides"},
+				{Field: "request.video.codec", Message: "video codec must be one of: [h264 hevc vp9 av1]"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := tt.template
+			got := FieldErrors(template.Validate())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FieldErrors() = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(template, tt.template) {
+				t.Errorf("Validate() modified the template")
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	maxOwnerLength = 128
+	maxLabels      = 32
+)
+
+var (
+	// labelKey and labelValue keep labels usable in query parameters,
+	// e.g. label=team=player-web
+	labelKey   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]{0,62}$`)
+	labelValue = regexp.MustCompile(`^[A-Za-z0-9._:/@+-]{1,128}$`)
+)
+
+// validateLabels checks the labels of a job, in the order of their keys.
+func validateLabels(labels map[string]string) []error {
+	var errs []error
+	if len(labels) > maxLabels {
+		errs = append(errs, fieldError("labels", "a job can have at most %d labels", maxLabels))
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !labelKey.MatchString(key) {
+			errs = append(errs, fieldError("labels", "label key %q must be 1 to 63 letters, digits, '.', '_', '/' or '-', starting with a letter or digit", key))
+			continue
+		}
+		if !labelValue.MatchString(labels[key]) {
+			errs = append(errs, fieldError("labels."+key, "label value must be 1 to 128 letters, digits or any of . _ : / @ + -"))
+		}
+	}
+	return errs
+}
+
+// parseLabelSelector reads a label filter, key=value for a label with the
+// value or key for any job with the label.
+func parseLabelSelector(selector string) (key, value string, err error) {
+	key, value, hasValue := strings.Cut(selector, "=")
+	if !labelKey.MatchString(key) || (hasValue && !labelValue.MatchString(value)) {
+		return "", "", fieldError("label", "label filter %q must be key=value or key", selector)
+	}
+	return key, value, nil
+}
+
+// MatchLabels reports whether labels have all the labels of a selector, an
+// empty value in the selector matches any value.
+func MatchLabels(labels, selector map[string]string) bool {
+	for key, want := range selector {
+		value, ok := labels[key]
+		if !ok || (want != "" && value != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// labelSelectors returns a selector as key=value filters, sorted by key.
+func labelSelectors(selector map[string]string) []string {
+	list := make([]string, 0, len(selector))
+	for key, value := range selector {
+		if value == "" {
+			list = append(list, key)
+		} else {
+			list = append(list, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+	sort.Strings(list)
+	return list
+}
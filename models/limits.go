@@ -0,0 +1,55 @@
+package models
+
+import "github.com/arunjeyaprasad/golive/config"
+
+const (
+	maxThreads = 64
+	maxNice    = 19
+	// minCPUs is the smallest cgroup v2 CPU quota, 1ms per 100ms period
+	minCPUs = 0.01
+	// maxCPUs is more cores than a host has, larger quotas overflow cpu.max
+	maxCPUs = 1024
+	// minMemory is the least memory ffmpeg needs to encode the test pattern
+	minMemory = 64 << 20
+)
+
+// JobLimits constrain the ffmpeg process of a job so that it cannot starve
+// the other jobs on the host. Every limit is optional.
+type JobLimits struct {
+	Threads int     `json:"threads,omitempty"` // Encoder and filter threads, ffmpeg picks one per core by default
+	Nice    int     `json:"nice,omitempty"`    // Scheduling niceness from 0 to 19, higher yields to other jobs
+	CPUs    float64 `json:"cpus,omitempty"`    // Cores the process may use, needs a cgroup v2
+	Memory  string  `json:"memory,omitempty"`  // Memory the process may use, e.g. 2G, needs a cgroup v2
+}
+
+// MemoryBytes returns the memory limit in bytes, 0 if there is none.
+func (jl *JobLimits) MemoryBytes() int64 {
+	n, _ := config.ParseSize(jl.Memory)
+	return n
+}
+
+// Cgroup reports whether the limits are enforced with a cgroup.
+func (jl *JobLimits) Cgroup() bool {
+	return jl.CPUs > 0 || jl.Memory != ""
+}
+
+func (jl *JobLimits) validate() []error {
+	var errs []error
+	if jl.Threads < 0 || jl.Threads > maxThreads {
+		errs = append(errs, fieldError("threads", "threads must be between 0 and %d", maxThreads))
+	}
+	if jl.Nice < 0 || jl.Nice > maxNice {
+		errs = append(errs, fieldError("nice", "nice must be between 0 and %d, jobs cannot be prioritized over the server", maxNice))
+	}
+	if jl.CPUs != 0 && (jl.CPUs < minCPUs || jl.CPUs > maxCPUs) {
+		errs = append(errs, fieldError("cpus", "cpus must be between %g and %d", minCPUs, maxCPUs))
+	}
+	if jl.Memory != "" {
+		if n, err := config.ParseSize(jl.Memory); err != nil {
+			errs = append(errs, fieldError("memory", "memory must be a number of bytes with an optional K, M, G or T suffix"))
+		} else if n < minMemory {
+			errs = append(errs, fieldError("memory", "memory must be at least 64M"))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes of job lists
+const (
+	DefaultJobListLimit = 50
+	MaxJobListLimit     = 500
+)
+
+// JobSortFields are the fields job lists can be sorted by, prefixed with
+// "-" for descending order.
+var JobSortFields = []string{"created", "status", "description"}
+
+// JobListQuery filters, sorts and pages a job list. Empty filters match
+// every job, repeated values of a filter match any of them. Labels are the
+// exception, a job must have all of them.
+type JobListQuery struct {
+	Statuses      []string          // status
+	Codecs        []string          // codec, of the video or the audio track
+	Owners        []string          // owner
+	Labels        map[string]string // label, key=value or key for any value
+	CreatedAfter  time.Time         // created_after
+	CreatedBefore time.Time         // created_before
+	Query         string            // q, a case insensitive substring of the description
+	Sort          string            // sort, e.g. -created, by creation by default
+	Limit         int               // limit
+	Offset        int               // offset
+}
+
+// JobList is a page of jobs.
+type JobList struct {
+	Jobs       []Job `json:"jobs"`
+	Total      int   `json:"total"` // Jobs matching the filters, on all pages
+	Offset     int   `json:"offset"`
+	Limit      int   `json:"limit"`
+	NextOffset int   `json:"next_offset,omitempty"` // Offset of the next page, if there is one
+}
+
+// ParseJobListQuery reads a query from URL query parameters. Filters take
+// repeated or comma separated values, e.g. status=created,running.
+func ParseJobListQuery(params map[string][]string) (JobListQuery, error) {
+	q := JobListQuery{Sort: "created", Limit: DefaultJobListLimit}
+	var errs []error
+	q.Statuses = listParam(params["status"])
+	q.Codecs = listParam(params["codec"])
+	q.Owners = listParam(params["owner"])
+	for _, selector := range listParam(params["label"]) {
+		key, value, err := parseLabelSelector(selector)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if q.Labels == nil {
+			q.Labels = make(map[string]string)
+		}
+		q.Labels[key] = value
+	}
+	q.Query = lastParam(params["q"])
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"created_after", &q.CreatedAfter}, {"created_before", &q.CreatedBefore}} {
+		name, t := p.name, p.t
+		value := lastParam(params[name])
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			errs = append(errs, fieldError(name, "%s must be an RFC3339 timestamp", name))
+			continue
+		}
+		*t = parsed
+	}
+	if value := lastParam(params["sort"]); value != "" {
+		q.Sort = value
+		field := strings.TrimPrefix(value, "-")
+		valid := false
+		for _, f := range JobSortFields {
+			if field == f {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			errs = append(errs, fieldError("sort", "sort must be one of %v, prefixed with - for descending order", JobSortFields))
+		}
+	}
+	if value := lastParam(params["limit"]); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxJobListLimit {
+			errs = append(errs, fieldError("limit", "limit must be between 1 and %d", MaxJobListLimit))
+		}
+		q.Limit = limit
+	}
+	if value := lastParam(params["offset"]); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			errs = append(errs, fieldError("offset", "offset must be 0 or greater"))
+		}
+		q.Offset = offset
+	}
+	if err := errors.Join(errs...); err != nil {
+		return JobListQuery{}, err
+	}
+	return q, nil
+}
+
+// Filtered reports whether the query has any filters, bulk operations
+// refuse to apply to every job.
+func (q JobListQuery) Filtered() bool {
+	return len(q.Statuses) > 0 || len(q.Codecs) > 0 || len(q.Owners) > 0 || len(q.Labels) > 0 ||
+		!q.CreatedAfter.IsZero() || !q.CreatedBefore.IsZero() || q.Query != ""
+}
+
+// Values returns the query as URL query parameters, see ParseJobListQuery.
+func (q JobListQuery) Values() url.Values {
+	values := url.Values{}
+	if len(q.Statuses) > 0 {
+		values.Set("status", strings.Join(q.Statuses, ","))
+	}
+	if len(q.Codecs) > 0 {
+		values.Set("codec", strings.Join(q.Codecs, ","))
+	}
+	if len(q.Owners) > 0 {
+		values.Set("owner", strings.Join(q.Owners, ","))
+	}
+	if len(q.Labels) > 0 {
+		values.Set("label", strings.Join(labelSelectors(q.Labels), ","))
+	}
+	if !q.CreatedAfter.IsZero() {
+		values.Set("created_after", q.CreatedAfter.Format(time.RFC3339))
+	}
+	if !q.CreatedBefore.IsZero() {
+		values.Set("created_before", q.CreatedBefore.Format(time.RFC3339))
+	}
+	if q.Query != "" {
+		values.Set("q", q.Query)
+	}
+	if q.Sort != "" {
+		values.Set("sort", q.Sort)
+	}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		values.Set("offset", strconv.Itoa(q.Offset))
+	}
+	return values
+}
+
+// listParam splits repeated and comma separated values.
+func listParam(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				list = append(list, v)
+			}
+		}
+	}
+	return list
+}
+
+func lastParam(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// BulkJobResponse is the result of an operation on the jobs matching a query.
+type BulkJobResponse struct {
+	Jobs    []string     `json:"jobs"`    // Jobs the operation applied to
+	Skipped []SkippedJob `json:"skipped"` // Matching jobs it could not apply to
+}
+
+// SkippedJob is a job a bulk operation left as it was.
+type SkippedJob struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
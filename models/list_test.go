@@ -0,0 +1,81 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseJobListQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     map[string][]string
+		want       JobListQuery
+		wantFields []string
+	}{
+		{
+			name:   "Defaults",
+			params: map[string][]string{},
+			want:   JobListQuery{Sort: "created", Limit: DefaultJobListLimit},
+		},
+		{
+			name: "Filters, sort and page",
+			params: map[string][]string{
+				"status":        {"created,running", "error"},
+				"codec":         {"hevc"},
+				"owner":         {"player-web"},
+				"label":         {"team=player-web,ci_run", "env=lab"},
+				"created_after": {"2026-01-02T15:04:05Z"},
+				"q":             {"lab"},
+				"sort":          {"-created"},
+				"limit":         {"10"},
+				"offset":        {"20"},
+			},
+			want: JobListQuery{
+				Statuses:     []string{"created", "running", "error"},
+				Codecs:       []string{"hevc"},
+				Owners:       []string{"player-web"},
+				Labels:       map[string]string{"team": "player-web", "ci_run": "", "env": "lab"},
+				CreatedAfter: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+				Query:        "lab",
+				Sort:         "-created",
+				Limit:        10,
+				Offset:       20,
+			},
+		},
+		{
+			name: "Invalid values",
+			params: map[string][]string{
+				"label":          {"team=player web"},
+				"created_after":  {"yesterday"},
+				"created_before": {"2026-01-02"},
+				"sort":           {"bitrate"},
+				"limit":          {"1000"},
+				"offset":         {"-1"},
+			},
+			wantFields: []string{"label", "created_after", "created_before", "sort", "limit", "offset"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJobListQuery(tt.params)
+			var fields []string
+			for _, fe := range FieldErrors(err) {
+				fields = append(fields, fe.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Fatalf("ParseJobListQuery() error fields = %v, want %v", fields, tt.wantFields)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseJobListQuery() = %+v, want %+v", got, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			// The query survives a round trip through URL parameters
+			if again, err := ParseJobListQuery(got.Values()); err != nil || !reflect.DeepEqual(again, got) {
+				t.Errorf("ParseJobListQuery(Values()) = %+v, %v, want %+v", again, err, got)
+			}
+		})
+	}
+}
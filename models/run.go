@@ -0,0 +1,16 @@
+package models
+
+// Run is one start of a job. Every run writes to its own output directory,
+// so repeated runs of a job do not overwrite each other.
+type Run struct {
+	Number       int            `json:"number"` // Runs are numbered from 1
+	Status       string         `json:"status"` // running, completed or error
+	StartedAt    string         `json:"started"`
+	CompletedAt  string         `json:"completed,omitempty"`
+	PlaybackURLs []PlaybackURLs `json:"playback_urls,omitempty"`
+	Segments     int            `json:"segments"`             // Media segments written, across all playlists
+	Stats        *EncoderStats  `json:"stats,omitempty"`      // Last progress report of the encoder
+	Error        string         `json:"error,omitempty"`      // Why the run failed
+	RestartOf    int            `json:"restart_of,omitempty"` // The run whose encoder this one replaced to apply an update
+	Expired      bool           `json:"expired,omitempty"`    // The retention policies deleted the output of the run
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/arunjeyaprasad/golive/pkg/cron"
+)
+
+// JobSchedule starts and stops a job automatically. Times are RFC3339.
+type JobSchedule struct {
+	StartAt  string `json:"start_at,omitempty"` // Start the job at this time instead of waiting for a start request
+	StopAt   string `json:"stop_at,omitempty"`  // Stop the job at this time
+	Duration int    `json:"duration,omitempty"` // Stop the job this many seconds after it started
+	Schedule string `json:"schedule,omitempty"` // Cron expression of recurring start times, each run lasts duration or the event
+}
+
+// ScheduledRun is the next or current scheduled run of a job.
+type ScheduledRun struct {
+	StartAt string `json:"start_at,omitempty"`
+	StopAt  string `json:"stop_at,omitempty"`
+}
+
+// NextStart returns the next time the job should be started after now.
+func (js JobSchedule) NextStart(now time.Time) (time.Time, bool) {
+	if js.Schedule != "" {
+		schedule, err := cron.Parse(js.Schedule)
+		if err != nil {
+			return time.Time{}, false
+		}
+		next := schedule.Next(now)
+		return next, !next.IsZero()
+	}
+	if startAt, err := time.Parse(time.RFC3339, js.StartAt); err == nil && startAt.After(now) {
+		return startAt, true
+	}
+	return time.Time{}, false
+}
+
+// StopTime returns the time a run that started at startedAt should stop.
+func (js JobSchedule) StopTime(startedAt time.Time) (time.Time, bool) {
+	if stopAt, err := time.Parse(time.RFC3339, js.StopAt); err == nil {
+		return stopAt, true
+	}
+	if js.Duration > 0 {
+		return startedAt.Add(time.Duration(js.Duration) * time.Second), true
+	}
+	return time.Time{}, false
+}
+
+func (js JobSchedule) validate(now time.Time) []error {
+	var (
+		errs             []error
+		startAt, stopAt  time.Time
+		startErr, endErr error
+	)
+	if js.StartAt != "" {
+		if startAt, startErr = time.Parse(time.RFC3339, js.StartAt); startErr != nil {
+			errs = append(errs, fieldError("start_at", "start_at must be an RFC3339 time"))
+		} else if !startAt.After(now) {
+			errs = append(errs, fieldError("start_at", "start_at must be in the future"))
+		}
+	}
+	if js.StopAt != "" {
+		if stopAt, endErr = time.Parse(time.RFC3339, js.StopAt); endErr != nil {
+			errs = append(errs, fieldError("stop_at", "stop_at must be an RFC3339 time"))
+		} else if !stopAt.After(now) || (js.StartAt != "" && startErr == nil && !stopAt.After(startAt)) {
+			errs = append(errs, fieldError("stop_at", "stop_at must be after start_at and in the future"))
+		}
+	}
+	if js.Duration < 0 {
+		errs = append(errs, fieldError("duration", "duration must not be negative"))
+	} else if js.Duration > 0 && js.StopAt != "" {
+		errs = append(errs, fieldError("duration", "only one of stop_at and duration can be set"))
+	}
+	if js.Schedule != "" {
+		if _, err := cron.Parse(js.Schedule); err != nil {
+			errs = append(errs, fieldError("schedule", "schedule must be a cron expression: %v", err))
+		}
+		if js.StartAt != "" || js.StopAt != "" {
+			errs = append(errs, fieldError("schedule", "schedule cannot be combined with start_at or stop_at"))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Template is a named job configuration that jobs can be created from.
+type Template struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	BuiltIn     bool             `json:"built_in"` // Shipped with the server, it cannot be changed
+	Request     JobCreateRequest `json:"request"`
+}
+
+// JobTemplateRequest creates a job from a template. The overrides are a JSON
+// merge patch of the request of the template, see JobCreateRequest.Merge.
+type JobTemplateRequest struct {
+	Template  string                     `json:"template"`
+	Overrides map[string]json.RawMessage `json:"overrides,omitempty"`
+}
+
+// templateName keeps names usable in URLs and as file names
+var templateName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// Validate checks that the template name is valid and that its request can
+// create a job on its own.
+func (t *Template) Validate() error {
+	var errs []error
+	if !templateName.MatchString(t.Name) {
+		errs = append(errs, fieldError("name", "template name must be 1 to 64 lowercase letters, digits, '.', '_' or '-', starting with a letter or digit"))
+	}
+	for i, wh := range t.Request.Webhooks {
+		if wh.Secret != "" {
+			// Stored templates are marshalled, which leaves secrets out
+			errs = append(errs, fieldError(fmt.Sprintf("request.webhooks[%d].secret", i), "templates cannot keep webhook secrets, set the webhook in the overrides"))
+		}
+	}
+	// Validate a copy, it fills in the defaults
+	request, err := t.Request.Merge(nil)
+	if err == nil {
+		err = request.Validate()
+	}
+	if err != nil {
+		errs = append(errs, inField("request", unwrap(err))...)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+)
+
+type VariantOrder string
+
+const (
+	VariantOrderBandwidthAsc  VariantOrder = "bandwidth_asc"
+	VariantOrderBandwidthDesc VariantOrder = "bandwidth_desc"
+	VariantOrderReverse       VariantOrder = "reverse"
+)
+
+// ManifestTransforms are rewrites applied to a job's manifests on every request,
+// used to test players against malformed or CDN modified manifests.
+type ManifestTransforms struct {
+	DropRenditions    []string     `json:"drop_renditions,omitempty"`     // DASH representation ids or HLS playlist URIs / rendition names to remove
+	ReorderVariants   VariantOrder `json:"reorder_variants,omitempty"`    // Order of the variants in the HLS master playlist
+	AbsoluteURLs      bool         `json:"absolute_urls,omitempty"`       // Rewrite relative URLs to absolute ones on the requested host
+	URLPrefix         string       `json:"url_prefix,omitempty"`          // Prefix segment and playlist URLs with a CDN base URL
+	InjectMasterTags  []string     `json:"inject_master_tags,omitempty"`  // Extra tags for the HLS master playlist
+	InjectMediaTags   []string     `json:"inject_media_tags,omitempty"`   // Extra tags for the HLS media playlists
+	BandwidthScale    float64      `json:"bandwidth_scale,omitempty"`     // Multiplier for the advertised BANDWIDTH / @bandwidth
+	DVRWindowSegments int          `json:"dvr_window_segments,omitempty"` // Only advertise the most recent N segments
+	TargetDuration    int          `json:"target_duration,omitempty"`     // Advertised EXT-X-TARGETDURATION regardless of the real segments
+}
+
+func (mt *ManifestTransforms) validate() []error {
+	var errs []error
+	switch mt.ReorderVariants {
+	case "", VariantOrderBandwidthAsc, VariantOrderBandwidthDesc, VariantOrderReverse:
+	default:
+		errs = append(errs, fieldError("reorder_variants", "reorder_variants must be one of: %v",
+			[]VariantOrder{VariantOrderBandwidthAsc, VariantOrderBandwidthDesc, VariantOrderReverse}))
+	}
+	if mt.URLPrefix != "" {
+		u, err := url.Parse(mt.URLPrefix)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fieldError("url_prefix", "url_prefix must be an absolute http or https URL"))
+		}
+	}
+	for _, tag := range mt.InjectMasterTags {
+		if !validTag(tag) {
+			errs = append(errs, fieldError("inject_master_tags", "injected tag %q must be a single line starting with #EXT", tag))
+		}
+	}
+	for _, tag := range mt.InjectMediaTags {
+		if !validTag(tag) {
+			errs = append(errs, fieldError("inject_media_tags", "injected tag %q must be a single line starting with #EXT", tag))
+		}
+	}
+	if mt.BandwidthScale < 0 || mt.BandwidthScale > 100 {
+		errs = append(errs, fieldError("bandwidth_scale", "bandwidth_scale must be between 0 and 100"))
+	}
+	if mt.DVRWindowSegments < 0 {
+		errs = append(errs, fieldError("dvr_window_segments", "dvr_window_segments must not be negative"))
+	}
+	if mt.TargetDuration < 0 {
+		errs = append(errs, fieldError("target_duration", "target_duration must not be negative"))
+	}
+	return errs
+}
+
+func validTag(tag string) bool {
+	return strings.HasPrefix(tag, "#EXT") && !strings.ContainsAny(tag, "\r\n")
+}
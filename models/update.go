@@ -0,0 +1,185 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+)
+
+// UpdateMode is how a changed setting is applied to a running job.
+type UpdateMode string
+
+const (
+	UpdateModeHot     UpdateMode = "hot"     // Applied to the running encoder
+	UpdateModeRestart UpdateMode = "restart" // Restarts the encoder, players see a discontinuity
+)
+
+// UpdateModes lists the settings of a job that can be updated, by their
+// field in the create request.
+var UpdateModes = map[string]UpdateMode{
+	"description":         UpdateModeHot, // The overlay text
+	"manifest_transforms": UpdateModeHot, // Applied as the manifests are served
+	"webhooks":            UpdateModeHot,
+	"owner":               UpdateModeHot,
+	"labels":              UpdateModeHot, // Merged by key, null removes a label
+	"start_at":            UpdateModeHot, // Rearms the scheduled start or stop
+	"stop_at":             UpdateModeHot,
+	"duration":            UpdateModeHot,
+	"schedule":            UpdateModeHot,
+	"video":               UpdateModeRestart,
+	"audio":               UpdateModeRestart,
+	"audio_config":        UpdateModeRestart,
+	"output_format":       UpdateModeRestart,
+	"segment_length":      UpdateModeRestart,
+	"window_size":         UpdateModeRestart,
+	"dvr_window_seconds":  UpdateModeRestart,
+	"vod_on_stop":         UpdateModeRestart,
+	"event":               UpdateModeRestart, // The event starts over with its pre-roll
+	"limits":              UpdateModeRestart,
+}
+
+// FieldUpdate is a setting changed by an update.
+type FieldUpdate struct {
+	Field string     `json:"field"`
+	Mode  UpdateMode `json:"mode"`
+}
+
+// JobUpdateResponse is the result of updating a job.
+type JobUpdateResponse struct {
+	Job       Job           `json:"job"`
+	Changes   []FieldUpdate `json:"changes"`
+	Restarted bool          `json:"restarted"` // The encoder was restarted to apply the changes
+}
+
+// Merge applies a JSON merge patch (RFC 7396) to a copy of the request.
+// Objects in the patch are merged, other values replace the setting and null
+// resets it to its default. The result is not validated.
+func (jcr JobCreateRequest) Merge(patch map[string]json.RawMessage) (JobCreateRequest, error) {
+	var errs []error
+	for _, field := range sortedFields(patch) {
+		if _, ok := UpdateModes[field]; !ok {
+			errs = append(errs, fieldError(field, "%s is not a setting of the job", field))
+		}
+	}
+	if len(errs) > 0 {
+		return jcr, errors.Join(errs...)
+	}
+
+	doc, err := toDocument(jcr)
+	if err != nil {
+		return jcr, err
+	}
+	for field, raw := range patch {
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return jcr, err
+		}
+		if merged := mergePatch(doc[field], value); merged != nil {
+			doc[field] = merged
+		} else {
+			delete(doc, field)
+		}
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return jcr, err
+	}
+	var merged JobCreateRequest
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return jcr, err
+	}
+	if _, ok := patch["webhooks"]; !ok {
+		// Secrets are never marshalled, keep the webhooks as they are
+		merged.Webhooks = jcr.Webhooks
+	}
+	return merged, nil
+}
+
+// Update merges a patch into a copy of the request of a job and validates the
+// result. It returns the settings that changed, sorted.
+func (jcr JobCreateRequest) Update(patch map[string]json.RawMessage) (JobCreateRequest, []string, error) {
+	updated, err := jcr.Merge(patch)
+	if err != nil {
+		return jcr, nil, err
+	}
+	if err := updated.validateUpdate(jcr); err != nil {
+		return jcr, nil, err
+	}
+
+	before, err := toDocument(jcr)
+	if err != nil {
+		return jcr, nil, err
+	}
+	after, err := toDocument(updated)
+	if err != nil {
+		return jcr, nil, err
+	}
+	var changed []string
+	for _, field := range sortedFields(patch) {
+		if field == "webhooks" || !reflect.DeepEqual(before[field], after[field]) {
+			changed = append(changed, field)
+		}
+	}
+	return updated, changed, nil
+}
+
+// validateUpdate validates an updated request. Schedule times of the previous
+// request may have passed since it was created, they are only checked if they
+// changed.
+func (jcr *JobCreateRequest) validateUpdate(previous JobCreateRequest) error {
+	err := jcr.Validate()
+	if err == nil {
+		return nil
+	}
+	var errs []error
+	for _, err := range unwrap(err) {
+		var fe *FieldError
+		if errors.As(err, &fe) && jcr.StartAt == previous.StartAt &&
+			(fe.Field == "start_at" || (fe.Field == "stop_at" && jcr.StopAt == previous.StopAt)) {
+			continue
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// toDocument returns the JSON object of a request.
+func toDocument(jcr JobCreateRequest) (map[string]any, error) {
+	data, err := json.Marshal(jcr)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	return doc, json.Unmarshal(data, &doc)
+}
+
+// sortedFields returns the fields of a patch in order.
+func sortedFields(patch map[string]json.RawMessage) []string {
+	fields := make([]string, 0, len(patch))
+	for field := range patch {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// mergePatch applies a JSON merge patch to a decoded JSON value.
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+type WebhookEvent string
+
+const (
+	WebhookEventCreated      WebhookEvent = "job.created"
+	WebhookEventQueued       WebhookEvent = "job.queued"
+	WebhookEventStarted      WebhookEvent = "job.started"
+	WebhookEventFirstSegment WebhookEvent = "job.first_segment"
+	WebhookEventStalled      WebhookEvent = "job.stalled"
+	WebhookEventUpdated      WebhookEvent = "job.updated"
+	WebhookEventPhaseChanged WebhookEvent = "job.phase_changed"
+	WebhookEventFailed       WebhookEvent = "job.failed"
+	WebhookEventStopped      WebhookEvent = "job.stopped"
+	WebhookEventCleanedUp    WebhookEvent = "job.cleaned_up"
+)
+
+var WebhookEvents = []WebhookEvent{
+	WebhookEventCreated,
+	WebhookEventQueued,
+	WebhookEventStarted,
+	WebhookEventFirstSegment,
+	WebhookEventStalled,
+	WebhookEventUpdated,
+	WebhookEventPhaseChanged,
+	WebhookEventFailed,
+	WebhookEventStopped,
+	WebhookEventCleanedUp,
+}
+
+// Webhook receives job lifecycle events as JSON POST requests.
+type Webhook struct {
+	URL    string         `json:"url"`
+	Secret string         `json:"secret,omitempty"` // Signs deliveries with HMAC-SHA256, never returned by the API
+	Events []WebhookEvent `json:"events,omitempty"` // Events to deliver, all when empty
+}
+
+// MarshalJSON leaves the secret out of API responses.
+func (wh Webhook) MarshalJSON() ([]byte, error) {
+	type webhook Webhook
+	out := webhook(wh)
+	out.Secret = ""
+	return json.Marshal(out)
+}
+
+// Wants reports whether the webhook subscribed to an event.
+func (wh Webhook) Wants(event WebhookEvent) bool {
+	if len(wh.Events) == 0 {
+		return true
+	}
+	for _, e := range wh.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload is the body of a webhook delivery.
+type WebhookPayload struct {
+	ID        string       `json:"id"` // Unique per delivery, retries reuse it
+	Event     WebhookEvent `json:"event"`
+	JobID     string       `json:"job_id"`
+	Timestamp string       `json:"timestamp"`
+	Job       Job          `json:"job"`
+}
+
+func (wh Webhook) validate() []error {
+	var errs []error
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fieldError("url", "webhook url must be an absolute http or https URL"))
+	}
+	for _, event := range wh.Events {
+		known := false
+		for _, e := range WebhookEvents {
+			if event == e {
+				known = true
+				break
+			}
+		}
+		if !known {
+			errs = append(errs, fieldError("events", "webhook events must be one of: %v", WebhookEvents))
+			break
+		}
+	}
+	return errs
+}
@@ -0,0 +1,61 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "lists ranges and steps", expr: "0,30 9-17/2 1-15 */3 mon-fri"},
+		{name: "names", expr: "0 12 * jan,jul sun"},
+		{name: "shortcut", expr: "@daily"},
+		{name: "too few fields", expr: "0 12 * *", wantErr: true},
+		{name: "minute out of range", expr: "60 * * * *", wantErr: true},
+		{name: "zero step", expr: "*/0 * * * *", wantErr: true},
+		{name: "reversed range", expr: "* 5-1 * * *", wantErr: true},
+		{name: "unknown name", expr: "* * * foo *", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.expr); (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	from := time.Date(2025, 6, 7, 14, 33, 20, 0, time.UTC) // A Saturday
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{name: "every minute", expr: "* * * * *", want: time.Date(2025, 6, 7, 14, 34, 0, 0, time.UTC)},
+		{name: "every 15 minutes", expr: "*/15 * * * *", want: time.Date(2025, 6, 7, 14, 45, 0, 0, time.UTC)},
+		{name: "daily", expr: "@daily", want: time.Date(2025, 6, 8, 0, 0, 0, 0, time.UTC)},
+		{name: "weekdays at nine", expr: "0 9 * * mon-fri", want: time.Date(2025, 6, 9, 9, 0, 0, 0, time.UTC)},
+		{name: "sunday as seven", expr: "30 8 * * 7", want: time.Date(2025, 6, 8, 8, 30, 0, 0, time.UTC)},
+		{name: "day of month or week", expr: "0 0 10 * 1", want: time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC)},
+		{name: "next year", expr: "0 0 1 jan *", want: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", expr: "0 0 29 2 *", want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "never", expr: "0 0 30 2 *", want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			if got := s.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package dash
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationPattern = regexp.MustCompile(`^(-)?P(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseDuration parses an xs:duration such as PT1M30.5S. Years and months are
+// not supported as their length is ambiguous.
+func ParseDuration(value string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(value)
+	if m == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("dash: invalid duration %q", value)
+	}
+	var total float64
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(m[i+2], 64)
+		if err != nil {
+			return 0, fmt.Errorf("dash: invalid duration %q: %w", value, err)
+		}
+		total += n * float64(unit)
+	}
+	d := time.Duration(total)
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// FormatDuration formats a duration the way ffmpeg writes them, e.g. PT1H2M3.5S.
+func FormatDuration(d time.Duration) string {
+	var sb strings.Builder
+	if d < 0 {
+		sb.WriteByte('-')
+		d = -d
+	}
+	sb.WriteString("PT")
+	if h := d / time.Hour; h > 0 {
+		fmt.Fprintf(&sb, "%dH", h)
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m > 0 {
+		fmt.Fprintf(&sb, "%dM", m)
+		d -= m * time.Minute
+	}
+	sb.WriteString(strconv.FormatFloat(d.Seconds(), 'f', 1, 64))
+	sb.WriteByte('S')
+	return sb.String()
+}
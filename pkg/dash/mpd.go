@@ -0,0 +1,199 @@
+// Package dash parses and serializes MPEG-DASH Media Presentation Descriptions.
+//
+// Elements and attributes the package does not model are preserved so that an
+// MPD survives a Decode/Encode round trip, although their position among known
+// siblings may change.
+package dash
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	Namespace = "urn:mpeg:dash:schema:mpd:2011"
+
+	TypeStatic  = "static"
+	TypeDynamic = "dynamic"
+)
+
+// prefixes maps well known namespace URLs to the prefix they are written with.
+var prefixes = map[string]string{
+	"http://www.w3.org/2001/XMLSchema-instance": "xsi",
+	"http://www.w3.org/1999/xlink":              "xlink",
+	"urn:mpeg:cenc:2013":                        "cenc",
+	"http://www.w3.org/XML/1998/namespace":      "xml",
+	"http://dashif.org/guidelines/clearKey":     "dashif",
+}
+
+// MPD is the root element of a Media Presentation Description.
+type MPD struct {
+	XMLName                    xml.Name     `xml:"MPD"`
+	ID                         string       `xml:"id,attr,omitempty"`
+	Profiles                   string       `xml:"profiles,attr,omitempty"`
+	Type                       string       `xml:"type,attr,omitempty"`
+	AvailabilityStartTime      string       `xml:"availabilityStartTime,attr,omitempty"`
+	AvailabilityEndTime        string       `xml:"availabilityEndTime,attr,omitempty"`
+	PublishTime                string       `xml:"publishTime,attr,omitempty"`
+	MediaPresentationDuration  string       `xml:"mediaPresentationDuration,attr,omitempty"`
+	MinimumUpdatePeriod        string       `xml:"minimumUpdatePeriod,attr,omitempty"`
+	MinBufferTime              string       `xml:"minBufferTime,attr,omitempty"`
+	TimeShiftBufferDepth       string       `xml:"timeShiftBufferDepth,attr,omitempty"`
+	SuggestedPresentationDelay string       `xml:"suggestedPresentationDelay,attr,omitempty"`
+	MaxSegmentDuration         string       `xml:"maxSegmentDuration,attr,omitempty"`
+	Attrs                      []xml.Attr   `xml:",any,attr"`
+	BaseURLs                   []string     `xml:"BaseURL,omitempty"`
+	Elements                   []Element    `xml:",any"` // Unknown children, written before the periods
+	Periods                    []*Period    `xml:"Period"`
+	UTCTimings                 []Descriptor `xml:"UTCTiming,omitempty"`
+}
+
+// Period is a span of the presentation with a fixed set of adaptation sets.
+type Period struct {
+	ID             string           `xml:"id,attr,omitempty"`
+	Start          string           `xml:"start,attr,omitempty"`
+	Duration       string           `xml:"duration,attr,omitempty"`
+	Attrs          []xml.Attr       `xml:",any,attr"`
+	BaseURLs       []string         `xml:"BaseURL,omitempty"`
+	Elements       []Element        `xml:",any"`
+	AdaptationSets []*AdaptationSet `xml:"AdaptationSet"`
+}
+
+// AdaptationSet groups interchangeable representations of one content component.
+type AdaptationSet struct {
+	ID                 string            `xml:"id,attr,omitempty"`
+	ContentType        string            `xml:"contentType,attr,omitempty"`
+	MimeType           string            `xml:"mimeType,attr,omitempty"`
+	Codecs             string            `xml:"codecs,attr,omitempty"`
+	Lang               string            `xml:"lang,attr,omitempty"`
+	StartWithSAP       string            `xml:"startWithSAP,attr,omitempty"`
+	SegmentAlignment   string            `xml:"segmentAlignment,attr,omitempty"`
+	BitstreamSwitching string            `xml:"bitstreamSwitching,attr,omitempty"`
+	FrameRate          string            `xml:"frameRate,attr,omitempty"`
+	MaxWidth           int               `xml:"maxWidth,attr,omitempty"`
+	MaxHeight          int               `xml:"maxHeight,attr,omitempty"`
+	Par                string            `xml:"par,attr,omitempty"`
+	Attrs              []xml.Attr        `xml:",any,attr"`
+	Roles              []Descriptor      `xml:"Role,omitempty"`
+	Elements           []Element         `xml:",any"`
+	SegmentTemplate    *SegmentTemplate  `xml:"SegmentTemplate,omitempty"`
+	Representations    []*Representation `xml:"Representation"`
+}
+
+// Representation is a single encoded version of a content component.
+type Representation struct {
+	ID                string           `xml:"id,attr"`
+	MimeType          string           `xml:"mimeType,attr,omitempty"`
+	Codecs            string           `xml:"codecs,attr,omitempty"`
+	Bandwidth         int64            `xml:"bandwidth,attr"`
+	Width             int              `xml:"width,attr,omitempty"`
+	Height            int              `xml:"height,attr,omitempty"`
+	FrameRate         string           `xml:"frameRate,attr,omitempty"`
+	Sar               string           `xml:"sar,attr,omitempty"`
+	AudioSamplingRate string           `xml:"audioSamplingRate,attr,omitempty"`
+	Attrs             []xml.Attr       `xml:",any,attr"`
+	Elements          []Element        `xml:",any"`
+	BaseURLs          []string         `xml:"BaseURL,omitempty"`
+	SegmentTemplate   *SegmentTemplate `xml:"SegmentTemplate,omitempty"`
+}
+
+// Descriptor is a generic schemeIdUri/value element such as Role or UTCTiming.
+type Descriptor struct {
+	SchemeIDURI string     `xml:"schemeIdUri,attr"`
+	Value       string     `xml:"value,attr,omitempty"`
+	ID          string     `xml:"id,attr,omitempty"`
+	Attrs       []xml.Attr `xml:",any,attr"`
+}
+
+// Element holds an element the package does not model, including its content.
+type Element struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   string     `xml:",innerxml"`
+}
+
+// Decode parses an MPD.
+func Decode(data []byte) (*MPD, error) {
+	var mpd MPD
+	if err := xml.Unmarshal(data, &mpd); err != nil {
+		return nil, err
+	}
+	mpd.normalize()
+	return &mpd, nil
+}
+
+// Encode serializes the MPD with an XML declaration.
+func (mpd *MPD) Encode() ([]byte, error) {
+	out := *mpd
+	out.XMLName = xml.Name{Local: "MPD"}
+	// Make sure the default namespace is declared exactly once
+	hasNamespace := false
+	for _, attr := range out.Attrs {
+		if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			hasNamespace = true
+		}
+	}
+	if !hasNamespace {
+		out.Attrs = append([]xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: Namespace}}, out.Attrs...)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "\t")
+	if err := enc.Encode(&out); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// IsDynamic reports whether the MPD describes a live presentation.
+func (mpd *MPD) IsDynamic() bool {
+	return mpd.Type == TypeDynamic
+}
+
+// normalize rewrites namespaced names the decoder resolved to URLs back to
+// their prefixed form, so they are written the way they were read.
+func (mpd *MPD) normalize() {
+	mpd.Attrs = normalizeAttrs(mpd.Attrs)
+	normalizeElements(mpd.Elements)
+	for _, period := range mpd.Periods {
+		period.Attrs = normalizeAttrs(period.Attrs)
+		normalizeElements(period.Elements)
+		for _, as := range period.AdaptationSets {
+			as.Attrs = normalizeAttrs(as.Attrs)
+			normalizeElements(as.Elements)
+			for _, rep := range as.Representations {
+				rep.Attrs = normalizeAttrs(rep.Attrs)
+				normalizeElements(rep.Elements)
+			}
+		}
+	}
+}
+
+func normalizeAttrs(attrs []xml.Attr) []xml.Attr {
+	for i, attr := range attrs {
+		switch {
+		case attr.Name.Space == "":
+		case attr.Name.Space == "xmlns":
+			attrs[i].Name = xml.Name{Local: "xmlns:" + attr.Name.Local}
+		case prefixes[attr.Name.Space] != "":
+			attrs[i].Name = xml.Name{Local: prefixes[attr.Name.Space] + ":" + attr.Name.Local}
+		}
+	}
+	return attrs
+}
+
+func normalizeElements(elements []Element) {
+	for i := range elements {
+		if elements[i].XMLName.Space == Namespace {
+			elements[i].XMLName.Space = ""
+		} else if prefix := prefixes[elements[i].XMLName.Space]; prefix != "" {
+			elements[i].XMLName = xml.Name{Local: prefix + ":" + elements[i].XMLName.Local}
+		}
+		elements[i].Attrs = normalizeAttrs(elements[i].Attrs)
+		elements[i].Inner = strings.TrimSpace(elements[i].Inner)
+	}
+}
@@ -0,0 +1,207 @@
+package dash
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeFixture(t *testing.T, name string) (*MPD, []byte) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	mpd, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	return mpd, data
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want []string // Fragments that must survive the round trip
+	}{
+		{
+			name: "ffmpeg live MPD",
+			file: "ffmpeg_live.mpd",
+			want: []string{
+				`xmlns="urn:mpeg:dash:schema:mpd:2011"`,
+				`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"`,
+				`xsi:schemaLocation="urn:mpeg:DASH:schema:MPD:2011`,
+				`type="dynamic"`,
+				`timeShiftBufferDepth="PT36.0S"`,
+				`<ProducerReferenceTime id="0" inband="true"`,
+				`<AudioChannelConfiguration schemeIdUri="urn:mpeg:dash:23003:3:audio_channel_configuration:2011" value="2">`,
+				`media="chunk-stream$RepresentationID$-$Number%05d$.m4s" startNumber="3"`,
+				`<S t="184320" d="92160" r="1"></S>`,
+				`<UTCTiming schemeIdUri="urn:mpeg:dash:utc:http-xsdate:2014"`,
+			},
+		},
+		{
+			name: "Static multi-period MPD with unknown elements",
+			file: "static_multiperiod.mpd",
+			want: []string{
+				`<BaseURL>https://cdn.example.com/vod/</BaseURL>`,
+				`<EventStream schemeIdUri="urn:scte:scte35:2014:xml+bin" timescale="1">`,
+				`<Role schemeIdUri="urn:mpeg:dash:role:2011" value="main">`,
+				`cenc:default_KID="10000000-1000-1000-1000-100000000001"`,
+				`startNumber="0"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mpd, _ := decodeFixture(t, tt.file)
+			encoded, err := mpd.Encode()
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			for _, fragment := range tt.want {
+				if !strings.Contains(string(encoded), fragment) {
+					t.Errorf("Encode() is missing %s\n%s", fragment, encoded)
+				}
+			}
+			if strings.Count(string(encoded), "xmlns=") != 1 {
+				t.Errorf("Encode() should declare the default namespace once\n%s", encoded)
+			}
+			again, err := Decode(encoded)
+			if err != nil {
+				t.Fatalf("Decode() of encoded MPD error = %v", err)
+			}
+			if !reflect.DeepEqual(mpd, again) {
+				t.Errorf("Decode(Encode()) = %+v, want %+v", again, mpd)
+			}
+			reencoded, err := again.Encode()
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if string(reencoded) != string(encoded) {
+				t.Errorf("Encode() is not stable:\n%s\nwant\n%s", reencoded, encoded)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	mpd, _ := decodeFixture(t, "static_multiperiod.mpd")
+	if mpd.IsDynamic() || mpd.MediaPresentationDuration != "PT1M30.5S" {
+		t.Errorf("Decode() type = %s, duration = %s", mpd.Type, mpd.MediaPresentationDuration)
+	}
+	if len(mpd.Periods) != 2 {
+		t.Fatalf("Decode() periods = %d, want 2", len(mpd.Periods))
+	}
+	as := mpd.Periods[0].AdaptationSets[0]
+	if len(as.Representations) != 2 || as.Representations[0].Bandwidth != 7800000 {
+		t.Errorf("Decode() representations = %+v", as.Representations)
+	}
+	// The template is inherited from the adaptation set
+	tmpl := as.TemplateFor(as.Representations[1])
+	if tmpl == nil || tmpl.GetTimescale() != 90000 {
+		t.Fatalf("TemplateFor() = %+v, want the adaptation set template", tmpl)
+	}
+	segments := tmpl.Segments()
+	if len(segments) != 10 || segments[9].Time != 4860000 || segments[9].Number != 10 {
+		t.Errorf("Segments() = %+v", segments)
+	}
+	if got := ExpandTemplate(tmpl.Media, as.Representations[1], segments[9].Number, segments[9].Time); got != "720p/4860000.m4s" {
+		t.Errorf("ExpandTemplate() = %v, want 720p/4860000.m4s", got)
+	}
+	audio := mpd.Periods[1].AdaptationSets[0]
+	if got := audio.TemplateFor(audio.Representations[0]).FirstNumber(); got != 0 {
+		t.Errorf("FirstNumber() = %d, want 0", got)
+	}
+}
+
+func TestSegmentTemplate_SetSegments(t *testing.T) {
+	mpd, _ := decodeFixture(t, "ffmpeg_live.mpd")
+	tmpl := mpd.Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate
+	segments := tmpl.Segments()
+	if len(segments) != 3 || segments[0].Number != 3 || segments[2].Time != 368640 {
+		t.Fatalf("Segments() = %+v", segments)
+	}
+	// Drop the first segment and add one with a gap in front of it
+	segments = append(segments[1:], Segment{Number: 6, Time: 500000, Duration: 92160})
+	tmpl.SetSegments(segments)
+	if got := tmpl.FirstNumber(); got != 4 {
+		t.Errorf("FirstNumber() = %d, want 4", got)
+	}
+	if len(tmpl.SegmentTimeline.S) != 2 || tmpl.SegmentTimeline.S[0].R != 1 || *tmpl.SegmentTimeline.S[1].T != 500000 {
+		t.Errorf("SetSegments() timeline = %+v", tmpl.SegmentTimeline.S)
+	}
+	if !reflect.DeepEqual(tmpl.Segments(), segments) {
+		t.Errorf("Segments() = %+v, want %+v", tmpl.Segments(), segments)
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	rep := &Representation{ID: "0", Bandwidth: 1000000}
+	tests := []struct {
+		tmpl   string
+		number int64
+		time   uint64
+		want   string
+	}{
+		{"chunk-stream$RepresentationID$-$Number%05d$.m4s", 42, 0, "chunk-stream0-00042.m4s"},
+		{"init-stream$RepresentationID$.m4s", 0, 0, "init-stream0.m4s"},
+		{"seg-$Time$.m4s", 0, 92160, "seg-92160.m4s"},
+		{"$Bandwidth$/seg-$Number$$$.m4s", 7, 0, "1000000/seg-7$.m4s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			if got := ExpandTemplate(tt.tmpl, rep, tt.number, tt.time); got != tt.want {
+				t.Errorf("ExpandTemplate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "PT36.0S", want: 36 * time.Second},
+		{value: "PT1M30.5S", want: 90*time.Second + 500*time.Millisecond},
+		{value: "P1DT2H", want: 26 * time.Hour},
+		{value: "-PT5S", want: -5 * time.Second},
+		{value: "PT", wantErr: true},
+		{value: "36s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDuration(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{36 * time.Second, "PT36.0S"},
+		{time.Hour + 2*time.Minute + 3500*time.Millisecond, "PT1H2M3.5S"},
+		{0, "PT0.0S"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := FormatDuration(tt.d); got != tt.want {
+				t.Errorf("FormatDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
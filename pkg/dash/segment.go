@@ -0,0 +1,140 @@
+package dash
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SegmentTemplate describes segment URLs through $identifier$ substitution.
+type SegmentTemplate struct {
+	Timescale              uint64           `xml:"timescale,attr,omitempty"`
+	Duration               uint64           `xml:"duration,attr,omitempty"`
+	PresentationTimeOffset uint64           `xml:"presentationTimeOffset,attr,omitempty"`
+	Initialization         string           `xml:"initialization,attr,omitempty"`
+	Media                  string           `xml:"media,attr,omitempty"`
+	StartNumber            *int64           `xml:"startNumber,attr,omitempty"`
+	Attrs                  []xml.Attr       `xml:",any,attr"`
+	SegmentTimeline        *SegmentTimeline `xml:"SegmentTimeline,omitempty"`
+}
+
+// SegmentTimeline lists segment start times and durations explicitly.
+type SegmentTimeline struct {
+	S []S `xml:"S"`
+}
+
+// S is a run of r+1 consecutive segments of equal duration d starting at t.
+type S struct {
+	T *uint64 `xml:"t,attr,omitempty"`
+	D uint64  `xml:"d,attr"`
+	R int     `xml:"r,attr,omitempty"`
+}
+
+// Segment is a single media segment expanded from a SegmentTemplate.
+type Segment struct {
+	Number   int64
+	Time     uint64 // Start time in timescale units
+	Duration uint64 // Duration in timescale units
+}
+
+// TemplateFor returns the SegmentTemplate in effect for a representation,
+// which may be inherited from its adaptation set.
+func (as *AdaptationSet) TemplateFor(rep *Representation) *SegmentTemplate {
+	if rep.SegmentTemplate != nil {
+		return rep.SegmentTemplate
+	}
+	return as.SegmentTemplate
+}
+
+// FirstNumber returns startNumber, defaulting to 1 as the spec does.
+func (st *SegmentTemplate) FirstNumber() int64 {
+	if st.StartNumber == nil {
+		return 1
+	}
+	return *st.StartNumber
+}
+
+// GetTimescale returns timescale, defaulting to 1 as the spec does.
+func (st *SegmentTemplate) GetTimescale() uint64 {
+	if st.Timescale == 0 {
+		return 1
+	}
+	return st.Timescale
+}
+
+// Segments expands the SegmentTimeline into individual segments. Gaps in the
+// timeline are kept as found: a segment's Time is taken from @t when present.
+func (st *SegmentTemplate) Segments() []Segment {
+	if st.SegmentTimeline == nil {
+		return nil
+	}
+	var (
+		segments []Segment
+		next     uint64
+	)
+	number := st.FirstNumber()
+	for _, s := range st.SegmentTimeline.S {
+		if s.T != nil {
+			next = *s.T
+		}
+		for r := 0; r <= s.R; r++ {
+			segments = append(segments, Segment{Number: number, Time: next, Duration: s.D})
+			number++
+			next += s.D
+		}
+	}
+	return segments
+}
+
+// SetSegments rebuilds the SegmentTimeline from individual segments, merging
+// consecutive segments of equal duration into repeated S elements.
+func (st *SegmentTemplate) SetSegments(segments []Segment) {
+	timeline := &SegmentTimeline{}
+	var next uint64
+	for i, seg := range segments {
+		if i == 0 {
+			number := seg.Number
+			st.StartNumber = &number
+		}
+		last := len(timeline.S) - 1
+		if i > 0 && seg.Time == next && timeline.S[last].D == seg.Duration {
+			timeline.S[last].R++
+		} else {
+			s := S{D: seg.Duration}
+			if i == 0 || seg.Time != next {
+				t := seg.Time
+				s.T = &t
+			}
+			timeline.S = append(timeline.S, s)
+		}
+		next = seg.Time + seg.Duration
+	}
+	st.SegmentTimeline = timeline
+}
+
+var templateIdentifier = regexp.MustCompile(`\$(RepresentationID|Number|Time|Bandwidth)(%0(\d+)d)?\$`)
+
+// ExpandTemplate substitutes the identifiers of a SegmentTemplate URL.
+func ExpandTemplate(tmpl string, rep *Representation, number int64, t uint64) string {
+	out := templateIdentifier.ReplaceAllStringFunc(tmpl, func(match string) string {
+		parts := templateIdentifier.FindStringSubmatch(match)
+		width := 1
+		if parts[3] != "" {
+			width, _ = strconv.Atoi(parts[3])
+		}
+		switch parts[1] {
+		case "RepresentationID":
+			return rep.ID
+		case "Number":
+			return fmt.Sprintf("%0*d", width, number)
+		case "Time":
+			return fmt.Sprintf("%0*d", width, t)
+		case "Bandwidth":
+			return fmt.Sprintf("%0*d", width, rep.Bandwidth)
+		}
+		return match
+	})
+	return strings.ReplaceAll(out, "$$", "$")
+}
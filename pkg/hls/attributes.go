@@ -0,0 +1,106 @@
+package hls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attribute is a single NAME=VALUE pair of an m3u8 attribute list.
+type Attribute struct {
+	Key    string
+	Value  string
+	Quoted bool // The value is a quoted-string
+}
+
+// Attributes is an attribute list in the order it appears in the playlist.
+type Attributes []Attribute
+
+// Get returns the value of the attribute named key.
+func (a Attributes) Get(key string) (string, bool) {
+	for _, attr := range a {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set replaces the value of the attribute named key or appends it.
+func (a *Attributes) Set(key, value string, quoted bool) {
+	for i := range *a {
+		if (*a)[i].Key == key {
+			(*a)[i].Value = value
+			(*a)[i].Quoted = quoted
+			return
+		}
+	}
+	*a = append(*a, Attribute{Key: key, Value: value, Quoted: quoted})
+}
+
+// Delete removes the attribute named key.
+func (a *Attributes) Delete(key string) {
+	out := (*a)[:0]
+	for _, attr := range *a {
+		if attr.Key != key {
+			out = append(out, attr)
+		}
+	}
+	*a = out
+}
+
+func (a Attributes) String() string {
+	var sb strings.Builder
+	for i, attr := range a {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(attr.Key)
+		sb.WriteByte('=')
+		if attr.Quoted {
+			sb.WriteByte('"')
+			sb.WriteString(attr.Value)
+			sb.WriteByte('"')
+		} else {
+			sb.WriteString(attr.Value)
+		}
+	}
+	return sb.String()
+}
+
+// ParseAttributes parses an m3u8 attribute list such as
+// BANDWIDTH=1144000,CODECS="avc1.64001f,mp4a.40.2".
+func ParseAttributes(list string) (Attributes, error) {
+	var attrs Attributes
+	for len(list) > 0 {
+		eq := strings.IndexByte(list, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid attribute list near %q", list)
+		}
+		attr := Attribute{Key: strings.TrimSpace(list[:eq])}
+		list = list[eq+1:]
+		if strings.HasPrefix(list, `"`) {
+			end := strings.IndexByte(list[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted-string for attribute %s", attr.Key)
+			}
+			attr.Value = list[1 : end+1]
+			attr.Quoted = true
+			list = list[end+2:]
+		} else {
+			end := strings.IndexByte(list, ',')
+			if end < 0 {
+				end = len(list)
+			}
+			attr.Value = strings.TrimSpace(list[:end])
+			list = list[end:]
+		}
+		attrs = append(attrs, attr)
+		if len(list) > 0 {
+			if list[0] != ',' {
+				return nil, fmt.Errorf("expected ',' after attribute %s", attr.Key)
+			}
+			list = list[1:]
+		}
+	}
+	return attrs, nil
+}
@@ -0,0 +1,192 @@
+package hls
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MasterPlaylist lists the variant streams and renditions of a presentation.
+type MasterPlaylist struct {
+	Version             int
+	IndependentSegments bool
+	Start               *Start
+	Tags                []string // Unknown tags, written verbatim after the known header tags
+	Renditions          []*Rendition
+	Variants            []*Variant
+	IFrameVariants      []*Variant // EXT-X-I-FRAME-STREAM-INF, the URI is an attribute
+}
+
+// Rendition is an EXT-X-MEDIA tag.
+type Rendition struct {
+	Attributes Attributes
+}
+
+func (r *Rendition) Type() string {
+	v, _ := r.Attributes.Get("TYPE")
+	return v
+}
+
+func (r *Rendition) GroupID() string {
+	v, _ := r.Attributes.Get("GROUP-ID")
+	return v
+}
+
+func (r *Rendition) Name() string {
+	v, _ := r.Attributes.Get("NAME")
+	return v
+}
+
+func (r *Rendition) URI() string {
+	v, _ := r.Attributes.Get("URI")
+	return v
+}
+
+func (r *Rendition) SetURI(uri string) {
+	r.Attributes.Set("URI", uri, true)
+}
+
+// Variant is an EXT-X-STREAM-INF tag and the URI of its media playlist.
+type Variant struct {
+	Attributes Attributes
+	URI        string
+}
+
+// Bandwidth returns the BANDWIDTH attribute, 0 when absent or malformed.
+func (v *Variant) Bandwidth() int64 {
+	value, _ := v.Attributes.Get("BANDWIDTH")
+	n, _ := strconv.ParseInt(value, 10, 64)
+	return n
+}
+
+func (v *Variant) SetBandwidth(bandwidth int64) {
+	v.Attributes.Set("BANDWIDTH", strconv.FormatInt(bandwidth, 10), false)
+}
+
+// AverageBandwidth returns the AVERAGE-BANDWIDTH attribute, 0 when absent.
+func (v *Variant) AverageBandwidth() int64 {
+	value, _ := v.Attributes.Get("AVERAGE-BANDWIDTH")
+	n, _ := strconv.ParseInt(value, 10, 64)
+	return n
+}
+
+func (v *Variant) SetAverageBandwidth(bandwidth int64) {
+	v.Attributes.Set("AVERAGE-BANDWIDTH", strconv.FormatInt(bandwidth, 10), false)
+}
+
+func (v *Variant) Resolution() string {
+	value, _ := v.Attributes.Get("RESOLUTION")
+	return value
+}
+
+func (v *Variant) Codecs() string {
+	value, _ := v.Attributes.Get("CODECS")
+	return value
+}
+
+// DecodeMaster parses a master playlist.
+func DecodeMaster(data []byte) (*MasterPlaylist, error) {
+	lines, err := lines(data)
+	if err != nil {
+		return nil, err
+	}
+	if !isMaster(data) {
+		return nil, ErrNotMaster
+	}
+	pl := &MasterPlaylist{}
+	var pending *Variant
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "#") {
+			if pending == nil {
+				return nil, fmt.Errorf("hls: URI %q without EXT-X-STREAM-INF", line)
+			}
+			pending.URI = line
+			pl.Variants = append(pl.Variants, pending)
+			pending = nil
+			continue
+		}
+		name, value := tag(line)
+		switch name {
+		case "#EXT-X-VERSION":
+			n, err := parseInt(name, value)
+			if err != nil {
+				return nil, err
+			}
+			pl.Version = int(n)
+		case "#EXT-X-INDEPENDENT-SEGMENTS":
+			pl.IndependentSegments = true
+		case "#EXT-X-START":
+			if pl.Start, err = parseStart(value); err != nil {
+				return nil, err
+			}
+		case "#EXT-X-MEDIA":
+			attrs, err := ParseAttributes(value)
+			if err != nil {
+				return nil, err
+			}
+			pl.Renditions = append(pl.Renditions, &Rendition{Attributes: attrs})
+		case "#EXT-X-STREAM-INF":
+			attrs, err := ParseAttributes(value)
+			if err != nil {
+				return nil, err
+			}
+			pending = &Variant{Attributes: attrs}
+		case "#EXT-X-I-FRAME-STREAM-INF":
+			attrs, err := ParseAttributes(value)
+			if err != nil {
+				return nil, err
+			}
+			pl.IFrameVariants = append(pl.IFrameVariants, &Variant{Attributes: attrs})
+		default:
+			pl.Tags = append(pl.Tags, line)
+		}
+	}
+	if pending != nil {
+		return nil, fmt.Errorf("hls: EXT-X-STREAM-INF without URI")
+	}
+	return pl, nil
+}
+
+// Encode serializes the master playlist.
+func (pl *MasterPlaylist) Encode() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("#EXTM3U\n")
+	if pl.Version > 0 {
+		fmt.Fprintf(&buf, "#EXT-X-VERSION:%d\n", pl.Version)
+	}
+	if pl.IndependentSegments {
+		buf.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	}
+	if pl.Start != nil {
+		buf.WriteString(pl.Start.String() + "\n")
+	}
+	for _, t := range pl.Tags {
+		buf.WriteString(t + "\n")
+	}
+	for _, r := range pl.Renditions {
+		buf.WriteString("#EXT-X-MEDIA:" + r.Attributes.String() + "\n")
+	}
+	for _, v := range pl.Variants {
+		buf.WriteString("#EXT-X-STREAM-INF:" + v.Attributes.String() + "\n")
+		buf.WriteString(v.URI + "\n")
+	}
+	for _, v := range pl.IFrameVariants {
+		buf.WriteString("#EXT-X-I-FRAME-STREAM-INF:" + v.Attributes.String() + "\n")
+	}
+	return buf.Bytes()
+}
+
+// PlaylistURIs returns the URIs of every media playlist the master playlist references.
+func (pl *MasterPlaylist) PlaylistURIs() []string {
+	var uris []string
+	for _, r := range pl.Renditions {
+		if uri := r.URI(); uri != "" {
+			uris = append(uris, uri)
+		}
+	}
+	for _, v := range pl.Variants {
+		uris = append(uris, v.URI)
+	}
+	return uris
+}
@@ -0,0 +1,220 @@
+package hls
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type PlaylistType string
+
+const (
+	PlaylistTypeEvent PlaylistType = "EVENT"
+	PlaylistTypeVOD   PlaylistType = "VOD"
+)
+
+// MediaPlaylist lists the segments of a single rendition.
+type MediaPlaylist struct {
+	Version               int
+	TargetDuration        int // 0 when the playlist has no EXT-X-TARGETDURATION
+	MediaSequence         int64
+	DiscontinuitySequence int64
+	PlaylistType          PlaylistType
+	IndependentSegments   bool
+	Start                 *Start
+	Tags                  []string // Unknown header tags, written verbatim before the first segment
+	Map                   *Map     // EXT-X-MAP that applies from the first segment
+	Segments              []*Segment
+	TrailingTags          []string // Unknown tags after the last segment
+	EndList               bool
+}
+
+// Map is an EXT-X-MAP tag pointing at the initialization section.
+type Map struct {
+	URI       string
+	ByteRange string
+}
+
+func (m *Map) String() string {
+	attrs := Attributes{{Key: "URI", Value: m.URI, Quoted: true}}
+	if m.ByteRange != "" {
+		attrs = append(attrs, Attribute{Key: "BYTERANGE", Value: m.ByteRange, Quoted: true})
+	}
+	return "#EXT-X-MAP:" + attrs.String()
+}
+
+// Segment is a media segment and the tags that apply to it.
+type Segment struct {
+	URI             string
+	Duration        float64
+	Title           string
+	Discontinuity   bool
+	ProgramDateTime string // Kept as written, encoders disagree on the exact format
+	ByteRange       string
+	Map             *Map     // EXT-X-MAP that changes the initialization section from this segment on
+	Tags            []string // Unknown tags preceding the segment
+}
+
+// DecodeMedia parses a media playlist.
+func DecodeMedia(data []byte) (*MediaPlaylist, error) {
+	lines, err := lines(data)
+	if err != nil {
+		return nil, err
+	}
+	if isMaster(data) {
+		return nil, ErrNotMedia
+	}
+	pl := &MediaPlaylist{}
+	seg := &Segment{Duration: -1}
+	inSegments := false
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "#") {
+			if seg.Duration < 0 {
+				return nil, fmt.Errorf("hls: segment %q without EXTINF", line)
+			}
+			seg.URI = line
+			pl.Segments = append(pl.Segments, seg)
+			seg = &Segment{Duration: -1}
+			continue
+		}
+		name, value := tag(line)
+		switch name {
+		case "#EXT-X-VERSION":
+			n, err := parseInt(name, value)
+			if err != nil {
+				return nil, err
+			}
+			pl.Version = int(n)
+		case "#EXT-X-TARGETDURATION":
+			n, err := parseInt(name, value)
+			if err != nil {
+				return nil, err
+			}
+			pl.TargetDuration = int(n)
+		case "#EXT-X-MEDIA-SEQUENCE":
+			if pl.MediaSequence, err = parseInt(name, value); err != nil {
+				return nil, err
+			}
+		case "#EXT-X-DISCONTINUITY-SEQUENCE":
+			if pl.DiscontinuitySequence, err = parseInt(name, value); err != nil {
+				return nil, err
+			}
+		case "#EXT-X-PLAYLIST-TYPE":
+			pl.PlaylistType = PlaylistType(value)
+		case "#EXT-X-INDEPENDENT-SEGMENTS":
+			pl.IndependentSegments = true
+		case "#EXT-X-START":
+			if pl.Start, err = parseStart(value); err != nil {
+				return nil, err
+			}
+		case "#EXT-X-ENDLIST":
+			pl.EndList = true
+		case "#EXT-X-MAP":
+			attrs, err := ParseAttributes(value)
+			if err != nil {
+				return nil, err
+			}
+			m := &Map{}
+			m.URI, _ = attrs.Get("URI")
+			m.ByteRange, _ = attrs.Get("BYTERANGE")
+			if inSegments || len(pl.Segments) > 0 {
+				seg.Map = m
+			} else {
+				pl.Map = m
+			}
+		case "#EXTINF":
+			duration, title, _ := strings.Cut(value, ",")
+			if seg.Duration, err = strconv.ParseFloat(duration, 64); err != nil {
+				return nil, fmt.Errorf("hls: invalid EXTINF: %w", err)
+			}
+			seg.Title = title
+			inSegments = true
+		case "#EXT-X-DISCONTINUITY":
+			seg.Discontinuity = true
+			inSegments = true
+		case "#EXT-X-PROGRAM-DATE-TIME":
+			seg.ProgramDateTime = value
+			inSegments = true
+		case "#EXT-X-BYTERANGE":
+			seg.ByteRange = value
+			inSegments = true
+		default:
+			if inSegments || len(pl.Segments) > 0 {
+				seg.Tags = append(seg.Tags, line)
+			} else {
+				pl.Tags = append(pl.Tags, line)
+			}
+		}
+	}
+	if seg.Duration >= 0 {
+		return nil, fmt.Errorf("hls: EXTINF without segment URI")
+	}
+	// Whatever is left over after the last segment belongs to the playlist
+	pl.TrailingTags = seg.Tags
+	return pl, nil
+}
+
+// Encode serializes the media playlist.
+func (pl *MediaPlaylist) Encode() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("#EXTM3U\n")
+	if pl.Version > 0 {
+		fmt.Fprintf(&buf, "#EXT-X-VERSION:%d\n", pl.Version)
+	}
+	fmt.Fprintf(&buf, "#EXT-X-TARGETDURATION:%d\n", pl.TargetDuration)
+	fmt.Fprintf(&buf, "#EXT-X-MEDIA-SEQUENCE:%d\n", pl.MediaSequence)
+	if pl.DiscontinuitySequence > 0 {
+		fmt.Fprintf(&buf, "#EXT-X-DISCONTINUITY-SEQUENCE:%d\n", pl.DiscontinuitySequence)
+	}
+	if pl.PlaylistType != "" {
+		fmt.Fprintf(&buf, "#EXT-X-PLAYLIST-TYPE:%s\n", pl.PlaylistType)
+	}
+	if pl.IndependentSegments {
+		buf.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	}
+	if pl.Start != nil {
+		buf.WriteString(pl.Start.String() + "\n")
+	}
+	for _, t := range pl.Tags {
+		buf.WriteString(t + "\n")
+	}
+	if pl.Map != nil {
+		buf.WriteString(pl.Map.String() + "\n")
+	}
+	for _, seg := range pl.Segments {
+		for _, t := range seg.Tags {
+			buf.WriteString(t + "\n")
+		}
+		if seg.Discontinuity {
+			buf.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		if seg.Map != nil {
+			buf.WriteString(seg.Map.String() + "\n")
+		}
+		fmt.Fprintf(&buf, "#EXTINF:%s,%s\n", strconv.FormatFloat(seg.Duration, 'f', 6, 64), seg.Title)
+		if seg.ByteRange != "" {
+			buf.WriteString("#EXT-X-BYTERANGE:" + seg.ByteRange + "\n")
+		}
+		if seg.ProgramDateTime != "" {
+			buf.WriteString("#EXT-X-PROGRAM-DATE-TIME:" + seg.ProgramDateTime + "\n")
+		}
+		buf.WriteString(seg.URI + "\n")
+	}
+	for _, t := range pl.TrailingTags {
+		buf.WriteString(t + "\n")
+	}
+	if pl.EndList {
+		buf.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return buf.Bytes()
+}
+
+// Duration returns the sum of all segment durations in seconds.
+func (pl *MediaPlaylist) Duration() float64 {
+	var total float64
+	for _, seg := range pl.Segments {
+		total += seg.Duration
+	}
+	return total
+}
@@ -0,0 +1,108 @@
+// Package hls parses and serializes HLS master and media playlists (RFC 8216).
+//
+// Tags the package does not model are preserved verbatim so that a playlist
+// survives a Decode/Encode round trip without losing information.
+package hls
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotPlaylist = errors.New("hls: playlist does not start with #EXTM3U")
+	ErrNotMaster   = errors.New("hls: not a master playlist")
+	ErrNotMedia    = errors.New("hls: not a media playlist")
+)
+
+// Playlist is implemented by *MasterPlaylist and *MediaPlaylist.
+type Playlist interface {
+	Encode() []byte
+}
+
+// Start is the EXT-X-START tag.
+type Start struct {
+	TimeOffset float64
+	Precise    bool
+}
+
+func (s *Start) String() string {
+	out := "#EXT-X-START:TIME-OFFSET=" + strconv.FormatFloat(s.TimeOffset, 'f', -1, 64)
+	if s.Precise {
+		out += ",PRECISE=YES"
+	}
+	return out
+}
+
+func parseStart(value string) (*Start, error) {
+	attrs, err := ParseAttributes(value)
+	if err != nil {
+		return nil, err
+	}
+	start := &Start{}
+	offset, ok := attrs.Get("TIME-OFFSET")
+	if !ok {
+		return nil, fmt.Errorf("hls: EXT-X-START without TIME-OFFSET")
+	}
+	if start.TimeOffset, err = strconv.ParseFloat(offset, 64); err != nil {
+		return nil, fmt.Errorf("hls: invalid TIME-OFFSET: %w", err)
+	}
+	precise, _ := attrs.Get("PRECISE")
+	start.Precise = precise == "YES"
+	return start, nil
+}
+
+// Decode parses either a master or a media playlist.
+func Decode(data []byte) (Playlist, error) {
+	if isMaster(data) {
+		return DecodeMaster(data)
+	}
+	return DecodeMedia(data)
+}
+
+func isMaster(data []byte) bool {
+	for _, tag := range []string{"#EXT-X-STREAM-INF:", "#EXT-X-MEDIA:", "#EXT-X-I-FRAME-STREAM-INF:"} {
+		if bytes.Contains(data, []byte(tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// lines returns the non-empty lines of a playlist after checking the #EXTM3U header.
+func lines(data []byte) ([]string, error) {
+	var out []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		out = append(out, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 || out[0] != "#EXTM3U" {
+		return nil, ErrNotPlaylist
+	}
+	return out[1:], nil
+}
+
+// tag splits a tag line into its name and value.
+func tag(line string) (string, string) {
+	name, value, _ := strings.Cut(line, ":")
+	return name, value
+}
+
+func parseInt(name, value string) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("hls: invalid %s: %w", name, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,140 @@
+package hls
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		master bool
+	}{
+		{name: "ffmpeg master playlist", file: "ffmpeg_master.m3u8", master: true},
+		{name: "ffmpeg media playlist", file: "ffmpeg_media.m3u8"},
+		{name: "Multivariant playlist with unknown tags", file: "multivariant.m3u8", master: true},
+		{name: "VOD playlist with discontinuities", file: "vod_media.m3u8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+			pl, err := Decode(data)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if _, ok := pl.(*MasterPlaylist); ok != tt.master {
+				t.Fatalf("Decode() returned %T, want master = %v", pl, tt.master)
+			}
+			got := pl.Encode()
+			if string(got) != string(data) {
+				t.Errorf("Encode() =\n%s\nwant\n%s", got, data)
+			}
+			again, err := Decode(got)
+			if err != nil {
+				t.Fatalf("Decode() of encoded playlist error = %v", err)
+			}
+			if !reflect.DeepEqual(pl, again) {
+				t.Errorf("Decode(Encode()) = %+v, want %+v", again, pl)
+			}
+		})
+	}
+}
+
+func TestDecodeMaster(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "multivariant.m3u8"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	pl, err := DecodeMaster(data)
+	if err != nil {
+		t.Fatalf("DecodeMaster() error = %v", err)
+	}
+	if len(pl.Variants) != 2 || len(pl.Renditions) != 3 || len(pl.IFrameVariants) != 1 {
+		t.Fatalf("DecodeMaster() variants = %d, renditions = %d, iframes = %d, want 2, 3, 1",
+			len(pl.Variants), len(pl.Renditions), len(pl.IFrameVariants))
+	}
+	if got := pl.Variants[0].Bandwidth(); got != 7800000 {
+		t.Errorf("Bandwidth() = %d, want 7800000", got)
+	}
+	if got := pl.Variants[1].Codecs(); got != "avc1.64001f,mp4a.40.2" {
+		t.Errorf("Codecs() = %q, want %q", got, "avc1.64001f,mp4a.40.2")
+	}
+	if pl.Start == nil || pl.Start.TimeOffset != -12.5 || !pl.Start.Precise {
+		t.Errorf("Start = %+v, want TIME-OFFSET=-12.5,PRECISE=YES", pl.Start)
+	}
+	want := []string{"audio/en.m3u8", "audio/de.m3u8", "video/1080p.m3u8", "video/720p.m3u8"}
+	if got := pl.PlaylistURIs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PlaylistURIs() = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeMedia(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "vod_media.m3u8"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	pl, err := DecodeMedia(data)
+	if err != nil {
+		t.Fatalf("DecodeMedia() error = %v", err)
+	}
+	if pl.PlaylistType != PlaylistTypeVOD || !pl.EndList {
+		t.Errorf("PlaylistType = %q, EndList = %v, want VOD and true", pl.PlaylistType, pl.EndList)
+	}
+	if len(pl.Segments) != 3 {
+		t.Fatalf("DecodeMedia() segments = %d, want 3", len(pl.Segments))
+	}
+	ad := pl.Segments[2]
+	if !ad.Discontinuity || ad.Map == nil || ad.Map.URI != "ad_init.mp4" || len(ad.Tags) != 1 {
+		t.Errorf("segment 2 = %+v, want a discontinuity with its own map and a DATERANGE tag", ad)
+	}
+	if got := pl.Duration(); got != 28.018 {
+		t.Errorf("Duration() = %v, want 28.018", got)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "Missing header", data: "#EXT-X-VERSION:3\n"},
+		{name: "Empty", data: ""},
+		{name: "Segment without EXTINF", data: "#EXTM3U\n#EXT-X-TARGETDURATION:6\nsegment.ts\n"},
+		{name: "EXTINF without URI", data: "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\n"},
+		{name: "Variant without URI", data: "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000\n"},
+		{name: "Unterminated quoted-string", data: "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000,CODECS=\"avc1\nvideo.m3u8\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode([]byte(tt.data)); err == nil {
+				t.Errorf("Decode() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	attrs, err := ParseAttributes(`BANDWIDTH=1144000,CODECS="avc1.64001f,mp4a.40.2",AUDIO="group_A1"`)
+	if err != nil {
+		t.Fatalf("ParseAttributes() error = %v", err)
+	}
+	want := Attributes{
+		{Key: "BANDWIDTH", Value: "1144000"},
+		{Key: "CODECS", Value: "avc1.64001f,mp4a.40.2", Quoted: true},
+		{Key: "AUDIO", Value: "group_A1", Quoted: true},
+	}
+	if !reflect.DeepEqual(attrs, want) {
+		t.Errorf("ParseAttributes() = %v, want %v", attrs, want)
+	}
+	attrs.Set("BANDWIDTH", "2000000", false)
+	attrs.Delete("AUDIO")
+	if got := attrs.String(); got != `BANDWIDTH=2000000,CODECS="avc1.64001f,mp4a.40.2"` {
+		t.Errorf("String() = %v", got)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd)
+
+package resources
+
+// DiskFree returns -1, the free space is not known on this platform.
+func DiskFree(dir string) int64 {
+	return -1
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package resources
+
+import "syscall"
+
+// DiskFree returns the bytes available to unprivileged users on the file
+// system of dir, or -1 if it cannot be read.
+func DiskFree(dir string) int64 {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return -1
+	}
+	return int64(uint64(st.Bavail) * uint64(st.Bsize))
+}
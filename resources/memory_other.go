@@ -0,0 +1,8 @@
+//go:build !linux
+
+package resources
+
+// availableMemory returns -1, the available memory is not known on this platform.
+func availableMemory() int64 {
+	return -1
+}
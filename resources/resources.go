@@ -0,0 +1,183 @@
+// Package resources estimates what a job costs the host and checks that the
+// host can sustain it in real time.
+package resources
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+const (
+	// pixelsPerCore is the pixel rate one core encodes to H.264 in real time
+	// at the preset of the streamer, 720p30
+	pixelsPerCore = 1280 * 720 * 30
+	// audioCores is the cost of encoding an audio track
+	audioCores = 0.05
+	// baseMemory is the footprint of an ffmpeg process before frame buffers
+	baseMemory = 64 << 20
+	// framesInFlight are the frames an encoder buffers for lookahead and references
+	framesInFlight = 40
+	// extraSegments are the segments the muxer keeps past the playlist window
+	extraSegments = 5
+	// unboundedRecording is the recording length assumed for jobs kept as
+	// VOD without a planned end
+	unboundedRecording = time.Hour
+)
+
+// codecComplexity is the cost of encoding with a codec relative to H.264
+var codecComplexity = map[string]float64{
+	"h264": 1,
+	"hevc": 3,
+	"vp9":  3,
+	"av1":  5,
+}
+
+// Cost is what a job takes from the host while it runs.
+type Cost struct {
+	CPU    float64 // Cores
+	Memory int64   // Bytes
+	Disk   int64   // Bytes the output takes at most
+}
+
+// Add returns the sum of two costs.
+func (c Cost) Add(o Cost) Cost {
+	return Cost{CPU: c.CPU + o.CPU, Memory: c.Memory + o.Memory, Disk: c.Disk + o.Disk}
+}
+
+// Estimate returns the cost of a validated job configuration. The encoder
+// writes a single video rendition for all output formats, so the cost grows
+// with the resolution, frame rate and codec of that rendition and the number
+// of audio tracks.
+func Estimate(request models.JobCreateRequest) Cost {
+	var cost Cost
+	if v := request.VideoTrack; v != nil {
+		var width, height int
+		fmt.Sscanf(v.Resolution, "%dx%d", &width, &height)
+		fps, _ := strconv.Atoi(v.Framerate)
+		complexity := codecComplexity[strings.ToLower(v.Codec)]
+		if complexity == 0 {
+			complexity = 1
+		}
+		cost.CPU = float64(width*height*fps) / pixelsPerCore * complexity
+		cost.Memory = baseMemory + int64(float64(width*height*3/2*framesInFlight)*complexity)
+	}
+	audioTracks := 0
+	if request.AudioTrack != nil {
+		audioTracks = 1
+		if c := request.AudioConfig; c != nil && c.AudioTracks > 1 {
+			audioTracks = c.AudioTracks
+		}
+	}
+	cost.CPU += float64(audioTracks) * audioCores
+	if l := request.Limits; l != nil {
+		// A confined job takes no more than its limits, it may fall behind real time instead
+		if l.CPUs > 0 {
+			cost.CPU = min(cost.CPU, l.CPUs)
+		}
+		if memory := l.MemoryBytes(); memory > 0 {
+			cost.Memory = min(cost.Memory, memory)
+		}
+	}
+
+	var bitrate float64 // Bits per second
+	if v := request.VideoTrack; v != nil {
+		bitrate += parseBitrate(v.BitRate)
+	}
+	if a := request.AudioTrack; a != nil {
+		bitrate += parseBitrate(a.AudioBitrate) * float64(audioTracks)
+	}
+	cost.Disk = int64(bitrate / 8 * outputLength(request).Seconds())
+	return cost
+}
+
+// outputLength returns the media time the output directory holds at most.
+func outputLength(request models.JobCreateRequest) time.Duration {
+	segment := time.Duration(request.SegmentLength) * time.Second
+	if request.VODOnStop {
+		switch {
+		case request.Event != nil:
+			return request.Event.Length()
+		case request.Duration > 0:
+			return time.Duration(request.Duration) * time.Second
+		default:
+			return unboundedRecording
+		}
+	}
+	return time.Duration(request.PlaylistWindow()+extraSegments) * segment
+}
+
+// parseBitrate parses a bitrate like 1.2M or 128k into bits per second.
+func parseBitrate(s string) float64 {
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1e6
+	case strings.HasSuffix(s, "k"):
+		multiplier = 1e3
+	}
+	n, _ := strconv.ParseFloat(strings.TrimRight(s, "Mk"), 64)
+	return n * multiplier
+}
+
+// Host is what the host has available, -1 where it is unknown.
+type Host struct {
+	Cores  int
+	Memory int64 // Bytes available to new processes
+	Disk   int64 // Free bytes of the media directory
+}
+
+// Probe returns the resources of the host, with the free space of dir.
+func Probe(dir string) Host {
+	return Host{Cores: runtime.NumCPU(), Memory: availableMemory(), Disk: DiskFree(dir)}
+}
+
+// InsufficientError is returned for a job the host cannot sustain.
+type InsufficientError struct {
+	Shortfalls []string
+}
+
+func (e *InsufficientError) Error() string {
+	return "insufficient resources: " + strings.Join(e.Shortfalls, "; ")
+}
+
+// Check returns an *InsufficientError if the host cannot run a job of cost
+// next to jobs of the committed cost and keep reserve bytes of disk free.
+func Check(cost, committed Cost, host Host, reserve int64) error {
+	var shortfalls []string
+	if host.Cores > 0 && committed.CPU+cost.CPU > float64(host.Cores) {
+		shortfalls = append(shortfalls, fmt.Sprintf("the job needs %.1f CPU cores, %.1f of %d are free",
+			cost.CPU, max(float64(host.Cores)-committed.CPU, 0), host.Cores))
+	}
+	if host.Memory >= 0 && cost.Memory > host.Memory {
+		shortfalls = append(shortfalls, fmt.Sprintf("the job needs %s of memory, %s is available",
+			FormatBytes(cost.Memory), FormatBytes(host.Memory)))
+	}
+	// The running jobs keep writing until their output reaches its cost
+	if host.Disk >= 0 && committed.Disk+cost.Disk+reserve > host.Disk {
+		shortfalls = append(shortfalls, fmt.Sprintf("the job needs %s of disk on top of the %s kept free, %s is free after the output of the running jobs",
+			FormatBytes(cost.Disk), FormatBytes(reserve), FormatBytes(max(host.Disk-committed.Disk, 0))))
+	}
+	if len(shortfalls) > 0 {
+		return &InsufficientError{Shortfalls: shortfalls}
+	}
+	return nil
+}
+
+// FormatBytes formats a size with a binary unit, e.g. 1.5 GiB.
+func FormatBytes(n int64) string {
+	const unit = 1 << 10
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,151 @@
+package resources
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+func TestEstimate(t *testing.T) {
+	video := &models.VideoTrack{BitRate: "2M", Resolution: "1280x720", Framerate: "30", Codec: "h264"}
+	audio := &models.AudioTrack{AudioCodec: "aac", AudioBitrate: "128k"}
+	tests := []struct {
+		name    string
+		request models.JobCreateRequest
+		want    Cost
+	}{
+		{
+			name: "Live 720p30",
+			request: models.JobCreateRequest{
+				VideoTrack: video,
+				AudioTrack: audio,
+				JobFormat:  models.JobFormat{SegmentLength: 6, WindowSize: 6},
+			},
+			// 720p30 takes a core, the output holds the window and 5 more segments
+			want: Cost{CPU: 1.05, Memory: 64<<20 + 1280*720*3/2*40, Disk: 266000 * 66},
+		},
+		{
+			name: "Recording",
+			request: models.JobCreateRequest{
+				VideoTrack:  video,
+				AudioTrack:  audio,
+				JobSchedule: models.JobSchedule{Duration: 600},
+				JobFormat:   models.JobFormat{SegmentLength: 6, WindowSize: 6, VODOnStop: true},
+			},
+			want: Cost{CPU: 1.05, Memory: 64<<20 + 1280*720*3/2*40, Disk: 266000 * 600},
+		},
+		{
+			name: "HEVC 1080p60",
+			request: models.JobCreateRequest{
+				VideoTrack: &models.VideoTrack{BitRate: "4M", Resolution: "1920x1080", Framerate: "60", Codec: "hevc"},
+				JobFormat:  models.JobFormat{SegmentLength: 2, WindowSize: 5},
+			},
+			want: Cost{CPU: 13.5, Memory: 64<<20 + 1920*1080*3/2*40*3, Disk: 500000 * 20},
+		},
+		{
+			name: "Limits",
+			request: models.JobCreateRequest{
+				VideoTrack: &models.VideoTrack{BitRate: "4M", Resolution: "1920x1080", Framerate: "60", Codec: "hevc"},
+				Limits:     &models.JobLimits{CPUs: 2, Memory: "256M"},
+				JobFormat:  models.JobFormat{SegmentLength: 2, WindowSize: 5},
+			},
+			want: Cost{CPU: 2, Memory: 256 << 20, Disk: 500000 * 20},
+		},
+		{
+			name: "Multiple audio tracks",
+			request: models.JobCreateRequest{
+				AudioTrack:  audio,
+				AudioConfig: &models.AudioConfig{AudioTracks: 3},
+				JobFormat:   models.JobFormat{SegmentLength: 6, WindowSize: 5},
+			},
+			want: Cost{CPU: 0.15000000000000002, Disk: 48000 * 60},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Estimate(tt.request); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Estimate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cost := Cost{CPU: 2, Memory: 100, Disk: 1000}
+	tests := []struct {
+		name           string
+		committed      Cost
+		host           Host
+		reserve        int64
+		wantShortfalls int
+	}{
+		{
+			name: "Fits",
+			host: Host{Cores: 4, Memory: 200, Disk: 2000},
+		},
+		{
+			name: "Unknown host",
+			host: Host{Cores: -1, Memory: -1, Disk: -1},
+		},
+		{
+			name:           "Cores taken by running jobs",
+			committed:      Cost{CPU: 3},
+			host:           Host{Cores: 4, Memory: 200, Disk: 2000},
+			wantShortfalls: 1,
+		},
+		{
+			name:           "Disk taken by running jobs",
+			committed:      Cost{Disk: 1500},
+			host:           Host{Cores: 4, Memory: 200, Disk: 2000},
+			wantShortfalls: 1,
+		},
+		{
+			name:           "Disk reserve",
+			host:           Host{Cores: 4, Memory: 200, Disk: 2000},
+			reserve:        1500,
+			wantShortfalls: 1,
+		},
+		{
+			name:           "Nothing fits",
+			host:           Host{Cores: 1, Memory: 50, Disk: 500},
+			wantShortfalls: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(cost, tt.committed, tt.host, tt.reserve)
+			var insufficient *InsufficientError
+			if tt.wantShortfalls == 0 {
+				if err != nil {
+					t.Errorf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.As(err, &insufficient) {
+				t.Fatalf("Check() error = %v, want *InsufficientError", err)
+			}
+			if len(insufficient.Shortfalls) != tt.wantShortfalls {
+				t.Errorf("Check() shortfalls = %q, want %d", insufficient.Shortfalls, tt.wantShortfalls)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{n: 512, want: "512 B"},
+		{n: 1536, want: "1.5 KiB"},
+		{n: 1 << 30, want: "1.0 GiB"},
+		{n: 5 << 40, want: "5.0 TiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
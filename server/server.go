@@ -7,17 +7,24 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/events"
 	"github.com/arunjeyaprasad/golive/internal/api/handlers"
 	"github.com/arunjeyaprasad/golive/internal/api/middleware"
+	"github.com/arunjeyaprasad/golive/internal/api/postprocessor"
+	"github.com/arunjeyaprasad/golive/internal/web"
+	"github.com/arunjeyaprasad/golive/jobs"
+	"github.com/arunjeyaprasad/golive/models"
 
 	"github.com/gorilla/mux"
 )
 
-func StartServer() error {
+// newRouter builds the router of the dashboard and the API.
+func newRouter() *mux.Router {
 	r := mux.NewRouter()
 
 	// Add middleware
@@ -26,23 +33,49 @@ func StartServer() error {
 	r.Use(middleware.Logging)
 	r.Use(middleware.Recovery)
 
-	// API routes
-	api := r.PathPrefix("/").Subrouter()
-	api.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
-	})
+	// Dashboard, registered first as the API routes match every path
+	r.PathPrefix(web.Prefix).Handler(web.Handler()).Methods(http.MethodGet, http.MethodHead)
+	r.Handle("/", http.RedirectHandler(web.Prefix, http.StatusFound)).Methods(http.MethodGet)
 
+	// API routes, the unversioned ones are kept for clients predating /v1
+	prefix := "/" + config.API_VERSION
+	api := r.PathPrefix(prefix).Subrouter()
+	legacy := r.MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		return !strings.HasPrefix(r.URL.Path, prefix+"/")
+	}).Subrouter()
+	legacy.Use(middleware.Deprecated(prefix))
 	handlers.RegisterRoutes(api)
+	handlers.RegisterLegacyRoutes(legacy)
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postprocessor.FormatError(w, http.StatusMethodNotAllowed, models.ErrorCodeMethodNotAllowed, "Method Not Allowed")
+	})
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postprocessor.FormatError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Not Found")
+	})
 
+	return r
+}
+
+func StartServer() error {
 	// Create server with timeouts
 	srv := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", "0.0.0.0", config.DEFAULT_SERVER_PORT),
-		Handler:      r,
+		Handler:      newRouter(),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Shutdown waits for active connections, end the event streams so it does not hang
+	srv.RegisterOnShutdown(events.CloseAll)
+
+	// Jobs do not outlive the process, their output is left behind otherwise
+	jobs.SweepOrphans()
+	stopRetention := jobs.StartRetention(config.RETENTION_INTERVAL)
+	defer stopRetention()
+	stopGuard := jobs.StartGuard(config.RESOURCE_CHECK_INTERVAL)
+	defer stopGuard()
+
 	// Start server in a goroutine
 	go func() {
 		slog.Info("Starting server", "addr", srv.Addr)
@@ -66,6 +99,8 @@ func StartServer() error {
 		slog.Error("Server shutdown failed", "error", err)
 		return err
 	}
+	// ffmpeg runs in a process group of its own, the signal did not reach it
+	jobs.StopAll()
 
 	slog.Info("Server shutdown completed")
 	return nil
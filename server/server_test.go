@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+func TestNewRouter(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		wantStatus     int
+		wantDeprecated bool
+		wantErrorCode  models.ErrorCode
+		wantBody       string // Prefix of the body
+	}{
+		{name: "Versioned API", path: "/v1/jobs", wantStatus: http.StatusOK, wantBody: `{"jobs":[]`},
+		{name: "Unversioned API", path: "/jobs", wantStatus: http.StatusOK, wantDeprecated: true, wantBody: `[]`},
+		{name: "Invalid job list query", path: "/v1/jobs?limit=0", wantStatus: http.StatusBadRequest, wantErrorCode: models.ErrorCodeValidation},
+		{name: "Versioned missing job", path: "/v1/jobs/missing", wantStatus: http.StatusNotFound, wantErrorCode: models.ErrorCodeNotFound},
+		{name: "Unknown route", path: "/v2/jobs", wantStatus: http.StatusNotFound, wantErrorCode: models.ErrorCodeNotFound},
+		{name: "Dashboard", path: "/ui/", wantStatus: http.StatusOK},
+		{name: "Root redirects to the dashboard", path: "/", wantStatus: http.StatusFound},
+	}
+	r := newRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Deprecation") == "true"; got != tt.wantDeprecated {
+				t.Errorf("deprecated = %v, want %v", got, tt.wantDeprecated)
+			}
+			if !strings.HasPrefix(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to start with %s", rec.Body.String(), tt.wantBody)
+			}
+			if tt.wantErrorCode != "" {
+				var resp models.ErrorResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Code != tt.wantErrorCode {
+					t.Errorf("body = %s, want error code %s", rec.Body.String(), tt.wantErrorCode)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package streamer
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// cpuPeriod is the cgroup v2 CPU period in microseconds the quota is a share of
+const cpuPeriod = 100000
+
+// cgroup is the cgroup v2 enforcing the CPU and memory limits of a job.
+type cgroup struct {
+	path string
+	dir  *os.File // Open for starting processes in the cgroup
+}
+
+// newCgroup creates a cgroup with the limits under config.CGROUP_ROOT.
+func newCgroup(name string, limits *models.JobLimits) (*cgroup, error) {
+	if config.CGROUP_ROOT == "" {
+		return nil, errors.New("no cgroup root is configured, set GOLIVE_CGROUP_ROOT")
+	}
+	if err := enableControllers(config.CGROUP_ROOT, "cpu", "memory"); err != nil {
+		return nil, err
+	}
+	cg := &cgroup{path: filepath.Join(config.CGROUP_ROOT, name)}
+	if err := os.Mkdir(cg.path, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, err
+	}
+	dir, err := os.Open(cg.path)
+	if err != nil {
+		cg.remove()
+		return nil, err
+	}
+	cg.dir = dir
+	if limits.CPUs > 0 {
+		quota := fmt.Sprintf("%d %d", int(limits.CPUs*cpuPeriod), cpuPeriod)
+		if err := cg.write("cpu.max", quota); err != nil {
+			cg.remove()
+			return nil, err
+		}
+	}
+	if memory := limits.MemoryBytes(); memory > 0 {
+		if err := cg.write("memory.max", strconv.FormatInt(memory, 10)); err != nil {
+			cg.remove()
+			return nil, err
+		}
+	}
+	return cg, nil
+}
+
+// enableControllers enables controllers for the children of a cgroup, they
+// only get the controllers their parent enables. Those already enabled are
+// left alone, the parent may not be writable.
+func enableControllers(root string, controllers ...string) error {
+	file := filepath.Join(root, "cgroup.subtree_control")
+	enabled, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	for _, controller := range controllers {
+		if slices.Contains(strings.Fields(string(enabled)), controller) {
+			continue
+		}
+		if err := os.WriteFile(file, []byte("+"+controller), 0); err != nil {
+			return fmt.Errorf("failed to enable the %s controller: %w", controller, err)
+		}
+	}
+	return nil
+}
+
+// enter makes a command start in the cgroup, the processes it spawns follow
+// it.
+func (cg *cgroup) enter(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(cg.dir.Fd())
+}
+
+// remove kills what is left in the cgroup and deletes it.
+func (cg *cgroup) remove() {
+	if cg.dir != nil {
+		cg.dir.Close()
+	}
+	cg.write("cgroup.kill", "1")
+	// The cgroup is busy until the killed processes are gone
+	for i := 0; i < 20; i++ {
+		err := os.Remove(cg.path)
+		if err == nil || errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	slog.Error("Failed to remove cgroup", "path", cg.path)
+}
+
+func (cg *cgroup) write(file, value string) error {
+	return os.WriteFile(filepath.Join(cg.path, file), []byte(value), 0)
+}
@@ -0,0 +1,81 @@
+package streamer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+func TestNewCgroup(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled string // Content of cgroup.subtree_control of the root, none if empty
+		want    string // cgroup.subtree_control of the root afterwards
+		wantErr bool
+	}{
+		{name: "Controllers enabled", enabled: "cpu io memory\n", want: "cpu io memory\n"},
+		{name: "Controller to enable", enabled: "cpu\n", want: "+memory"},
+		{name: "Not a cgroup", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := config.CGROUP_ROOT
+			config.CGROUP_ROOT = t.TempDir()
+			t.Cleanup(func() { config.CGROUP_ROOT = root })
+			control := filepath.Join(config.CGROUP_ROOT, "cgroup.subtree_control")
+			if tt.enabled != "" {
+				if err := os.WriteFile(control, []byte(tt.enabled), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cg, err := newCgroup("job", &models.JobLimits{CPUs: 1.5})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newCgroup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer cg.dir.Close()
+			if got, _ := os.ReadFile(control); string(got) != tt.want {
+				t.Errorf("cgroup.subtree_control = %q, want %q", got, tt.want)
+			}
+			if got, _ := os.ReadFile(filepath.Join(cg.path, "cpu.max")); string(got) != "150000 100000" {
+				t.Errorf("cpu.max = %q, want %q", got, "150000 100000")
+			}
+		})
+	}
+}
+
+func TestStartJob_CgroupFallback(t *testing.T) {
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte("#!/bin/sh\ntrap 'exit 0' INT TERM\nwhile :; do sleep 0.1; done\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	mediaDir, root := config.DEFAULT_MEDIA_DIR, config.CGROUP_ROOT
+	config.DEFAULT_MEDIA_DIR, config.CGROUP_ROOT = t.TempDir(), t.TempDir()
+	t.Cleanup(func() { config.DEFAULT_MEDIA_DIR, config.CGROUP_ROOT = mediaDir, root })
+	// A directory the kernel cannot start processes in
+	if err := os.WriteFile(filepath.Join(config.CGROUP_ROOT, "cgroup.subtree_control"), []byte("cpu memory\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	job := &models.Job{ID: "job", Run: 1, Configuration: models.JobCreateRequest{Limits: &models.JobLimits{CPUs: 1}}}
+	if err := job.Configuration.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	sp := NewStreamingProcess(job)
+	if err := sp.StartJob(); err != nil {
+		t.Fatalf("StartJob() error = %v, want ffmpeg to run without its limits", err)
+	}
+	if !alive(sp.Pid) {
+		t.Errorf("ffmpeg %d is not running", sp.Pid)
+	}
+	if err := sp.StopJob(); err != nil {
+		t.Errorf("StopJob() error = %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package streamer
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// cgroup is the cgroup v2 enforcing the CPU and memory limits of a job.
+type cgroup struct{}
+
+// newCgroup fails, cgroups need a Linux host.
+func newCgroup(name string, limits *models.JobLimits) (*cgroup, error) {
+	return nil, errors.New("cgroups need a Linux host")
+}
+
+func (cg *cgroup) enter(cmd *exec.Cmd) {}
+
+func (cg *cgroup) remove() {}
@@ -0,0 +1,45 @@
+package streamer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// eventSlates returns the filters drawing the pre and post event slates over
+// the test pattern. They are enabled by the stream time, so the phases
+// change without restarting ffmpeg.
+func eventSlates(ec *models.EventConfig) string {
+	preRollText := ec.PreRollText
+	if preRollText == "" {
+		preRollText = "Starting soon"
+	}
+	postRollText := ec.PostRollText
+	if postRollText == "" {
+		postRollText = "Event ended"
+	}
+	liveAt := ec.PreRollSeconds
+	endedAt := ec.PreRollSeconds + ec.LiveSeconds
+
+	var filters []string
+	if ec.PreRollSeconds > 0 {
+		pre := fmt.Sprintf("lt(t,%d)", liveAt)
+		filters = append(filters,
+			fmt.Sprintf("drawbox=x=0:y=0:w=iw:h=ih:color=black:t=fill:enable='%s'", pre),
+			fmt.Sprintf("drawtext=text='%s':fontsize=64:fontcolor=white:x=(w-text_w)/2:y=(h-text_h)/2-50:enable='%s'", preRollText, pre),
+			fmt.Sprintf("drawtext=text='Live in %%{eif\\:max(0,ceil(%d-t))\\:d}s':fontsize=42:fontcolor=yellow:x=(w-text_w)/2:y=(h-text_h)/2+50:enable='%s'", liveAt, pre),
+		)
+	}
+	if ec.PostRollSeconds > 0 {
+		post := fmt.Sprintf("gte(t,%d)", endedAt)
+		filters = append(filters,
+			fmt.Sprintf("drawbox=x=0:y=0:w=iw:h=ih:color=black:t=fill:enable='%s'", post),
+			fmt.Sprintf("drawtext=text='%s':fontsize=64:fontcolor=white:x=(w-text_w)/2:y=(h-text_h)/2:enable='%s'", postRollText, post),
+		)
+	}
+	if len(filters) == 0 {
+		return ""
+	}
+	return "," + strings.Join(filters, ",")
+}
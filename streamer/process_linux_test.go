@@ -0,0 +1,141 @@
+package streamer
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// alive reports whether a process runs, zombies are not reaped in every
+// container so they count as gone.
+func alive(pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+// startFakeFFmpeg starts a job with a shell script as its ffmpeg.
+func startFakeFFmpeg(t *testing.T, script string) *StreamingProcess {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	mediaDir := config.DEFAULT_MEDIA_DIR
+	config.DEFAULT_MEDIA_DIR = t.TempDir()
+	t.Cleanup(func() { config.DEFAULT_MEDIA_DIR = mediaDir })
+
+	job := &models.Job{ID: "job", Run: 1, Configuration: models.JobCreateRequest{Limits: &models.JobLimits{Nice: 5}}}
+	if err := job.Configuration.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	sp := NewStreamingProcess(job)
+	if err := sp.StartJob(); err != nil {
+		t.Fatal(err)
+	}
+	return sp
+}
+
+func TestStopJob(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		timeout time.Duration
+		within  time.Duration
+	}{
+		{
+			name:    "Returns once ffmpeg exits",
+			script:  "trap 'exit 0' INT TERM\nwhile :; do sleep 0.1; done\n",
+			timeout: time.Minute,
+			within:  time.Second,
+		},
+		{
+			name:    "Kills ffmpeg ignoring SIGINT",
+			script:  "trap '' INT\nwhile :; do sleep 0.1; done\n",
+			timeout: 300 * time.Millisecond,
+			within:  2 * time.Second,
+		},
+	}
+	defer func(timeout time.Duration) { config.STOP_TIMEOUT = timeout }(config.STOP_TIMEOUT)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.STOP_TIMEOUT = tt.timeout
+			sp := startFakeFFmpeg(t, tt.script)
+			exited := make(chan error, 1)
+			sp.OnExit = func(err error) { exited <- err }
+			started := time.Now()
+			if err := sp.StopJob(); err != nil {
+				t.Fatalf("StopJob() error = %v", err)
+			}
+			if took := time.Since(started); took > tt.within {
+				t.Errorf("StopJob() took %v, want less than %v", took, tt.within)
+			}
+			if alive(sp.Pid) {
+				t.Errorf("ffmpeg %d is still running", sp.Pid)
+			}
+			if err := <-exited; err != nil {
+				t.Errorf("OnExit() error = %v, want nil for a stopped job", err)
+			}
+			// Stopping again returns right away
+			started = time.Now()
+			if err := sp.StopJob(); err != nil || time.Since(started) > 100*time.Millisecond {
+				t.Errorf("StopJob() of a stopped job error = %v, took %v", err, time.Since(started))
+			}
+		})
+	}
+}
+
+func TestStopJob_KillsChildren(t *testing.T) {
+	// An ffmpeg that spawns a process ignoring SIGINT
+	out := t.TempDir()
+	sp := startFakeFFmpeg(t, "(trap '' INT; exec sleep 60) &\necho $! > "+filepath.Join(out, "child")+"\ntrap 'exit 0' INT TERM\nwhile :; do sleep 0.1; done\n")
+	var child int
+	for deadline := time.Now().Add(5 * time.Second); child == 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		data, _ := os.ReadFile(filepath.Join(out, "child"))
+		child, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	if child == 0 {
+		t.Fatal("ffmpeg did not start its child process")
+	}
+	if err := sp.StopJob(); err != nil {
+		t.Fatalf("StopJob() error = %v", err)
+	}
+	for deadline := time.Now().Add(2 * time.Second); alive(child) && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if alive(child) {
+		t.Errorf("Child process %d of ffmpeg is still running", child)
+	}
+}
+
+func TestExit_KillsChildren(t *testing.T) {
+	// An ffmpeg that exits and leaves a process behind
+	out := t.TempDir()
+	sp := startFakeFFmpeg(t, "sleep 60 &\necho $! > "+filepath.Join(out, "child")+"\nsleep 0.2\n")
+	select {
+	case <-sp.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ffmpeg did not exit")
+	}
+	data, err := os.ReadFile(filepath.Join(out, "child"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	for deadline := time.Now().Add(2 * time.Second); alive(child) && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if alive(child) {
+		t.Errorf("Child process %d of ffmpeg is still running", child)
+	}
+}
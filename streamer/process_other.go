@@ -0,0 +1,33 @@
+//go:build !unix
+
+package streamer
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// isolate does nothing, process groups need a Unix host.
+func isolate(cmd *exec.Cmd) {}
+
+// signalGroup sends a signal to the process, process groups need a Unix host.
+func signalGroup(pid int, sig syscall.Signal) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if sig == syscall.SIGKILL {
+		return process.Kill()
+	}
+	return process.Signal(sig)
+}
+
+// niced returns the command line as it is, niceness needs a Unix host.
+func niced(cmd []string, nice int) []string {
+	if nice != 0 {
+		slog.Warn("Running ffmpeg without its niceness, it needs a Unix host", "nice", nice)
+	}
+	return cmd
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package streamer
+
+import (
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// isolate starts ffmpeg in a process group of its own, so that stopping it
+// reaches the processes it spawns and signals to the server do not reach it.
+func isolate(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalGroup sends a signal to the process group led by pid.
+func signalGroup(pid int, sig syscall.Signal) error {
+	return syscall.Kill(-pid, sig)
+}
+
+// niced returns a command line that runs at a niceness. The niceness is set
+// by nice(1) before ffmpeg starts so all of its threads inherit it.
+func niced(cmd []string, nice int) []string {
+	if nice == 0 {
+		return cmd
+	}
+	path, err := exec.LookPath("nice")
+	if err != nil {
+		slog.Warn("Running ffmpeg without its niceness, nice is not installed", "nice", nice)
+		return cmd
+	}
+	return append([]string{path, "-n", strconv.Itoa(nice)}, cmd...)
+}
@@ -0,0 +1,113 @@
+package streamer
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/pkg/hls"
+)
+
+// readProgress parses the key=value blocks ffmpeg writes with -progress and
+// reports each completed block.
+func readProgress(r io.Reader, report func(models.EncoderStats)) {
+	scanner := bufio.NewScanner(r)
+	var stats models.EncoderStats
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "frame":
+			stats.Frame, _ = strconv.ParseInt(value, 10, 64)
+		case "fps":
+			stats.FPS, _ = strconv.ParseFloat(value, 64)
+		case "bitrate":
+			stats.Bitrate = value
+		case "total_size":
+			stats.TotalSize, _ = strconv.ParseInt(value, 10, 64)
+		case "out_time":
+			stats.OutTime = value
+		case "dup_frames":
+			stats.DupFrames, _ = strconv.ParseInt(value, 10, 64)
+		case "drop_frames":
+			stats.DropFrames, _ = strconv.ParseInt(value, 10, 64)
+		case "speed":
+			stats.Speed = value
+		case "progress":
+			// Ends a block, "end" for the last one
+			report(stats)
+			stats = models.EncoderStats{}
+		}
+	}
+}
+
+// readLog logs the lines ffmpeg writes with -loglevel level+warning and
+// reports the errors.
+func readLog(r io.Reader, jobID string, report func(string)) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, "[error]"), strings.Contains(line, "[fatal]"), strings.Contains(line, "[panic]"):
+			slog.Error("ffmpeg error", "jobID", jobID, "line", line)
+			report(line)
+		case line != "":
+			slog.Warn("ffmpeg warning", "jobID", jobID, "line", line)
+		}
+	}
+}
+
+var mediaPlaylistName = regexp.MustCompile(`^media_\d+\.m3u8$`)
+
+// playlistTracker turns media playlist updates into notifications about the
+// segments added since the previous update.
+type playlistTracker struct {
+	dir  string
+	last map[string]int64 // Media sequence number of the last reported segment per playlist
+}
+
+func newPlaylistTracker(dir string) *playlistTracker {
+	return &playlistTracker{dir: dir, last: make(map[string]int64)}
+}
+
+// update reads a media playlist and returns its new segments.
+func (pt *playlistTracker) update(name string) []models.SegmentInfo {
+	data, err := os.ReadFile(filepath.Join(pt.dir, name))
+	if err != nil {
+		return nil
+	}
+	pl, err := hls.DecodeMedia(data)
+	if err != nil {
+		slog.Debug("Skipping unreadable playlist", "file", name, "error", err)
+		return nil
+	}
+	last, seen := pt.last[name]
+	var added []models.SegmentInfo
+	for i, seg := range pl.Segments {
+		sequence := pl.MediaSequence + int64(i)
+		if seen && sequence <= last {
+			continue
+		}
+		info := models.SegmentInfo{
+			File:     seg.URI,
+			Playlist: name,
+			Sequence: sequence,
+			Duration: seg.Duration,
+		}
+		if stat, err := os.Stat(filepath.Join(pt.dir, seg.URI)); err == nil {
+			info.Size = stat.Size()
+		}
+		added = append(added, info)
+		pt.last[name] = sequence
+	}
+	return added
+}
@@ -1,38 +1,87 @@
 package streamer
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/arunjeyaprasad/golive/config"
 	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/transform"
 	"github.com/fsnotify/fsnotify"
 )
 
+// keepAllSegments is passed as -extra_window_size so ffmpeg never deletes a
+// segment. ffmpeg adds the window, at most models.MaxWindowSize, to it in a
+// 32-bit int.
+const keepAllSegments = math.MaxInt32 - models.MaxWindowSize
+
 type StreamingProcess struct {
 	Job                  *models.Job
 	Pid                  int
 	OutDir               string
-	monitoringChannel    chan bool
 	lastSegmentCreatedAt int64 // Timestamp of the last segment created
-	channelClosed        bool
+	stopping             atomic.Bool
+	ctx                  context.Context
+	cancel               context.CancelFunc // Asks ffmpeg to stop, see StopJob
+	done                 chan struct{}      // Closed once ffmpeg has exited, or failed to start
+	stopMonitor          chan struct{}      // Closed to stop monitoring the output directory
+	stopMonitorOnce      sync.Once
+
+	// Callbacks, set them before StartJob
+	OnSegment func(seg models.SegmentInfo)    // A new segment was added to a media playlist
+	OnStall   func()                          // No segment was written for stallSegments segment lengths
+	OnStats   func(stats models.EncoderStats) // ffmpeg reported its progress
+	OnError   func(line string)               // ffmpeg logged an error
+	OnExit    func(err error)                 // ffmpeg exited, err is nil if it was stopped
 }
 
+// stallSegments is the number of segment lengths without a new segment after which a stream is stalled
+const stallSegments = 3
+
+// stopSignals is the number of SIGINTs ffmpeg gets to finalize its output,
+// it exits without finalizing on the fourth
+const stopSignals = 3
+
 func NewStreamingProcess(job *models.Job) *StreamingProcess {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &StreamingProcess{
-		Job:               job,
-		OutDir:            filepath.Join(config.DEFAULT_MEDIA_DIR, job.ID),
-		monitoringChannel: make(chan bool),
+		Job:         job,
+		OutDir:      RunDir(job.ID, job.Run),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		stopMonitor: make(chan struct{}),
 	}
 }
 
+// StartJob starts ffmpeg and returns once it runs. Its exit is reported to
+// OnExit unless it failed to start.
 func (sp *StreamingProcess) StartJob() error {
+	if err := sp.start(); err != nil {
+		close(sp.done)
+		return err
+	}
+	if err := sp.MonitorDirectory(); err != nil {
+		slog.Error("Failed to start directory monitoring", "error", err)
+	}
+	return nil
+}
+
+func (sp *StreamingProcess) start() error {
 	// Start the job using ffmpeg
 	cmd := sp.buildCommand(sp.Job)
 	// Create the output directory
@@ -41,63 +90,135 @@ func (sp *StreamingProcess) StartJob() error {
 		slog.Error("Failed to create output directory", "error", err)
 		return err
 	}
-	// Execute the command
+	if err := sp.UpdateOverlay(sp.Job.Configuration.Description); err != nil {
+		slog.Error("Failed to write overlay text", "error", err)
+		return err
+	}
+	// Confine ffmpeg so it shares the host with the other jobs
+	var cg *cgroup
+	if limits := sp.Job.Configuration.Limits; limits != nil {
+		cmd = niced(cmd, limits.Nice)
+		if limits.Cgroup() {
+			var cgErr error
+			if cg, cgErr = newCgroup(fmt.Sprintf("golive-%s-%d", sp.Job.ID, sp.Job.Run), limits); cgErr != nil {
+				slog.Warn("Running ffmpeg without its CPU and memory limits", "jobID", sp.Job.ID, "error", cgErr)
+			}
+		}
+	}
+
+	// ffmpeg writes to pipes of its own rather than ones copied by exec, so
+	// Wait returns when it exits even if a process it spawned holds them open
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		if cg != nil {
+			cg.remove()
+		}
+		return err
+	}
+	stderr, stderrW, err := os.Pipe()
+	if err != nil {
+		stdout.Close()
+		stdoutW.Close()
+		if cg != nil {
+			cg.remove()
+		}
+		return err
+	}
+	command := func() *exec.Cmd {
+		c := exec.CommandContext(sp.ctx, cmd[0], cmd[1:]...)
+		isolate(c)
+		// Cancelling the context asks ffmpeg and what it spawned to finalize their output
+		c.Cancel = func() error {
+			return signalGroup(c.Process.Pid, syscall.SIGINT)
+		}
+		c.Stdout, c.Stderr = stdoutW, stderrW
+		if cg != nil {
+			// ffmpeg starts in the cgroup, so nothing it spawns escapes the limits
+			cg.enter(c)
+		}
+		return c
+	}
+	execCmd := command()
+	err = execCmd.Start()
+	if err != nil && cg != nil {
+		// Kernels before 5.7 cannot start a process in a cgroup
+		slog.Warn("Running ffmpeg without its CPU and memory limits", "jobID", sp.Job.ID, "error", err)
+		cg.remove()
+		cg = nil
+		execCmd = command()
+		err = execCmd.Start()
+	}
+	stdoutW.Close()
+	stderrW.Close()
+	if err != nil {
+		slog.Error("Failed to start command", "error", err)
+		stdout.Close()
+		stderr.Close()
+		if cg != nil {
+			cg.remove()
+		}
+		return err
+	}
+	sp.Pid = execCmd.Process.Pid
+	// Log the command and PID
+	slog.Info("Streaming Command started", "command", cmd, "pid", sp.Pid)
+
+	// Read progress reports and log lines until ffmpeg closes its outputs,
+	// the job may be replaced meanwhile
+	jobID := sp.Job.ID
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go func() {
+		defer readers.Done()
+		defer stdout.Close()
+		readProgress(stdout, func(stats models.EncoderStats) {
+			if sp.OnStats != nil {
+				sp.OnStats(stats)
+			}
+		})
+	}()
 	go func() {
-		defer func() {
-			if err != nil {
-				slog.Error("Streaming Command failed", "error", err)
+		defer readers.Done()
+		defer stderr.Close()
+		readLog(stderr, jobID, func(line string) {
+			if sp.OnError != nil {
+				sp.OnError(line)
 			}
-		}()
-		execCmd := exec.Command(cmd[0], cmd[1:]...)
-		err := execCmd.Start()
-		if err != nil {
-			slog.Error("Failed to start command", "error", err)
-			return
+		})
+	}()
+	// Supervise ffmpeg, this is the only place that waits for it
+	go func() {
+		err := waitGroup(execCmd)
+		sp.cancel()
+		close(sp.done)
+		readers.Wait()
+		if cg != nil {
+			cg.remove()
 		}
-		sp.Pid = execCmd.Process.Pid
-		// Log the command and PID
-		slog.Info("Streaming Command started", "command", cmd, "pid", execCmd.Process.Pid)
-		// Wait for the command to finish
-		err = execCmd.Wait()
-		if err != nil {
+		if err != nil && !sp.stopping.Load() {
 			slog.Error("Encoding Command failed with error", "error", err)
 		}
+		sp.exited(err)
 	}()
-	if err := sp.MonitorDirectory(); err != nil {
-		slog.Error("Failed to start directory monitoring", "error", err)
-	}
 	return nil
 }
 
 func (sp *StreamingProcess) buildCommand(job *models.Job) []string {
-	// Compute Filter complex using provided description
-	var text string
-	if job.Configuration.Description == "" {
-		text = "Test Live Stream"
-	} else {
-		text = job.Configuration.Description
-	}
-	if len(text) > 50 {
-		text = text[:50] // Limit to 50 characters
-	}
-	slog.Info("Building command for job", "jobID", job.ID, "description", text)
-
-	filterString := "[0:v]drawtext=text='REPLACE_ME':fontsize=42:fontcolor=white:x=50+500*abs(sin(t/2)):y=(h-text_h)/3:box=1:boxcolor=black@0.7,drawtext=text='Frame %{frame_num}':fontsize=28:fontcolor=cyan:x=10:y=h-40:box=1:boxcolor=black@0.7[v]; [1:a]aloop=loop=-1:size=22050[a]"
-	filterString = strings.ReplaceAll(filterString, "REPLACE_ME", text)
-	host := fmt.Sprintf("http://localhost:%d", config.DEFAULT_SERVER_PORT)
-	job.PlaybackURLs = []models.PlaybackURLs{
-		{
-			Format: models.JobOutputFormatDASH,
-			URL:    fmt.Sprintf("%s%s%s", host, string(os.PathSeparator), filepath.Join("jobs", job.ID, "manifest.mpd")),
-		},
-		{
-			Format: models.JobOutputFormatHLS,
-			URL:    fmt.Sprintf("%s%s%s", host, string(os.PathSeparator), filepath.Join("jobs", job.ID, "master.m3u8")),
-		},
+	slog.Info("Building command for job", "jobID", job.ID, "description", overlayText(job.Configuration.Description))
+
+	// The description is drawn from a file ffmpeg rereads, so it can change while streaming
+	filterString := "[0:v]drawtext=textfile='REPLACE_ME':reload=1:fontsize=42:fontcolor=white:x=50+500*abs(sin(t/2)):y=(h-text_h)/3:box=1:boxcolor=black@0.7,drawtext=text='Frame %{frame_num}':fontsize=28:fontcolor=cyan:x=10:y=h-40:box=1:boxcolor=black@0.7[v]; [1:a]aloop=loop=-1:size=22050[a]"
+	filterString = strings.ReplaceAll(filterString, "REPLACE_ME", filepath.Join(sp.OutDir, overlayFile))
+	if job.Configuration.Event != nil {
+		filterString = strings.Replace(filterString, "[v];", eventSlates(job.Configuration.Event)+"[v];", 1)
 	}
+	job.PlaybackURLs = PlaybackURLs(job, 0)
 	slog.Info("Playback URLs for job", "jobID", job.ID, "urls", job.PlaybackURLs)
-	return []string{
+	cmd := []string{
 		"ffmpeg",
+		"-loglevel", "repeat+level+warning", // Tag log lines with their level so errors can be reported
+		"-nostats",
+		"-progress", "pipe:1",
 		"-re",
 		"-f", "lavfi",
 		"-i", fmt.Sprintf("testsrc=size=%s:rate=%s", job.Configuration.VideoTrack.Resolution, job.Configuration.VideoTrack.Framerate),
@@ -106,6 +227,12 @@ func (sp *StreamingProcess) buildCommand(job *models.Job) []string {
 		"-filter_complex", filterString,
 		"-map", "[v]",
 		"-map", "[a]",
+	}
+	if limits := job.Configuration.Limits; limits != nil && limits.Threads > 0 {
+		threads := strconv.Itoa(limits.Threads)
+		cmd = append(cmd, "-threads", threads, "-filter_complex_threads", threads)
+	}
+	cmd = append(cmd,
 		"-c:v", job.Configuration.VideoTrack.Codec,
 		"-b:v", job.Configuration.VideoTrack.BitRate,
 		"-g", "150",
@@ -118,7 +245,17 @@ func (sp *StreamingProcess) buildCommand(job *models.Job) []string {
 		"-ac", job.Configuration.AudioTrack.AudioChannels,
 		"-f", "dash",
 		"-seg_duration", fmt.Sprintf("%d", job.Configuration.SegmentLength),
-		"-window_size", "6",
+		"-window_size", fmt.Sprintf("%d", job.Configuration.PlaylistWindow()),
+	)
+	if job.Configuration.VODOnStop {
+		// Segments leaving the live window must stay on disk for the VOD asset
+		cmd = append(cmd, "-extra_window_size", fmt.Sprintf("%d", keepAllSegments))
+	}
+	if job.Configuration.Event != nil {
+		// Ending the encode makes ffmpeg write a static MPD and EXT-X-ENDLIST
+		cmd = append(cmd, "-t", fmt.Sprintf("%d", int(job.Configuration.Event.Length().Seconds())))
+	}
+	return append(cmd,
 		"-use_template", "1",
 		"-use_timeline", "1",
 		"-hls_playlist", "1",
@@ -126,46 +263,172 @@ func (sp *StreamingProcess) buildCommand(job *models.Job) []string {
 		"-write_prft", "1",
 		// "-ldash", "1",
 		"-y", filepath.Join(sp.OutDir, "manifest.mpd"), // Will generate HLS manifest and segments in the output directory
+	)
+}
+
+// overlayFile holds the text drawn over the test pattern, in the output directory
+const overlayFile = "overlay.txt"
+
+// overlayText returns the text drawn over the test pattern for a description.
+func overlayText(description string) string {
+	if description == "" {
+		return "Test Live Stream"
+	}
+	if len(description) > 50 {
+		return description[:50] // Limit to 50 characters
 	}
+	return description
 }
 
-func (sp *StreamingProcess) StopJob() error {
-	if sp.channelClosed {
-		slog.Info("Job already stopped or monitoring channel closed", "jobID", sp.Job.ID)
+// UpdateOverlay replaces the text drawn over the test pattern, ffmpeg picks it
+// up on its next frame.
+func (sp *StreamingProcess) UpdateOverlay(description string) error {
+	// ffmpeg must never read a partly written file, replace it in one rename
+	tmp := filepath.Join(sp.OutDir, overlayFile+".tmp")
+	if err := os.WriteFile(tmp, []byte(overlayText(description)), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(sp.OutDir, overlayFile))
+}
+
+// RunDir returns the output directory of a run of a job.
+func RunDir(jobID string, run int) string {
+	return filepath.Join(config.DEFAULT_MEDIA_DIR, jobID, "runs", strconv.Itoa(run))
+}
+
+// PlaybackURL returns the URL a file in the output directory of a run is
+// served at, or of the latest run of the job if run is 0. It is relative to
+// the public base URL, ResolvePlaybackURLs makes it absolute.
+func PlaybackURL(jobID string, run int, file string) string {
+	if run > 0 {
+		file = path.Join("runs", strconv.Itoa(run), file)
+	}
+	return path.Join(config.API_VERSION, "jobs", jobID, file)
+}
+
+// BaseURL returns the public base URL of the server when there is no request
+// to derive it from.
+func BaseURL() string {
+	if config.PUBLIC_URL != "" {
+		return config.PUBLIC_URL
+	}
+	return fmt.Sprintf("http://localhost:%d/", config.DEFAULT_SERVER_PORT)
+}
+
+// ResolvePlaybackURLs returns a copy of playback URLs made absolute against a
+// base URL, with their URLs on the CDN hosts.
+func ResolvePlaybackURLs(urls []models.PlaybackURLs, baseURL string) []models.PlaybackURLs {
+	if urls == nil {
 		return nil
 	}
-	slog.Info("Stopping job", "jobID", sp.Job.ID, "PID", sp.Pid)
-	// Stop monitoring the directory
-	if sp.monitoringChannel != nil {
-		sp.monitoringChannel <- true
-		sp.channelClosed = true
-	}
-	// Stop the process if it's running
-	if sp.Pid != 0 {
-		process, err := os.FindProcess(sp.Pid)
-		if err != nil {
-			slog.Error("Failed to find process", "error", err)
-			return err
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		slog.Error("Invalid base URL of the playback URLs", "url", baseURL, "error", err)
+		return urls
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	resolved := make([]models.PlaybackURLs, len(urls))
+	for i, u := range urls {
+		ref, err := url.Parse(u.URL)
+		if err != nil || ref.IsAbs() {
+			resolved[i] = u
+			continue
 		}
-		// Send SIGINT to the process
-		// Send multiple SIGINT signals if ffmpeg is concurrently doing both HLS and DASH
-		for i := 0; i < 3; i++ {
-			slog.Info("Sending SIGINT to process", "pid", sp.Pid, "attempt", i+1)
-			process.Signal(syscall.SIGINT)
-			time.Sleep(1 * time.Second)
+		u.URL = base.ResolveReference(ref).String()
+		u.CDNURLs = nil
+		for _, host := range config.CDN_HOSTS {
+			cdn := *base
+			cdn.Host = host
+			u.CDNURLs = append(u.CDNURLs, cdn.ResolveReference(ref).String())
 		}
-		err = process.Kill()
-		if err != nil {
-			if err.Error() == "os: process already finished" {
-				slog.Error("Process not found or already terminated", "pid", sp.Pid, "error", err)
-			} else {
-				slog.Error("Failed to kill process", "error", err)
+		resolved[i] = u
+	}
+	return resolved
+}
+
+// PlaybackURLs returns the live playback URLs of a run of a job, or of its
+// latest run if run is 0.
+func PlaybackURLs(job *models.Job, run int) []models.PlaybackURLs {
+	urls := []models.PlaybackURLs{
+		{
+			Format: models.JobOutputFormatDASH,
+			URL:    PlaybackURL(job.ID, run, "manifest.mpd"),
+		},
+		{
+			Format: models.JobOutputFormatHLS,
+			URL:    PlaybackURL(job.ID, run, "master.m3u8"),
+		},
+	}
+	if job.Configuration.DVRWindowSeconds > 0 {
+		urls = append(urls,
+			models.PlaybackURLs{
+				Format:  models.JobOutputFormatDASH,
+				Variant: models.PlaybackVariantStartOver,
+				URL:     PlaybackURL(job.ID, run, "manifest.mpd") + "?" + transform.StartOverQuery,
+			},
+			models.PlaybackURLs{
+				Format:  models.JobOutputFormatHLS,
+				Variant: models.PlaybackVariantStartOver,
+				URL:     PlaybackURL(job.ID, run, "master.m3u8") + "?" + transform.StartOverQuery,
+			},
+		)
+	}
+	return urls
+}
+
+// exited reports the exit of ffmpeg, errors caused by stopping it are expected.
+func (sp *StreamingProcess) exited(err error) {
+	if sp.stopping.Load() {
+		err = nil
+	} else if err == nil {
+		err = errors.New("ffmpeg exited without being stopped")
+	}
+	if sp.OnExit != nil {
+		sp.OnExit(err)
+	}
+}
+
+// StopJob stops monitoring the output directory and asks ffmpeg to finalize
+// its output. ffmpeg and its process group are killed if they have not
+// exited after config.STOP_TIMEOUT. It returns once ffmpeg has exited.
+func (sp *StreamingProcess) StopJob() error {
+	sp.stopping.Store(true)
+	sp.stopMonitorOnce.Do(func() { close(sp.stopMonitor) })
+	select {
+	case <-sp.done:
+		return nil // Already exited
+	default:
+	}
+	slog.Info("Stopping job", "jobID", sp.Job.ID, "PID", sp.Pid)
+	sp.cancel() // exec sends the first SIGINT
+	timeout := time.NewTimer(config.STOP_TIMEOUT)
+	defer timeout.Stop()
+	// ffmpeg may need more than one SIGINT when it writes both HLS and DASH
+	resend := time.NewTicker(time.Second)
+	defer resend.Stop()
+	for sent := 1; ; {
+		select {
+		case <-sp.done:
+			slog.Info("Process stopped", "pid", sp.Pid)
+			return nil
+		case <-resend.C:
+			if sent < stopSignals {
+				sent++
+				slog.Info("Sending SIGINT to process", "pid", sp.Pid, "attempt", sent)
+				signalGroup(sp.Pid, syscall.SIGINT)
+			}
+		case <-timeout.C:
+			slog.Warn("Process did not stop in time, killing its process group", "pid", sp.Pid, "timeout", config.STOP_TIMEOUT)
+			if err := signalGroup(sp.Pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+				slog.Error("Failed to kill process", "pid", sp.Pid, "error", err)
 				return err
 			}
+			<-sp.done
+			return nil
 		}
-		slog.Info("Process killed", "pid", sp.Pid)
 	}
-	return nil
 }
 
 func (sp *StreamingProcess) MonitorDirectory() error {
@@ -178,7 +441,16 @@ func (sp *StreamingProcess) MonitorDirectory() error {
 		return err
 	}
 
+	segmentLength := time.Duration(sp.Job.Configuration.SegmentLength) * time.Second
+	if segmentLength <= 0 {
+		segmentLength = time.Duration(config.DEFAULT_SEGMENT_LENGTH) * time.Second
+	}
 	go func() {
+		ticker := time.NewTicker(segmentLength)
+		defer ticker.Stop()
+		monitoredFrom := time.Now()
+		stalled := false
+		tracker := newPlaylistTracker(sp.OutDir)
 		for {
 			select {
 			case event, ok := <-watcher.Events:
@@ -189,7 +461,31 @@ func (sp *StreamingProcess) MonitorDirectory() error {
 					// Ignore the .tmp files
 					if strings.HasSuffix(event.Name, ".m4s") || strings.HasSuffix(event.Name, ".ts") {
 						slog.Debug("New media file created", "file", event.Name)
+						if strings.HasPrefix(filepath.Base(event.Name), "init-") {
+							continue // Initialization segments are not media
+						}
 						sp.lastSegmentCreatedAt = time.Now().Unix()
+						stalled = false
+					}
+					// ffmpeg renames updated playlists into place, each rename lists the new segments
+					if name := filepath.Base(event.Name); mediaPlaylistName.MatchString(name) {
+						for _, seg := range tracker.update(name) {
+							if sp.OnSegment != nil {
+								sp.OnSegment(seg)
+							}
+						}
+					}
+				}
+			case <-ticker.C:
+				last := monitoredFrom
+				if sp.lastSegmentCreatedAt > 0 {
+					last = time.Unix(sp.lastSegmentCreatedAt, 0)
+				}
+				if !stalled && time.Since(last) > stallSegments*segmentLength {
+					stalled = true
+					slog.Warn("Stream stalled, no new segments", "jobID", sp.Job.ID, "since", last)
+					if sp.OnStall != nil {
+						sp.OnStall()
 					}
 				}
 			case err, ok := <-watcher.Errors:
@@ -197,13 +493,11 @@ func (sp *StreamingProcess) MonitorDirectory() error {
 					return
 				}
 				slog.Error("Watcher error", "error", err)
-			case <-sp.monitoringChannel:
+			case <-sp.stopMonitor:
 				slog.Info("Stopping directory monitoring", "job", sp.Job.ID)
-				// Close the watcher when the monitoring channel is closed
 				if err := watcher.Close(); err != nil {
 					slog.Error("Failed to close watcher", "error", err)
 				}
-				defer close(sp.monitoringChannel)
 				return
 			}
 		}
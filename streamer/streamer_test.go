@@ -0,0 +1,218 @@
+package streamer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+func TestReadProgress(t *testing.T) {
+	progress := `frame=90
+fps=30.00
+stream_0_0_q=28.0
+bitrate=1021.3kbits/s
+total_size=383022
+out_time_us=3000000
+out_time=00:00:03.000000
+dup_frames=0
+drop_frames=2
+speed=1.01x
+progress=continue
+frame=180
+fps=30.01
+progress=end
+`
+	var got []models.EncoderStats
+	readProgress(strings.NewReader(progress), func(stats models.EncoderStats) {
+		got = append(got, stats)
+	})
+	if len(got) != 2 {
+		t.Fatalf("reports = %d, want 2", len(got))
+	}
+	want := models.EncoderStats{Frame: 90, FPS: 30, Bitrate: "1021.3kbits/s", TotalSize: 383022, OutTime: "00:00:03.000000", DropFrames: 2, Speed: "1.01x"}
+	if got[0] != want {
+		t.Errorf("first report = %+v, want %+v", got[0], want)
+	}
+	if got[1].Frame != 180 || got[1].Bitrate != "" {
+		t.Errorf("second report = %+v, want a fresh block", got[1])
+	}
+}
+
+func TestReadLog(t *testing.T) {
+	log := `[dash @ 0x55d0c8a3e2c0] [warning] No bit rate set for stream 1
+[dash @ 0x55d0c8a3e2c0] [error] Unable to open media/job/chunk-stream0-00003.m4s for writing
+[fatal] Conversion failed!
+`
+	var got []string
+	readLog(strings.NewReader(log), "job", func(line string) { got = append(got, line) })
+	if len(got) != 2 || !strings.Contains(got[0], "Unable to open") || !strings.Contains(got[1], "Conversion failed") {
+		t.Errorf("reported errors = %q, want the error and fatal lines", got)
+	}
+}
+
+func TestPlaylistTracker(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("chunk-stream0-00001.m4s", "0123456789")
+	write("media_0.m3u8", `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:1
+#EXT-X-MAP:URI="init-stream0.m4s"
+#EXTINF:6.000000,
+chunk-stream0-00001.m4s
+`)
+	pt := newPlaylistTracker(dir)
+	got := pt.update("media_0.m3u8")
+	want := models.SegmentInfo{File: "chunk-stream0-00001.m4s", Playlist: "media_0.m3u8", Sequence: 1, Duration: 6, Size: 10}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("update() = %+v, want %+v", got, want)
+	}
+
+	write("media_0.m3u8", `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:1
+#EXT-X-MAP:URI="init-stream0.m4s"
+#EXTINF:6.000000,
+chunk-stream0-00001.m4s
+#EXTINF:5.500000,
+chunk-stream0-00002.m4s
+`)
+	got = pt.update("media_0.m3u8")
+	if len(got) != 1 || got[0].File != "chunk-stream0-00002.m4s" || got[0].Sequence != 2 || got[0].Duration != 5.5 {
+		t.Errorf("update() = %+v, want only the new segment", got)
+	}
+	if got := pt.update("media_0.m3u8"); len(got) != 0 {
+		t.Errorf("update() = %+v, want nothing new", got)
+	}
+}
+
+func TestPlaybackURL(t *testing.T) {
+	tests := []struct {
+		name string
+		run  int
+		file string
+		want string
+	}{
+		{
+			name: "Latest run",
+			file: "master.m3u8",
+			want: "v1/jobs/job/master.m3u8",
+		},
+		{
+			name: "Run",
+			run:  2,
+			file: "manifest.mpd",
+			want: "v1/jobs/job/runs/2/manifest.mpd",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlaybackURL("job", tt.run, tt.file); got != tt.want {
+				t.Errorf("PlaybackURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got, want := RunDir("job", 2), filepath.Join(config.DEFAULT_MEDIA_DIR, "job", "runs", "2"); got != want {
+		t.Errorf("RunDir() = %v, want %v", got, want)
+	}
+}
+
+func TestResolvePlaybackURLs(t *testing.T) {
+	urls := []models.PlaybackURLs{
+		{Format: models.JobOutputFormatHLS, URL: "v1/jobs/job/master.m3u8"},
+		{Format: models.JobOutputFormatDASH, Variant: models.PlaybackVariantStartOver, URL: "v1/jobs/job/manifest.mpd?startover=1"},
+	}
+	tests := []struct {
+		name     string
+		base     string
+		cdnHosts []string
+		want     []models.PlaybackURLs
+	}{
+		{
+			name: "Host",
+			base: "http://localhost:9090/",
+			want: []models.PlaybackURLs{
+				{Format: models.JobOutputFormatHLS, URL: "http://localhost:9090/v1/jobs/job/master.m3u8"},
+				{Format: models.JobOutputFormatDASH, Variant: models.PlaybackVariantStartOver, URL: "http://localhost:9090/v1/jobs/job/manifest.mpd?startover=1"},
+			},
+		},
+		{
+			name:     "Path prefix and CDN hosts",
+			base:     "https://streams.example.com/golive",
+			cdnHosts: []string{"cdn1.example.com", "cdn2.example.com:8443"},
+			want: []models.PlaybackURLs{
+				{
+					Format:  models.JobOutputFormatHLS,
+					URL:     "https://streams.example.com/golive/v1/jobs/job/master.m3u8",
+					CDNURLs: []string{"https://cdn1.example.com/golive/v1/jobs/job/master.m3u8", "https://cdn2.example.com:8443/golive/v1/jobs/job/master.m3u8"},
+				},
+				{
+					Format:  models.JobOutputFormatDASH,
+					Variant: models.PlaybackVariantStartOver,
+					URL:     "https://streams.example.com/golive/v1/jobs/job/manifest.mpd?startover=1",
+					CDNURLs: []string{"https://cdn1.example.com/golive/v1/jobs/job/manifest.mpd?startover=1", "https://cdn2.example.com:8443/golive/v1/jobs/job/manifest.mpd?startover=1"},
+				},
+			},
+		},
+	}
+	defer func(hosts []string) { config.CDN_HOSTS = hosts }(config.CDN_HOSTS)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.CDN_HOSTS = tt.cdnHosts
+			got := ResolvePlaybackURLs(urls, tt.base)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolvePlaybackURLs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if urls[0].URL != "v1/jobs/job/master.m3u8" {
+		t.Errorf("ResolvePlaybackURLs() changed its argument to %s", urls[0].URL)
+	}
+}
+
+func TestBuildCommand_Limits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits *models.JobLimits
+		want   string
+	}{
+		{
+			name: "No limits",
+		},
+		{
+			name:   "Limits without threads",
+			limits: &models.JobLimits{Nice: 10},
+		},
+		{
+			name:   "Threads",
+			limits: &models.JobLimits{Threads: 2},
+			want:   "-threads 2 -filter_complex_threads 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.Job{ID: "job", Configuration: models.JobCreateRequest{Limits: tt.limits}}
+			if err := job.Configuration.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Join(NewStreamingProcess(job).buildCommand(job), " ")
+			if tt.want == "" && strings.Contains(got, "-threads") {
+				t.Errorf("buildCommand() = %v, want no -threads", got)
+			}
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("buildCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
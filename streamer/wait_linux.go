@@ -0,0 +1,26 @@
+package streamer
+
+import (
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// pPID is the idtype of waitid for a single process
+const pPID = 1
+
+// waitGroup waits for ffmpeg to exit and kills what it left behind in its
+// process group. The group is killed before ffmpeg is reaped, while its
+// zombie keeps the group ID from being reused by another process.
+func waitGroup(cmd *exec.Cmd) error {
+	var info [128]byte // siginfo_t
+	for {
+		_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, pPID, uintptr(cmd.Process.Pid),
+			uintptr(unsafe.Pointer(&info)), syscall.WEXITED|syscall.WNOWAIT, 0, 0)
+		if errno != syscall.EINTR {
+			break
+		}
+	}
+	signalGroup(cmd.Process.Pid, syscall.SIGKILL)
+	return cmd.Wait()
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package streamer
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// waitGroup waits for ffmpeg to exit and kills what it left behind in its
+// process group. Without waitid the group is killed after ffmpeg is reaped.
+func waitGroup(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	signalGroup(cmd.Process.Pid, syscall.SIGKILL)
+	return err
+}
@@ -0,0 +1,191 @@
+// Package templates keeps named job configurations, the built-in ones and
+// those saved through the API as JSON files in config.TEMPLATES_DIR.
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+var (
+	ErrNotFound = errors.New("template not found")
+	ErrExists   = errors.New("template already exists")
+	ErrBuiltIn  = errors.New("built-in templates cannot be changed")
+)
+
+// mu serializes the changes to the templates directory
+var mu sync.Mutex
+
+// builtIn are the templates shipped with the server
+var builtIn = []models.Template{
+	{
+		Name:        "1080p-hevc",
+		Description: "Full HD HEVC at 6 Mbps",
+		Request: models.JobCreateRequest{
+			VideoTrack: &models.VideoTrack{BitRate: "6M", Resolution: "1920x1080", Framerate: "30", Codec: "hevc"},
+			AudioTrack: &models.AudioTrack{AudioCodec: "aac", AudioBitrate: "128k", AudioSampleRate: "48000", AudioChannels: "2"},
+		},
+	},
+	{
+		Name:        "4k-av1",
+		Description: "UHD AV1 at 12 Mbps",
+		Request: models.JobCreateRequest{
+			VideoTrack: &models.VideoTrack{BitRate: "12M", Resolution: "3840x2160", Framerate: "30", Codec: "av1"},
+			AudioTrack: &models.AudioTrack{AudioCodec: "aac", AudioBitrate: "192k", AudioSampleRate: "48000", AudioChannels: "2"},
+		},
+	},
+	{
+		Name:        "ll-hls-2s",
+		Description: "Low latency 720p H.264 with 2 second segments",
+		Request: models.JobCreateRequest{
+			VideoTrack: &models.VideoTrack{BitRate: "2M", Resolution: "1280x720", Framerate: "30", Codec: "h264"},
+			JobFormat:  models.JobFormat{SegmentLength: 2, WindowSize: 3},
+		},
+	},
+}
+
+func init() {
+	for i := range builtIn {
+		builtIn[i].BuiltIn = true
+	}
+}
+
+// findBuiltIn returns the built-in template with the name.
+func findBuiltIn(name string) (models.Template, bool) {
+	for _, t := range builtIn {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return models.Template{}, false
+}
+
+// path returns the file of a saved template, names are validated to be file names.
+func path(name string) string {
+	return filepath.Join(config.TEMPLATES_DIR, name+".json")
+}
+
+// List returns all templates sorted by name.
+func List() ([]models.Template, error) {
+	templates := append([]models.Template{}, builtIn...)
+	entries, err := os.ReadDir(config.TEMPLATES_DIR)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if _, shadowed := findBuiltIn(name); shadowed {
+			continue
+		}
+		t, err := load(name)
+		if err != nil {
+			slog.Error("Skipping unreadable template", "file", entry.Name(), "error", err)
+			continue
+		}
+		templates = append(templates, *t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// Get returns a template by name, or ErrNotFound.
+func Get(name string) (*models.Template, error) {
+	if t, ok := findBuiltIn(name); ok {
+		return &t, nil
+	}
+	t, err := load(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return t, err
+}
+
+// Create saves a new template, or returns ErrExists. The template must be valid.
+func Create(t models.Template) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, err := Get(t.Name); err == nil {
+		return ErrExists
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return save(t)
+}
+
+// Put saves a template, replacing the one with the same name. It reports
+// whether the template is new. The template must be valid.
+func Put(t models.Template) (bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := findBuiltIn(t.Name); ok {
+		return false, ErrBuiltIn
+	}
+	_, err := os.Stat(path(t.Name))
+	created := errors.Is(err, os.ErrNotExist)
+	return created, save(t)
+}
+
+// Delete removes a saved template.
+func Delete(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := findBuiltIn(name); ok {
+		return ErrBuiltIn
+	}
+	err := os.Remove(path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func load(name string) (*models.Template, error) {
+	data, err := os.ReadFile(path(name))
+	if err != nil {
+		return nil, err
+	}
+	var t models.Template
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	t.Name = name
+	t.BuiltIn = false
+	return &t, nil
+}
+
+// save writes a template in one rename, readers never see a partial file.
+func save(t models.Template) error {
+	t.BuiltIn = false
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(config.TEMPLATES_DIR, os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(config.TEMPLATES_DIR, t.Name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path(t.Name))
+}
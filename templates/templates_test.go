@@ -0,0 +1,111 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+func setup(t *testing.T) {
+	dir := config.TEMPLATES_DIR
+	config.TEMPLATES_DIR = filepath.Join(t.TempDir(), "templates")
+	t.Cleanup(func() { config.TEMPLATES_DIR = dir })
+}
+
+func TestBuiltIn(t *testing.T) {
+	for _, template := range builtIn {
+		t.Run(template.Name, func(t *testing.T) {
+			if !template.BuiltIn {
+				t.Error("BuiltIn = false")
+			}
+			if err := template.Validate(); err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	setup(t)
+	template := models.Template{
+		Name:    "team",
+		Request: models.JobCreateRequest{Description: "Team stream"},
+	}
+
+	if err := Create(template); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := Create(template); !errors.Is(err, ErrExists) {
+		t.Errorf("Create() of an existing template error = %v, want ErrExists", err)
+	}
+	if err := Create(models.Template{Name: "4k-av1"}); !errors.Is(err, ErrExists) {
+		t.Errorf("Create() of a built-in template error = %v, want ErrExists", err)
+	}
+
+	template.Request.Description = "Updated"
+	if created, err := Put(template); err != nil || created {
+		t.Errorf("Put() of an existing template = %v, %v, want an update", created, err)
+	}
+	got, err := Get("team")
+	if err != nil || got.Request.Description != "Updated" || got.BuiltIn {
+		t.Errorf("Get() = %+v, %v, want the updated template", got, err)
+	}
+	if created, err := Put(models.Template{Name: "other"}); err != nil || !created {
+		t.Errorf("Put() of a new template = %v, %v, want it created", created, err)
+	}
+	if _, err := Put(models.Template{Name: "ll-hls-2s"}); !errors.Is(err, ErrBuiltIn) {
+		t.Errorf("Put() of a built-in template error = %v, want ErrBuiltIn", err)
+	}
+
+	// Unreadable files and files shadowed by a built-in template are skipped
+	for name, data := range map[string]string{"broken.json": "{", "4k-av1.json": "{}", "notes.txt": ""} {
+		if err := os.WriteFile(filepath.Join(config.TEMPLATES_DIR, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var names []string
+	for _, template := range list {
+		names = append(names, template.Name)
+	}
+	want := []string{"1080p-hevc", "4k-av1", "ll-hls-2s", "other", "team"}
+	if len(names) != len(want) {
+		t.Fatalf("List() names = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("List() names = %v, want %v", names, want)
+		}
+	}
+	if got, _ := Get("4k-av1"); !got.BuiltIn {
+		t.Errorf("Get() of a shadowed built-in template = %+v, want the built-in one", got)
+	}
+
+	if err := Delete("team"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if _, err := Get("team"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a deleted template error = %v, want ErrNotFound", err)
+	}
+	if err := Delete("team"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() of a deleted template error = %v, want ErrNotFound", err)
+	}
+	if err := Delete("1080p-hevc"); !errors.Is(err, ErrBuiltIn) {
+		t.Errorf("Delete() of a built-in template error = %v, want ErrBuiltIn", err)
+	}
+}
+
+func TestList_NoDirectory(t *testing.T) {
+	setup(t)
+	list, err := List()
+	if err != nil || len(list) != len(builtIn) {
+		t.Errorf("List() = %d templates, %v, want the built-in ones", len(list), err)
+	}
+}
@@ -0,0 +1,91 @@
+package transform
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/pkg/dash"
+	"github.com/arunjeyaprasad/golive/pkg/hls"
+)
+
+// Continuation describes a run that restarted the encoder of an earlier run
+// of the same job. The manifests of the job, served from its latest run, are
+// spliced onto those of the earlier runs so players see a discontinuity
+// instead of a stream that starts over.
+type Continuation struct {
+	// Dir is the directory of the run relative to the manifests of the job, e.g. runs/2/
+	Dir string
+	// Period is the ID of the DASH period of the run
+	Period string
+	// AvailabilityStartTime of the MPD of the first run, the periods of the
+	// later runs start relative to it
+	AvailabilityStartTime string
+	// MediaSequence is added to the media sequence numbers of the run, so they
+	// carry on from the last segment of the earlier run
+	MediaSequence int64
+	// Discontinuities is the number of restarts up to and including this run
+	Discontinuities int64
+}
+
+// FirstSegmentNumber is the number of the first segment of a run, ffmpeg's
+// DASH muxer numbers them from 1.
+const FirstSegmentNumber = 1
+
+// spliceMedia continues the media playlist of an earlier run. The first
+// segment of the run is marked as a discontinuity while it is listed.
+func spliceMedia(pl *hls.MediaPlaylist, c *Continuation) {
+	pl.DiscontinuitySequence += c.Discontinuities
+	if len(pl.Segments) > 0 && pl.MediaSequence == FirstSegmentNumber {
+		pl.Segments[0].Discontinuity = true
+		pl.DiscontinuitySequence--
+	}
+	pl.MediaSequence += c.MediaSequence
+
+	// The earlier runs wrote segments of the same names
+	if pl.Map != nil {
+		pl.Map.URI = runURL(pl.Map.URI, c)
+	}
+	for _, seg := range pl.Segments {
+		seg.URI = runURL(seg.URI, c)
+		if seg.Map != nil {
+			seg.Map.URI = runURL(seg.Map.URI, c)
+		}
+	}
+}
+
+// spliceMPD continues the presentation of an earlier run with a period of its
+// own, starting when the run started.
+func spliceMPD(mpd *dash.MPD, c *Continuation) {
+	start, err := time.Parse(time.RFC3339, mpd.AvailabilityStartTime)
+	first, ferr := time.Parse(time.RFC3339, c.AvailabilityStartTime)
+	if err == nil && ferr == nil {
+		mpd.AvailabilityStartTime = c.AvailabilityStartTime
+		for _, period := range mpd.Periods {
+			offset, _ := dash.ParseDuration(period.Start)
+			period.Start = dash.FormatDuration(offset + start.Sub(first))
+		}
+	}
+	for i, period := range mpd.Periods {
+		period.ID = c.Period
+		if i > 0 {
+			period.ID += "-" + strconv.Itoa(i)
+		}
+		if len(period.BaseURLs) == 0 {
+			period.BaseURLs = []string{c.Dir}
+			continue
+		}
+		for j, u := range period.BaseURLs {
+			period.BaseURLs[j] = runURL(u, c)
+		}
+	}
+}
+
+// runURL returns a URL relative to the directory of a run relative to the
+// manifests of the job.
+func runURL(uri string, c *Continuation) string {
+	if u, err := url.Parse(uri); err != nil || u.IsAbs() || u.Path == "" || u.Path[0] == '/' {
+		return uri
+	}
+	return c.Dir + uri
+}
@@ -0,0 +1,101 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/pkg/dash"
+)
+
+// DASH applies the transforms to an MPD. Injected tags only apply to HLS.
+func DASH(data []byte, mt *models.ManifestTransforms, opts Options) ([]byte, error) {
+	mpd, err := dash.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Continuation != nil {
+		spliceMPD(mpd, opts.Continuation)
+	}
+
+	var window time.Duration
+	trimmed := make(map[*dash.SegmentTemplate]bool)
+	for _, period := range mpd.Periods {
+		var sets []*dash.AdaptationSet
+		for _, as := range period.AdaptationSets {
+			// Drop renditions and the adaptation sets left empty
+			var reps []*dash.Representation
+			for _, rep := range as.Representations {
+				if !dropped(mt, rep.ID) {
+					reps = append(reps, rep)
+				}
+			}
+			if len(reps) == 0 {
+				continue
+			}
+			as.Representations = reps
+			sets = append(sets, as)
+
+			switch mt.ReorderVariants {
+			case models.VariantOrderBandwidthAsc:
+				sort.SliceStable(reps, func(i, j int) bool { return reps[i].Bandwidth < reps[j].Bandwidth })
+			case models.VariantOrderBandwidthDesc:
+				sort.SliceStable(reps, func(i, j int) bool { return reps[i].Bandwidth > reps[j].Bandwidth })
+			case models.VariantOrderReverse:
+				for i, j := 0, len(reps)-1; i < j; i, j = i+1, j-1 {
+					reps[i], reps[j] = reps[j], reps[i]
+				}
+			}
+
+			for _, rep := range reps {
+				rep.Bandwidth = scaleBandwidth(rep.Bandwidth, mt)
+
+				// Shorten the DVR window, templates may be shared by an adaptation set
+				tmpl := as.TemplateFor(rep)
+				if mt.DVRWindowSegments <= 0 || tmpl == nil || trimmed[tmpl] {
+					continue
+				}
+				trimmed[tmpl] = true
+				segments := tmpl.Segments()
+				if len(segments) > mt.DVRWindowSegments {
+					segments = segments[len(segments)-mt.DVRWindowSegments:]
+					tmpl.SetSegments(segments)
+				}
+				var total uint64
+				for _, seg := range segments {
+					total += seg.Duration
+				}
+				if d := time.Duration(float64(total) / float64(tmpl.GetTimescale()) * float64(time.Second)); d > window {
+					window = d
+				}
+			}
+		}
+		period.AdaptationSets = sets
+	}
+	if window > 0 && mpd.IsDynamic() {
+		mpd.TimeShiftBufferDepth = dash.FormatDuration(window)
+	}
+
+	// A presentation delay as deep as the timeshift buffer starts playback at the oldest segment
+	if opts.StartOver && mpd.IsDynamic() && mpd.TimeShiftBufferDepth != "" {
+		mpd.SuggestedPresentationDelay = mpd.TimeShiftBufferDepth
+	}
+
+	if mt.TargetDuration > 0 {
+		mpd.MaxSegmentDuration = fmt.Sprintf("PT%dS", mt.TargetDuration)
+	}
+
+	// Resolve segment URLs against an absolute or CDN base
+	if base := baseURL(mt, opts); base != "" {
+		if len(mpd.BaseURLs) == 0 {
+			mpd.BaseURLs = []string{base}
+		} else {
+			for i, u := range mpd.BaseURLs {
+				mpd.BaseURLs[i] = rewriteURL(u, mt, opts)
+			}
+		}
+	}
+
+	return mpd.Encode()
+}
@@ -0,0 +1,156 @@
+package transform
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/pkg/hls"
+)
+
+// ffmpeg's DASH muxer names the HLS playlist of representation N media_N.m3u8
+var mediaPlaylistIndex = regexp.MustCompile(`^media_(\d+)\.m3u8$`)
+
+func playlistIndex(uri string) string {
+	if m := mediaPlaylistIndex.FindStringSubmatch(uri); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// HLS applies the transforms to a master or media playlist.
+func HLS(data []byte, mt *models.ManifestTransforms, opts Options) ([]byte, error) {
+	pl, err := hls.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	switch pl := pl.(type) {
+	case *hls.MasterPlaylist:
+		transformMaster(pl, mt, opts)
+	case *hls.MediaPlaylist:
+		if opts.Continuation != nil {
+			spliceMedia(pl, opts.Continuation)
+		}
+		transformMedia(pl, mt, opts)
+	}
+	return pl.Encode(), nil
+}
+
+func transformMaster(pl *hls.MasterPlaylist, mt *models.ManifestTransforms, opts Options) {
+	// Drop renditions
+	if len(mt.DropRenditions) > 0 {
+		var renditions []*hls.Rendition
+		groups := make(map[string]bool)
+		for _, r := range pl.Renditions {
+			if dropped(mt, r.URI(), r.Name(), playlistIndex(r.URI())) {
+				continue
+			}
+			renditions = append(renditions, r)
+			groups[r.GroupID()] = true
+		}
+		pl.Renditions = renditions
+
+		var variants []*hls.Variant
+		for _, v := range pl.Variants {
+			if dropped(mt, v.URI, playlistIndex(v.URI)) {
+				continue
+			}
+			// Don't leave variants pointing at a rendition group that no longer exists
+			for _, attr := range []string{"AUDIO", "SUBTITLES"} {
+				if group, ok := v.Attributes.Get(attr); ok && !groups[group] {
+					v.Attributes.Delete(attr)
+				}
+			}
+			variants = append(variants, v)
+		}
+		pl.Variants = variants
+	}
+
+	// Reorder variants
+	switch mt.ReorderVariants {
+	case models.VariantOrderBandwidthAsc:
+		sort.SliceStable(pl.Variants, func(i, j int) bool {
+			return pl.Variants[i].Bandwidth() < pl.Variants[j].Bandwidth()
+		})
+	case models.VariantOrderBandwidthDesc:
+		sort.SliceStable(pl.Variants, func(i, j int) bool {
+			return pl.Variants[i].Bandwidth() > pl.Variants[j].Bandwidth()
+		})
+	case models.VariantOrderReverse:
+		for i, j := 0, len(pl.Variants)-1; i < j; i, j = i+1, j-1 {
+			pl.Variants[i], pl.Variants[j] = pl.Variants[j], pl.Variants[i]
+		}
+	}
+
+	// Inflate or shrink the advertised bandwidth
+	if mt.BandwidthScale != 0 {
+		for _, v := range append(append([]*hls.Variant{}, pl.Variants...), pl.IFrameVariants...) {
+			v.SetBandwidth(scaleBandwidth(v.Bandwidth(), mt))
+			if avg := v.AverageBandwidth(); avg != 0 {
+				v.SetAverageBandwidth(scaleBandwidth(avg, mt))
+			}
+		}
+	}
+
+	// Rewrite playlist URLs
+	for _, r := range pl.Renditions {
+		if uri := r.URI(); uri != "" {
+			r.SetURI(startOverURL(rewriteURL(uri, mt, opts), opts))
+		}
+	}
+	for _, v := range pl.Variants {
+		v.URI = startOverURL(rewriteURL(v.URI, mt, opts), opts)
+	}
+	for _, v := range pl.IFrameVariants {
+		if uri, ok := v.Attributes.Get("URI"); ok {
+			v.Attributes.Set("URI", rewriteURL(uri, mt, opts), true)
+		}
+	}
+
+	if opts.StartOver {
+		pl.Start = &hls.Start{TimeOffset: 0, Precise: true}
+	}
+	pl.Tags = append(pl.Tags, mt.InjectMasterTags...)
+}
+
+func transformMedia(pl *hls.MediaPlaylist, mt *models.ManifestTransforms, opts Options) {
+	// Shorten the DVR window
+	if n := mt.DVRWindowSegments; n > 0 && len(pl.Segments) > n {
+		drop := len(pl.Segments) - n
+		for _, seg := range pl.Segments[:drop] {
+			if seg.Discontinuity {
+				pl.DiscontinuitySequence++
+			}
+			// Carry forward the initialization section of the removed segments
+			if seg.Map != nil {
+				pl.Map = seg.Map
+			}
+		}
+		pl.Segments = pl.Segments[drop:]
+		pl.MediaSequence += int64(drop)
+		if pl.Segments[0].Map != nil {
+			pl.Map = pl.Segments[0].Map
+			pl.Segments[0].Map = nil
+		}
+	}
+
+	if mt.TargetDuration > 0 {
+		pl.TargetDuration = mt.TargetDuration
+	}
+
+	// Rewrite segment URLs
+	if pl.Map != nil {
+		pl.Map.URI = rewriteURL(pl.Map.URI, mt, opts)
+	}
+	for _, seg := range pl.Segments {
+		seg.URI = rewriteURL(seg.URI, mt, opts)
+		if seg.Map != nil {
+			seg.Map.URI = rewriteURL(seg.Map.URI, mt, opts)
+		}
+	}
+
+	if opts.StartOver {
+		pl.Start = &hls.Start{TimeOffset: 0, Precise: true}
+	}
+	pl.Tags = append(pl.Tags, mt.InjectMediaTags...)
+}
@@ -0,0 +1,109 @@
+// Package transform rewrites the manifests ffmpeg produces on the fly, so players
+// can be tested against manifests that were modified by a CDN or are malformed.
+package transform
+
+import (
+	"math"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// Options carries request specific information needed by the transforms.
+type Options struct {
+	// BaseURL is the absolute URL of the directory the manifest is served
+	// from, e.g. http://localhost:9090/jobs/<id>/
+	BaseURL string
+	// StartOver makes players begin at the start of the DVR window instead of the live edge
+	StartOver bool
+	// Continuation splices the manifests of a restarted run onto those of the earlier runs
+	Continuation *Continuation
+}
+
+// StartOverQuery is the query string that selects the start-over variant of a manifest
+const StartOverQuery = "startover=1"
+
+// Manifest applies the transforms to an HLS playlist or a DASH MPD based on its file name.
+// Files that are not manifests are returned unchanged.
+func Manifest(name string, data []byte, mt *models.ManifestTransforms, opts Options) ([]byte, error) {
+	if mt == nil {
+		if !opts.StartOver && opts.Continuation == nil {
+			return data, nil
+		}
+		mt = &models.ManifestTransforms{}
+	}
+	switch {
+	case strings.HasSuffix(name, ".m3u8"):
+		return HLS(data, mt, opts)
+	case strings.HasSuffix(name, ".mpd"):
+		return DASH(data, mt, opts)
+	}
+	return data, nil
+}
+
+// rewriteURL applies the absolute_urls and url_prefix transforms to a URL
+// relative to the manifest.
+func rewriteURL(uri string, mt *models.ManifestTransforms, opts Options) string {
+	if u, err := url.Parse(uri); err != nil || u.IsAbs() {
+		return uri
+	}
+	switch {
+	case mt.URLPrefix != "":
+		return joinURL(mt.URLPrefix, uri)
+	case mt.AbsoluteURLs && opts.BaseURL != "":
+		return joinURL(opts.BaseURL, uri)
+	}
+	return uri
+}
+
+// startOverURL carries the start-over selection over to the media playlists.
+func startOverURL(uri string, opts Options) string {
+	if !opts.StartOver {
+		return uri
+	}
+	if strings.Contains(uri, "?") {
+		return uri + "&" + StartOverQuery
+	}
+	return uri + "?" + StartOverQuery
+}
+
+// baseURL returns the URL relative references should resolve against, if any.
+func baseURL(mt *models.ManifestTransforms, opts Options) string {
+	switch {
+	case mt.URLPrefix != "":
+		return strings.TrimSuffix(mt.URLPrefix, "/") + "/"
+	case mt.AbsoluteURLs && opts.BaseURL != "":
+		return strings.TrimSuffix(opts.BaseURL, "/") + "/"
+	}
+	return ""
+}
+
+func joinURL(base, ref string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	u.Path = path.Join(u.Path, ref)
+	return u.String()
+}
+
+// dropped reports whether a rendition matches one of the drop_renditions entries.
+func dropped(mt *models.ManifestTransforms, ids ...string) bool {
+	for _, drop := range mt.DropRenditions {
+		for _, id := range ids {
+			if id != "" && drop == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func scaleBandwidth(bandwidth int64, mt *models.ManifestTransforms) int64 {
+	if mt.BandwidthScale == 0 || bandwidth == 0 {
+		return bandwidth
+	}
+	return int64(math.Round(float64(bandwidth) * mt.BandwidthScale))
+}
@@ -0,0 +1,271 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/pkg/dash"
+	"github.com/arunjeyaprasad/golive/pkg/hls"
+)
+
+const masterPlaylist = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="group_A1",NAME="audio_0",DEFAULT=YES,URI="media_2.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1144000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",AUDIO="group_A1"
+media_0.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=3144000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",AUDIO="group_A1"
+media_1.m3u8
+`
+
+const mediaPlaylist = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:3
+#EXT-X-MAP:URI="init-stream0.m4s"
+#EXTINF:6.000000,
+chunk-stream0-00003.m4s
+#EXTINF:6.000000,
+chunk-stream0-00004.m4s
+#EXTINF:6.000000,
+chunk-stream0-00005.m4s
+`
+
+const manifest = `<?xml version="1.0" encoding="utf-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011" type="dynamic" availabilityStartTime="2025-06-07T14:33:02Z" timeShiftBufferDepth="PT18.0S" maxSegmentDuration="PT6.0S" minBufferTime="PT12.0S">
+	<Period id="0" start="PT0.0S">
+		<AdaptationSet id="0" contentType="video">
+			<Representation id="0" mimeType="video/mp4" codecs="avc1.64001f" bandwidth="1000000" width="1280" height="720">
+				<SegmentTemplate timescale="15360" initialization="init-stream$RepresentationID$.m4s" media="chunk-stream$RepresentationID$-$Number%05d$.m4s" startNumber="3">
+					<SegmentTimeline>
+						<S t="184320" d="92160" r="2" />
+					</SegmentTimeline>
+				</SegmentTemplate>
+			</Representation>
+		</AdaptationSet>
+		<AdaptationSet id="1" contentType="audio">
+			<Representation id="1" mimeType="audio/mp4" codecs="mp4a.40.2" bandwidth="128000">
+				<SegmentTemplate timescale="44100" initialization="init-stream$RepresentationID$.m4s" media="chunk-stream$RepresentationID$-$Number%05d$.m4s" startNumber="3">
+					<SegmentTimeline>
+						<S t="529200" d="264600" r="2" />
+					</SegmentTimeline>
+				</SegmentTemplate>
+			</Representation>
+		</AdaptationSet>
+	</Period>
+</MPD>
+`
+
+func TestHLS_Master(t *testing.T) {
+	mt := &models.ManifestTransforms{
+		DropRenditions:   []string{"2"},
+		ReorderVariants:  models.VariantOrderBandwidthDesc,
+		URLPrefix:        "https://cdn.example.com/live/",
+		InjectMasterTags: []string{`#EXT-X-SESSION-DATA:DATA-ID="com.golive.test",VALUE="1"`},
+		BandwidthScale:   2,
+	}
+	out, err := HLS([]byte(masterPlaylist), mt, Options{})
+	if err != nil {
+		t.Fatalf("HLS() error = %v", err)
+	}
+	pl, err := hls.DecodeMaster(out)
+	if err != nil {
+		t.Fatalf("DecodeMaster() error = %v\n%s", err, out)
+	}
+	if len(pl.Renditions) != 0 {
+		t.Errorf("renditions = %d, want the audio rendition dropped", len(pl.Renditions))
+	}
+	if len(pl.Variants) != 2 {
+		t.Fatalf("variants = %d, want 2", len(pl.Variants))
+	}
+	if _, ok := pl.Variants[0].Attributes.Get("AUDIO"); ok {
+		t.Errorf("variant still references the dropped audio group")
+	}
+	if pl.Variants[0].URI != "https://cdn.example.com/live/media_1.m3u8" {
+		t.Errorf("first variant URI = %v, want the 1080p variant on the CDN", pl.Variants[0].URI)
+	}
+	if pl.Variants[0].Bandwidth() != 6288000 || pl.Variants[1].Bandwidth() != 2288000 {
+		t.Errorf("bandwidths = %d, %d, want 6288000, 2288000", pl.Variants[0].Bandwidth(), pl.Variants[1].Bandwidth())
+	}
+	if !strings.Contains(string(out), mt.InjectMasterTags[0]) {
+		t.Errorf("HLS() output is missing the injected tag\n%s", out)
+	}
+}
+
+func TestHLS_Media(t *testing.T) {
+	mt := &models.ManifestTransforms{
+		AbsoluteURLs:      true,
+		InjectMediaTags:   []string{"#EXT-X-GOLIVE-TEST:1"},
+		DVRWindowSegments: 2,
+		TargetDuration:    4,
+	}
+	out, err := HLS([]byte(mediaPlaylist), mt, Options{BaseURL: "http://golive:9090/jobs/job1/"})
+	if err != nil {
+		t.Fatalf("HLS() error = %v", err)
+	}
+	pl, err := hls.DecodeMedia(out)
+	if err != nil {
+		t.Fatalf("DecodeMedia() error = %v\n%s", err, out)
+	}
+	if len(pl.Segments) != 2 || pl.MediaSequence != 4 {
+		t.Errorf("segments = %d, media sequence = %d, want 2 and 4", len(pl.Segments), pl.MediaSequence)
+	}
+	if pl.TargetDuration != 4 {
+		t.Errorf("target duration = %d, want 4", pl.TargetDuration)
+	}
+	if pl.Map == nil || pl.Map.URI != "http://golive:9090/jobs/job1/init-stream0.m4s" {
+		t.Errorf("map = %+v, want an absolute URI", pl.Map)
+	}
+	if pl.Segments[0].URI != "http://golive:9090/jobs/job1/chunk-stream0-00004.m4s" {
+		t.Errorf("first segment = %v, want an absolute URI", pl.Segments[0].URI)
+	}
+	if len(pl.Tags) != 1 || pl.Tags[0] != "#EXT-X-GOLIVE-TEST:1" {
+		t.Errorf("tags = %v, want the injected tag", pl.Tags)
+	}
+}
+
+func TestDASH(t *testing.T) {
+	mt := &models.ManifestTransforms{
+		DropRenditions:    []string{"1"},
+		URLPrefix:         "https://cdn.example.com/live",
+		BandwidthScale:    0.5,
+		DVRWindowSegments: 1,
+		TargetDuration:    4,
+	}
+	out, err := DASH([]byte(manifest), mt, Options{})
+	if err != nil {
+		t.Fatalf("DASH() error = %v", err)
+	}
+	mpd, err := dash.Decode(out)
+	if err != nil {
+		t.Fatalf("Decode() error = %v\n%s", err, out)
+	}
+	if len(mpd.Periods[0].AdaptationSets) != 1 {
+		t.Fatalf("adaptation sets = %d, want the audio one dropped", len(mpd.Periods[0].AdaptationSets))
+	}
+	rep := mpd.Periods[0].AdaptationSets[0].Representations[0]
+	if rep.Bandwidth != 500000 {
+		t.Errorf("bandwidth = %d, want 500000", rep.Bandwidth)
+	}
+	if got := rep.SegmentTemplate.FirstNumber(); got != 5 {
+		t.Errorf("startNumber = %d, want 5", got)
+	}
+	if mpd.TimeShiftBufferDepth != "PT6.0S" || mpd.MaxSegmentDuration != "PT4S" {
+		t.Errorf("timeShiftBufferDepth = %s, maxSegmentDuration = %s", mpd.TimeShiftBufferDepth, mpd.MaxSegmentDuration)
+	}
+	if len(mpd.BaseURLs) != 1 || mpd.BaseURLs[0] != "https://cdn.example.com/live/" {
+		t.Errorf("BaseURLs = %v, want the CDN prefix", mpd.BaseURLs)
+	}
+}
+
+func TestStartOver(t *testing.T) {
+	out, err := Manifest("master.m3u8", []byte(masterPlaylist), nil, Options{StartOver: true})
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	master, err := hls.DecodeMaster(out)
+	if err != nil {
+		t.Fatalf("DecodeMaster() error = %v\n%s", err, out)
+	}
+	if master.Start == nil || master.Start.TimeOffset != 0 || !master.Start.Precise {
+		t.Errorf("master start = %+v, want TIME-OFFSET=0,PRECISE=YES", master.Start)
+	}
+	if master.Variants[0].URI != "media_0.m3u8?startover=1" || master.Renditions[0].URI() != "media_2.m3u8?startover=1" {
+		t.Errorf("media playlist URIs = %v, %v, want the start-over query carried over", master.Variants[0].URI, master.Renditions[0].URI())
+	}
+
+	out, err = Manifest("media_0.m3u8", []byte(mediaPlaylist), nil, Options{StartOver: true})
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	if !strings.Contains(string(out), "#EXT-X-START:TIME-OFFSET=0,PRECISE=YES") {
+		t.Errorf("media playlist is missing EXT-X-START\n%s", out)
+	}
+
+	out, err = Manifest("manifest.mpd", []byte(manifest), nil, Options{StartOver: true})
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	mpd, err := dash.Decode(out)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if mpd.SuggestedPresentationDelay != "PT18.0S" {
+		t.Errorf("suggestedPresentationDelay = %v, want the timeshift buffer depth", mpd.SuggestedPresentationDelay)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	data := []byte{0, 1, 2}
+	mt := &models.ManifestTransforms{TargetDuration: 4}
+	if out, err := Manifest("chunk-stream0-00001.m4s", data, mt, Options{}); err != nil || string(out) != string(data) {
+		t.Errorf("Manifest() should leave segments untouched, got %v, %v", out, err)
+	}
+	if out, err := Manifest("media_0.m3u8", []byte(mediaPlaylist), nil, Options{}); err != nil || string(out) != mediaPlaylist {
+		t.Errorf("Manifest() without transforms should return the playlist unchanged, got %v, %v", out, err)
+	}
+	if _, err := Manifest("media_0.m3u8", []byte("not a playlist"), mt, Options{}); err == nil {
+		t.Errorf("Manifest() error = nil, want an error for an invalid playlist")
+	}
+}
+
+func TestContinuation(t *testing.T) {
+	c := &Continuation{
+		Dir:                   "runs/3/",
+		Period:                "3",
+		AvailabilityStartTime: "2025-06-07T14:30:00Z",
+		MediaSequence:         40,
+		Discontinuities:       2,
+	}
+	tests := []struct {
+		name              string
+		mediaSequence     string
+		wantSequence      int64
+		wantDiscontinuity int64
+		wantTag           bool
+	}{
+		{name: "First segment listed", mediaSequence: "1", wantSequence: 41, wantDiscontinuity: 1, wantTag: true},
+		{name: "First segment gone", mediaSequence: "3", wantSequence: 43, wantDiscontinuity: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := strings.Replace(mediaPlaylist, "MEDIA-SEQUENCE:3", "MEDIA-SEQUENCE:"+tt.mediaSequence, 1)
+			out, err := Manifest("media_0.m3u8", []byte(data), nil, Options{Continuation: c})
+			if err != nil {
+				t.Fatalf("Manifest() error = %v", err)
+			}
+			pl, err := hls.DecodeMedia(out)
+			if err != nil {
+				t.Fatalf("DecodeMedia() error = %v\n%s", err, out)
+			}
+			if pl.MediaSequence != tt.wantSequence || pl.DiscontinuitySequence != tt.wantDiscontinuity {
+				t.Errorf("sequences = %d, %d, want %d, %d", pl.MediaSequence, pl.DiscontinuitySequence, tt.wantSequence, tt.wantDiscontinuity)
+			}
+			if pl.Segments[0].Discontinuity != tt.wantTag {
+				t.Errorf("first segment discontinuity = %v, want %v", pl.Segments[0].Discontinuity, tt.wantTag)
+			}
+			if pl.Map.URI != "runs/3/init-stream0.m4s" || pl.Segments[0].URI != "runs/3/chunk-stream0-00003.m4s" {
+				t.Errorf("URIs = %s, %s, want them in the directory of the run", pl.Map.URI, pl.Segments[0].URI)
+			}
+		})
+	}
+
+	out, err := Manifest("manifest.mpd", []byte(manifest), nil, Options{Continuation: c})
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	mpd, err := dash.Decode(out)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if mpd.AvailabilityStartTime != c.AvailabilityStartTime {
+		t.Errorf("availabilityStartTime = %s, want the one of the first run", mpd.AvailabilityStartTime)
+	}
+	period := mpd.Periods[0]
+	if period.ID != "3" || period.Start != "PT3M2.0S" {
+		t.Errorf("period = %s starting at %s, want 3 starting at PT3M2.0S", period.ID, period.Start)
+	}
+	if len(period.BaseURLs) != 1 || period.BaseURLs[0] != "runs/3/" {
+		t.Errorf("period BaseURLs = %v, want the directory of the run", period.BaseURLs)
+	}
+}
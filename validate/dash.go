@@ -0,0 +1,108 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/pkg/dash"
+)
+
+const dashManifest = "manifest.mpd"
+
+func (v *Validator) checkDASH(grace bool) []models.HealthIssue {
+	data, err := os.ReadFile(filepath.Join(v.dir, dashManifest))
+	if err != nil {
+		if grace {
+			return nil
+		}
+		return []models.HealthIssue{newIssue(CheckManifest, models.HealthSeverityError, dashManifest, "failed to read manifest: %v", err)}
+	}
+	manifest, err := dash.Decode(data)
+	if err != nil {
+		return []models.HealthIssue{newIssue(CheckManifest, models.HealthSeverityError, dashManifest, "failed to parse manifest: %v", err)}
+	}
+
+	var issues []models.HealthIssue
+	issues = append(issues, v.checkAvailabilityStartTime(manifest)...)
+	for _, period := range manifest.Periods {
+		for _, as := range period.AdaptationSets {
+			for _, rep := range as.Representations {
+				tmpl := as.TemplateFor(rep)
+				if tmpl == nil {
+					issues = append(issues, newIssue(CheckManifest, models.HealthSeverityError, dashManifest,
+						"representation %s has no SegmentTemplate", rep.ID))
+					continue
+				}
+				issues = append(issues, v.checkRepresentation(rep, tmpl)...)
+			}
+		}
+	}
+	return issues
+}
+
+func (v *Validator) checkAvailabilityStartTime(manifest *dash.MPD) []models.HealthIssue {
+	if !manifest.IsDynamic() {
+		return nil
+	}
+	if manifest.AvailabilityStartTime == "" {
+		return []models.HealthIssue{newIssue(CheckAvailabilityStartTime, models.HealthSeverityError, dashManifest,
+			"dynamic MPD has no availabilityStartTime")}
+	}
+	ast, err := time.Parse(time.RFC3339Nano, manifest.AvailabilityStartTime)
+	if err != nil {
+		return []models.HealthIssue{newIssue(CheckAvailabilityStartTime, models.HealthSeverityError, dashManifest,
+			"availabilityStartTime %q is not a valid date time", manifest.AvailabilityStartTime)}
+	}
+	// Allow for a little clock skew between ffmpeg and the server
+	const skew = 5 * time.Second
+	if ast.After(time.Now().Add(skew)) {
+		return []models.HealthIssue{newIssue(CheckAvailabilityStartTime, models.HealthSeverityError, dashManifest,
+			"availabilityStartTime %s is in the future", manifest.AvailabilityStartTime)}
+	}
+	if !v.startedAt.IsZero() && ast.Before(v.startedAt.Add(-time.Minute)) {
+		return []models.HealthIssue{newIssue(CheckAvailabilityStartTime, models.HealthSeverityWarning, dashManifest,
+			"availabilityStartTime %s is well before the stream was started", manifest.AvailabilityStartTime)}
+	}
+	return nil
+}
+
+func (v *Validator) checkRepresentation(rep *dash.Representation, tmpl *dash.SegmentTemplate) []models.HealthIssue {
+	var issues []models.HealthIssue
+	issues = append(issues, v.checkSequence(dashManifest+"#"+rep.ID, dashManifest, tmpl.FirstNumber())...)
+
+	if tmpl.Initialization != "" {
+		name := dash.ExpandTemplate(tmpl.Initialization, rep, 0, 0)
+		if !v.exists(name) {
+			issues = append(issues, newIssue(CheckMissingSegment, models.HealthSeverityError, dashManifest,
+				"initialization segment %s does not exist", name))
+		}
+	}
+
+	timescale := float64(tmpl.GetTimescale())
+	var next uint64
+	for i, seg := range tmpl.Segments() {
+		if i > 0 && seg.Time != next {
+			kind := "gap"
+			if seg.Time < next {
+				kind = "overlap"
+			}
+			issues = append(issues, newIssue(CheckTimelineGap, models.HealthSeverityError, dashManifest,
+				"representation %s has a timeline %s: expected t=%d, found t=%d", rep.ID, kind, next, seg.Time))
+		}
+		next = seg.Time + seg.Duration
+
+		duration := float64(seg.Duration) / timescale
+		if v.segmentLength > 0 && duration > float64(v.segmentLength)*1.5 {
+			issues = append(issues, newIssue(CheckSegmentDuration, models.HealthSeverityWarning, dashManifest,
+				"representation %s segment %d lasts %.3fs, configured segment length is %ds", rep.ID, seg.Number, duration, v.segmentLength))
+		}
+		name := dash.ExpandTemplate(tmpl.Media, rep, seg.Number, seg.Time)
+		if !v.exists(name) {
+			issues = append(issues, newIssue(CheckMissingSegment, models.HealthSeverityError, dashManifest,
+				"segment %s does not exist", name))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,99 @@
+package validate
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/arunjeyaprasad/golive/pkg/hls"
+)
+
+const hlsMasterPlaylist = "master.m3u8"
+
+func (v *Validator) checkHLS(grace bool) []models.HealthIssue {
+	data, err := os.ReadFile(filepath.Join(v.dir, hlsMasterPlaylist))
+	if err != nil {
+		if grace {
+			return nil
+		}
+		return []models.HealthIssue{newIssue(CheckManifest, models.HealthSeverityError, hlsMasterPlaylist, "failed to read playlist: %v", err)}
+	}
+	master, err := hls.DecodeMaster(data)
+	if err != nil {
+		return []models.HealthIssue{newIssue(CheckManifest, models.HealthSeverityError, hlsMasterPlaylist, "failed to parse playlist: %v", err)}
+	}
+	uris := master.PlaylistURIs()
+	if len(uris) == 0 {
+		return []models.HealthIssue{newIssue(CheckManifest, models.HealthSeverityError, hlsMasterPlaylist, "master playlist references no media playlists")}
+	}
+
+	var issues []models.HealthIssue
+	for _, uri := range uris {
+		issues = append(issues, v.checkMediaPlaylist(uri, grace)...)
+	}
+	return issues
+}
+
+func (v *Validator) checkMediaPlaylist(name string, grace bool) []models.HealthIssue {
+	data, err := os.ReadFile(filepath.Join(v.dir, name))
+	if err != nil {
+		if grace {
+			return nil
+		}
+		return []models.HealthIssue{newIssue(CheckManifest, models.HealthSeverityError, name, "failed to read playlist: %v", err)}
+	}
+	pl, err := hls.DecodeMedia(data)
+	if err != nil {
+		return []models.HealthIssue{newIssue(CheckManifest, models.HealthSeverityError, name, "failed to parse playlist: %v", err)}
+	}
+
+	var issues []models.HealthIssue
+	if pl.TargetDuration <= 0 {
+		issues = append(issues, newIssue(CheckTargetDuration, models.HealthSeverityError, name, "EXT-X-TARGETDURATION is missing"))
+	} else if pl.TargetDuration > v.segmentLength+1 {
+		issues = append(issues, newIssue(CheckTargetDuration, models.HealthSeverityWarning, name,
+			"target duration %ds is larger than the configured segment length %ds", pl.TargetDuration, v.segmentLength))
+	}
+	for _, segment := range pl.Segments {
+		// The EXTINF duration rounded to the nearest integer must not exceed the target duration
+		if pl.TargetDuration > 0 && int(math.Round(segment.Duration)) > pl.TargetDuration {
+			issues = append(issues, newIssue(CheckSegmentDuration, models.HealthSeverityError, name,
+				"segment %s lasts %.3fs which exceeds the target duration %ds", segment.URI, segment.Duration, pl.TargetDuration))
+		}
+	}
+	issues = append(issues, v.checkSequence(name, name, pl.MediaSequence)...)
+
+	if pl.Map != nil && !v.exists(pl.Map.URI) {
+		issues = append(issues, newIssue(CheckMissingSegment, models.HealthSeverityError, name, "initialization segment %s does not exist", pl.Map.URI))
+	}
+	for _, segment := range pl.Segments {
+		if segment.Map != nil && !v.exists(segment.Map.URI) {
+			issues = append(issues, newIssue(CheckMissingSegment, models.HealthSeverityError, name, "initialization segment %s does not exist", segment.Map.URI))
+		}
+		if !v.exists(segment.URI) {
+			issues = append(issues, newIssue(CheckMissingSegment, models.HealthSeverityError, name, "segment %s does not exist", segment.URI))
+		}
+	}
+	return issues
+}
+
+func (v *Validator) exists(name string) bool {
+	// Segments are referenced relative to the manifest; ignore anything absolute
+	if strings.Contains(name, "://") {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(v.dir, filepath.FromSlash(name)))
+	return err == nil
+}
+
+func newIssue(check string, severity models.HealthSeverity, file string, format string, args ...any) models.HealthIssue {
+	return models.HealthIssue{
+		Check:    check,
+		Severity: severity,
+		File:     file,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
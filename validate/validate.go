@@ -0,0 +1,132 @@
+package validate
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// Check names reported in models.HealthIssue
+const (
+	CheckManifest              = "manifest"
+	CheckTargetDuration        = "target_duration"
+	CheckSegmentDuration       = "segment_duration"
+	CheckMediaSequence         = "media_sequence"
+	CheckTimelineGap           = "timeline_gap"
+	CheckAvailabilityStartTime = "availability_start_time"
+	CheckMissingSegment        = "missing_segment"
+)
+
+// Validator periodically parses the MPD and m3u8 playlists in a job's
+// output directory and records any spec violations it finds.
+type Validator struct {
+	jobID         string
+	dir           string
+	segmentLength int
+	startedAt     time.Time
+
+	mu           sync.RWMutex
+	health       models.JobHealth
+	lastSequence map[string]int64 // Last seen media sequence / start number per playlist
+	stop         chan struct{}
+	stopOnce     sync.Once
+}
+
+func NewValidator(jobID, dir string, segmentLength int, startedAt time.Time) *Validator {
+	return &Validator{
+		jobID:         jobID,
+		dir:           dir,
+		segmentLength: segmentLength,
+		startedAt:     startedAt,
+		health: models.JobHealth{
+			ID:     jobID,
+			Status: models.HealthStatusPending,
+			Issues: []models.HealthIssue{},
+		},
+		lastSequence: make(map[string]int64),
+		stop:         make(chan struct{}),
+	}
+}
+
+// Start runs a validation pass every interval until Stop is called.
+func (v *Validator) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	slog.Info("Starting manifest validation", "jobID", v.jobID, "interval", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				v.Run()
+			case <-v.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the periodic validation. The last report stays available.
+func (v *Validator) Stop() {
+	v.stopOnce.Do(func() {
+		close(v.stop)
+	})
+}
+
+// Health returns a copy of the most recent report.
+func (v *Validator) Health() models.JobHealth {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	health := v.health
+	health.Issues = append([]models.HealthIssue{}, v.health.Issues...)
+	return health
+}
+
+// Run performs a single validation pass and updates the report.
+func (v *Validator) Run() models.JobHealth {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// Give ffmpeg a few segments worth of time before complaining about missing manifests
+	grace := time.Since(v.startedAt) < time.Duration(3*v.segmentLength)*time.Second
+
+	var issues []models.HealthIssue
+	issues = append(issues, v.checkDASH(grace)...)
+	issues = append(issues, v.checkHLS(grace)...)
+	if issues == nil {
+		issues = []models.HealthIssue{}
+	}
+
+	status := models.HealthStatusHealthy
+	for _, issue := range issues {
+		if issue.Severity == models.HealthSeverityError {
+			status = models.HealthStatusUnhealthy
+			break
+		}
+		status = models.HealthStatusDegraded
+	}
+	if len(issues) != 0 {
+		slog.Debug("Manifest validation found issues", "jobID", v.jobID, "issues", len(issues))
+	}
+	v.health.Status = status
+	v.health.CheckedAt = time.Now().Format(time.RFC3339)
+	v.health.Passes++
+	v.health.Issues = issues
+	return v.health
+}
+
+// checkSequence records the sequence number seen for a playlist and reports
+// an issue when it moved backwards since the previous pass.
+func (v *Validator) checkSequence(key, file string, sequence int64) []models.HealthIssue {
+	last, seen := v.lastSequence[key]
+	v.lastSequence[key] = sequence
+	if seen && sequence < last {
+		return []models.HealthIssue{newIssue(CheckMediaSequence, models.HealthSeverityError, file,
+			"sequence number went backwards from %d to %d", last, sequence)}
+	}
+	return nil
+}
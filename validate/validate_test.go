@@ -0,0 +1,168 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+// setupDir copies the testdata manifests into a temporary directory, creates
+// the segments they reference and applies the given edits to the manifests.
+func setupDir(t *testing.T, ast time.Time, edit func(name, content string) string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"manifest.mpd", "master.m3u8", "media_0.m3u8", "media_1.m3u8"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatalf("failed to read fixture %s: %v", name, err)
+		}
+		content := strings.ReplaceAll(string(data), "AVAILABILITY_START_TIME", ast.UTC().Format(time.RFC3339))
+		if edit != nil {
+			content = edit(name, content)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	segments := []string{"init-stream0.m4s", "init-stream1.m4s"}
+	for _, n := range []string{"00003", "00004", "00005"} {
+		segments = append(segments, "chunk-stream0-"+n+".m4s", "chunk-stream1-"+n+".m4s")
+	}
+	for _, name := range segments {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte{0}, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func checks(health models.JobHealth) map[string]bool {
+	found := make(map[string]bool)
+	for _, issue := range health.Issues {
+		found[issue.Check] = true
+	}
+	return found
+}
+
+func TestValidator_Run(t *testing.T) {
+	startedAt := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name       string
+		ast        time.Time
+		edit       func(name, content string) string
+		remove     string
+		wantStatus models.HealthStatus
+		wantCheck  string
+	}{
+		{
+			name:       "Conformant output",
+			ast:        startedAt,
+			wantStatus: models.HealthStatusHealthy,
+		},
+		{
+			name: "Segment longer than target duration",
+			ast:  startedAt,
+			edit: func(name, content string) string {
+				if name == "media_0.m3u8" {
+					return strings.Replace(content, "#EXTINF:6.000000,", "#EXTINF:7.200000,", 1)
+				}
+				return content
+			},
+			wantStatus: models.HealthStatusUnhealthy,
+			wantCheck:  CheckSegmentDuration,
+		},
+		{
+			name: "Missing target duration",
+			ast:  startedAt,
+			edit: func(name, content string) string {
+				if name == "media_1.m3u8" {
+					return strings.Replace(content, "#EXT-X-TARGETDURATION:6\n", "", 1)
+				}
+				return content
+			},
+			wantStatus: models.HealthStatusUnhealthy,
+			wantCheck:  CheckTargetDuration,
+		},
+		{
+			name: "Timeline gap",
+			ast:  startedAt,
+			edit: func(name, content string) string {
+				if name == "manifest.mpd" {
+					return strings.Replace(content, `<S d="92160" />`, `<S t="380000" d="92160" />`, 1)
+				}
+				return content
+			},
+			wantStatus: models.HealthStatusUnhealthy,
+			wantCheck:  CheckTimelineGap,
+		},
+		{
+			name:       "Availability start time in the future",
+			ast:        time.Now().Add(time.Hour),
+			wantStatus: models.HealthStatusUnhealthy,
+			wantCheck:  CheckAvailabilityStartTime,
+		},
+		{
+			name:       "Segment referenced but missing",
+			ast:        startedAt,
+			remove:     "chunk-stream1-00004.m4s",
+			wantStatus: models.HealthStatusUnhealthy,
+			wantCheck:  CheckMissingSegment,
+		},
+		{
+			name:       "Missing master playlist",
+			ast:        startedAt,
+			remove:     "master.m3u8",
+			wantStatus: models.HealthStatusUnhealthy,
+			wantCheck:  CheckManifest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupDir(t, tt.ast, tt.edit)
+			if tt.remove != "" {
+				os.Remove(filepath.Join(dir, tt.remove))
+			}
+			v := NewValidator("job1", dir, 6, startedAt)
+			got := v.Run()
+			if got.Status != tt.wantStatus {
+				t.Errorf("Run() status = %v, want %v (issues %v)", got.Status, tt.wantStatus, got.Issues)
+			}
+			if tt.wantCheck != "" && !checks(got)[tt.wantCheck] {
+				t.Errorf("Run() issues = %v, want a %s issue", got.Issues, tt.wantCheck)
+			}
+		})
+	}
+}
+
+func TestValidator_MediaSequence(t *testing.T) {
+	startedAt := time.Now().Add(-time.Minute)
+	dir := setupDir(t, startedAt, nil)
+	v := NewValidator("job1", dir, 6, startedAt)
+	if got := v.Run(); got.Status != models.HealthStatusHealthy {
+		t.Fatalf("Run() status = %v, want %v (issues %v)", got.Status, models.HealthStatusHealthy, got.Issues)
+	}
+
+	// Rewind the media sequence of one playlist
+	name := filepath.Join(dir, "media_0.m3u8")
+	data, _ := os.ReadFile(name)
+	os.WriteFile(name, []byte(strings.Replace(string(data), "#EXT-X-MEDIA-SEQUENCE:3", "#EXT-X-MEDIA-SEQUENCE:1", 1)), 0o644)
+
+	got := v.Run()
+	if !checks(got)[CheckMediaSequence] {
+		t.Errorf("Run() issues = %v, want a %s issue", got.Issues, CheckMediaSequence)
+	}
+	if got.Passes != 2 {
+		t.Errorf("Run() passes = %d, want 2", got.Passes)
+	}
+}
+
+func TestValidator_Grace(t *testing.T) {
+	v := NewValidator("job1", t.TempDir(), 6, time.Now())
+	if got := v.Run(); got.Status != models.HealthStatusHealthy {
+		t.Errorf("Run() status = %v, want %v before any manifest is due (issues %v)", got.Status, models.HealthStatusHealthy, got.Issues)
+	}
+}
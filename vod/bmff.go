@@ -0,0 +1,84 @@
+package vod
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+var errNoTfdt = errors.New("vod: segment has no tfdt box")
+
+// baseMediaDecodeTime returns the decode time of the first track fragment of
+// an fMP4 segment, read from its moof/traf/tfdt box.
+func baseMediaDecodeTime(name string) (uint64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return findTfdt(f, info.Size(), 0)
+}
+
+// findTfdt walks the boxes in [0, end) of r, descending into moof and traf.
+func findTfdt(r io.ReadSeeker, end int64, depth int) (uint64, error) {
+	for {
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+		if pos+8 > end {
+			return 0, errNoTfdt
+		}
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return 0, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		boxType := string(header[4:])
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = end - pos // Box extends to the end of its parent
+		case 1:
+			var large [8]byte
+			if _, err := io.ReadFull(r, large[:]); err != nil {
+				return 0, err
+			}
+			size = int64(binary.BigEndian.Uint64(large[:]))
+			headerSize = 16
+		}
+		if size < headerSize || pos+size > end {
+			return 0, errors.New("vod: malformed box " + boxType)
+		}
+
+		switch {
+		case boxType == "moof" && depth == 0, boxType == "traf" && depth == 1:
+			return findTfdt(r, pos+size, depth+1)
+		case boxType == "tfdt" && depth == 2:
+			var version [4]byte
+			if _, err := io.ReadFull(r, version[:]); err != nil {
+				return 0, err
+			}
+			if version[0] == 1 {
+				var t [8]byte
+				if _, err := io.ReadFull(r, t[:]); err != nil {
+					return 0, err
+				}
+				return binary.BigEndian.Uint64(t[:]), nil
+			}
+			var t [4]byte
+			if _, err := io.ReadFull(r, t[:]); err != nil {
+				return 0, err
+			}
+			return uint64(binary.BigEndian.Uint32(t[:])), nil
+		}
+		if _, err := r.Seek(pos+size, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+}
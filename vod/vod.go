@@ -0,0 +1,267 @@
+// Package vod turns the recording of a stopped live job into a VOD asset.
+package vod
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/pkg/dash"
+	"github.com/arunjeyaprasad/golive/pkg/hls"
+)
+
+// Names of the manifests Finalize writes next to the live ones
+const (
+	MPDFile    = "vod.mpd"
+	MasterFile = "vod.m3u8"
+)
+
+var mediaPlaylistIndex = regexp.MustCompile(`^media_(\d+)\.m3u8$`)
+
+// Finalize writes a static MPD and VOD HLS playlists covering every segment
+// recorded in dir. The live manifests only describe the last window, so the
+// segments are enumerated from disk. Segments older than since belong to an
+// earlier run of the job and are ignored.
+func Finalize(dir string, segmentLength int, since time.Time) error {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.mpd"))
+	if err != nil {
+		return err
+	}
+	mpd, err := dash.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	var longest float64
+	recordings := make(map[string]*recording)
+	for _, period := range mpd.Periods {
+		for _, as := range period.AdaptationSets {
+			for _, rep := range as.Representations {
+				tmpl := as.TemplateFor(rep)
+				if tmpl == nil {
+					continue
+				}
+				// Every representation gets its own timeline
+				if rep.SegmentTemplate == nil {
+					copied := *tmpl
+					rep.SegmentTemplate = &copied
+					tmpl = &copied
+				}
+				rec := record(dir, rep, tmpl, segmentLength, since)
+				if len(rec.segments) == 0 {
+					continue
+				}
+				tmpl.SetSegments(rec.segments)
+				tmpl.PresentationTimeOffset = rec.segments[0].Time
+				recordings[rep.ID] = rec
+				if d := rec.duration(); d > longest {
+					longest = d
+				}
+			}
+			as.SegmentTemplate = nil
+		}
+	}
+	if len(recordings) == 0 {
+		return errors.New("vod: no recorded segments found")
+	}
+
+	mpd.Type = dash.TypeStatic
+	mpd.MediaPresentationDuration = dash.FormatDuration(time.Duration(longest * float64(time.Second)))
+	mpd.AvailabilityStartTime = ""
+	mpd.PublishTime = ""
+	mpd.MinimumUpdatePeriod = ""
+	mpd.TimeShiftBufferDepth = ""
+	mpd.SuggestedPresentationDelay = ""
+	mpd.UTCTimings = nil
+	out, err := mpd.Encode()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, MPDFile), out, 0644); err != nil {
+		return err
+	}
+
+	return finalizeHLS(dir, recordings)
+}
+
+// finalizeHLS writes a VOD media playlist per representation referenced by
+// the live master playlist, and a master playlist pointing at them.
+func finalizeHLS(dir string, recordings map[string]*recording) error {
+	data, err := os.ReadFile(filepath.Join(dir, "master.m3u8"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // HLS output is disabled
+	} else if err != nil {
+		return err
+	}
+	master, err := hls.DecodeMaster(data)
+	if err != nil {
+		return err
+	}
+
+	var werr error
+	rename := func(uri string) string {
+		match := mediaPlaylistIndex.FindStringSubmatch(uri)
+		if match == nil {
+			return uri
+		}
+		rec, ok := recordings[match[1]]
+		if !ok {
+			return uri
+		}
+		name := "vod_" + uri
+		if werr == nil {
+			werr = writeMediaPlaylist(dir, uri, name, rec)
+		}
+		return name
+	}
+	for _, r := range master.Renditions {
+		if uri := r.URI(); uri != "" {
+			r.SetURI(rename(uri))
+		}
+	}
+	for _, v := range master.Variants {
+		v.URI = rename(v.URI)
+	}
+	if werr != nil {
+		return werr
+	}
+	return os.WriteFile(filepath.Join(dir, MasterFile), master.Encode(), 0644)
+}
+
+func writeMediaPlaylist(dir, live, name string, rec *recording) error {
+	pl := &hls.MediaPlaylist{
+		Version:       7,
+		MediaSequence: rec.segments[0].Number,
+		PlaylistType:  hls.PlaylistTypeVOD,
+		EndList:       true,
+	}
+	// Carry over the header of the live playlist
+	if data, err := os.ReadFile(filepath.Join(dir, live)); err == nil {
+		if livePl, err := hls.DecodeMedia(data); err == nil {
+			pl.Version = livePl.Version
+			pl.IndependentSegments = livePl.IndependentSegments
+			pl.Map = livePl.Map
+		}
+	}
+	if pl.Map == nil && rec.tmpl.Initialization != "" {
+		pl.Map = &hls.Map{URI: dash.ExpandTemplate(rec.tmpl.Initialization, rec.rep, 0, 0)}
+	}
+
+	timescale := float64(rec.tmpl.GetTimescale())
+	for _, seg := range rec.segments {
+		duration := float64(seg.Duration) / timescale
+		if d := int(math.Round(duration)); d > pl.TargetDuration {
+			pl.TargetDuration = d
+		}
+		pl.Segments = append(pl.Segments, &hls.Segment{
+			URI:      rec.uri(seg),
+			Duration: duration,
+		})
+	}
+	return os.WriteFile(filepath.Join(dir, name), pl.Encode(), 0644)
+}
+
+// recording is the full list of segments of one representation.
+type recording struct {
+	rep      *dash.Representation
+	tmpl     *dash.SegmentTemplate
+	segments []dash.Segment
+}
+
+func (rec *recording) uri(seg dash.Segment) string {
+	return dash.ExpandTemplate(rec.tmpl.Media, rec.rep, seg.Number, seg.Time)
+}
+
+// duration returns the length of the recording in seconds.
+func (rec *recording) duration() float64 {
+	var total uint64
+	for _, seg := range rec.segments {
+		total += seg.Duration
+	}
+	return float64(total) / float64(rec.tmpl.GetTimescale())
+}
+
+// record enumerates the segments of a representation on disk. Segments still
+// in the live timeline keep their timing, the duration of older ones is taken
+// from the decode times of consecutive segments, falling back to the nominal
+// segment length.
+func record(dir string, rep *dash.Representation, tmpl *dash.SegmentTemplate, segmentLength int, since time.Time) *recording {
+	rec := &recording{rep: rep, tmpl: tmpl}
+	timeline := tmpl.Segments()
+	if !strings.Contains(tmpl.Media, "$Number") {
+		// Only numbered segments can be enumerated, keep what the timeline lists
+		rec.segments = timeline
+		return rec
+	}
+	known := make(map[int64]dash.Segment, len(timeline))
+	for _, seg := range timeline {
+		known[seg.Number] = seg
+	}
+
+	exists := func(number int64) bool {
+		info, err := os.Stat(filepath.Join(dir, dash.ExpandTemplate(tmpl.Media, rep, number, 0)))
+		return err == nil && !info.ModTime().Before(since)
+	}
+	first := tmpl.FirstNumber()
+	for first > 0 && exists(first-1) {
+		first--
+	}
+	var numbers []int64
+	for n := first; exists(n) || known[n].Duration > 0; n++ {
+		numbers = append(numbers, n)
+	}
+	if len(numbers) == 0 {
+		return rec
+	}
+
+	decodeTime := func(number int64) (uint64, bool) {
+		t, err := baseMediaDecodeTime(filepath.Join(dir, dash.ExpandTemplate(tmpl.Media, rep, number, 0)))
+		return t, err == nil
+	}
+	nominal := uint64(segmentLength) * tmpl.GetTimescale()
+	durations := make([]uint64, len(numbers))
+	next, hasNext := uint64(0), false
+	for i := len(numbers) - 1; i >= 0; i-- {
+		t, ok := decodeTime(numbers[i])
+		switch {
+		case known[numbers[i]].Duration > 0:
+			durations[i] = known[numbers[i]].Duration
+		case ok && hasNext && next > t:
+			durations[i] = next - t
+		default:
+			durations[i] = nominal
+		}
+		next, hasNext = t, ok
+	}
+
+	// Anchor the start times on the first segment of the live timeline
+	var start uint64
+	for i, n := range numbers {
+		seg, ok := known[n]
+		if !ok {
+			continue
+		}
+		start = seg.Time
+		for _, d := range durations[:i] {
+			if start < d {
+				start = 0
+				break
+			}
+			start -= d
+		}
+		break
+	}
+	t := start
+	for i, n := range numbers {
+		if seg, ok := known[n]; ok && seg.Time >= t {
+			t = seg.Time // Keep gaps of the live timeline
+		}
+		rec.segments = append(rec.segments, dash.Segment{Number: n, Time: t, Duration: durations[i]})
+		t += durations[i]
+	}
+	return rec
+}
@@ -0,0 +1,203 @@
+package vod
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/pkg/dash"
+	"github.com/arunjeyaprasad/golive/pkg/hls"
+)
+
+// The live manifests only list the last two of the four recorded segments
+const liveManifest = `<?xml version="1.0" encoding="utf-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011" type="dynamic" availabilityStartTime="2025-06-07T14:33:02Z" minimumUpdatePeriod="PT6S" timeShiftBufferDepth="PT12.0S" minBufferTime="PT12.0S">
+	<Period id="0" start="PT0.0S">
+		<AdaptationSet id="0" contentType="video">
+			<Representation id="0" mimeType="video/mp4" codecs="avc1.64001f" bandwidth="1000000" width="1280" height="720">
+				<SegmentTemplate timescale="1000" initialization="init-stream$RepresentationID$.m4s" media="chunk-stream$RepresentationID$-$Number%05d$.m4s" startNumber="3">
+					<SegmentTimeline>
+						<S t="12000" d="6000" r="1" />
+					</SegmentTimeline>
+				</SegmentTemplate>
+			</Representation>
+		</AdaptationSet>
+	</Period>
+	<UTCTiming schemeIdUri="urn:mpeg:dash:utc:http-xsdate:2014" value="https://time.akamai.com/?iso" />
+</MPD>
+`
+
+const liveMaster = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-STREAM-INF:BANDWIDTH=1144000,RESOLUTION=1280x720,CODECS="avc1.64001f"
+media_0.m3u8
+`
+
+const liveMedia = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:3
+#EXT-X-MAP:URI="init-stream0.m4s"
+#EXTINF:6.000000,
+chunk-stream0-00003.m4s
+#EXTINF:6.000000,
+chunk-stream0-00004.m4s
+`
+
+// box builds an ISO BMFF box.
+func box(boxType string, payload ...[]byte) []byte {
+	size := 8
+	for _, p := range payload {
+		size += len(p)
+	}
+	out := binary.BigEndian.AppendUint32(nil, uint32(size))
+	out = append(out, boxType...)
+	for _, p := range payload {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// segment builds a minimal fMP4 segment whose fragment starts at t.
+func segment(t uint64, version byte) []byte {
+	tfdt := []byte{version, 0, 0, 0}
+	if version == 1 {
+		tfdt = binary.BigEndian.AppendUint64(tfdt, t)
+	} else {
+		tfdt = binary.BigEndian.AppendUint32(tfdt, uint32(t))
+	}
+	return append(box("styp", []byte("msdh")),
+		append(box("moof", box("mfhd", make([]byte, 8)), box("traf", box("tfhd", make([]byte, 8)), box("tfdt", tfdt))),
+			box("mdat", make([]byte, 16))...)...)
+}
+
+func TestBaseMediaDecodeTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    uint64
+		wantErr bool
+	}{
+		{name: "version 0", data: segment(92160, 0), want: 92160},
+		{name: "version 1", data: segment(1<<40, 1), want: 1 << 40},
+		{name: "no fragment", data: box("ftyp", []byte("isom")), wantErr: true},
+		{name: "truncated", data: segment(92160, 0)[:30], wantErr: true},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(dir, "segment.m4s")
+			if err := os.WriteFile(name, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := baseMediaDecodeTime(name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("baseMediaDecodeTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("baseMediaDecodeTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinalize(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"manifest.mpd":            []byte(liveManifest),
+		"master.m3u8":             []byte(liveMaster),
+		"media_0.m3u8":            []byte(liveMedia),
+		"init-stream0.m4s":        box("ftyp", []byte("isom")),
+		"chunk-stream0-00001.m4s": segment(0, 0),
+		"chunk-stream0-00002.m4s": segment(6500, 0),
+		"chunk-stream0-00003.m4s": segment(12000, 0),
+		"chunk-stream0-00004.m4s": segment(18000, 0),
+		"chunk-stream0-00005.m4s": segment(24000, 0), // Left over from an earlier run
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	since := time.Now().Add(-time.Minute)
+	stale := since.Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "chunk-stream0-00005.m4s"), stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Finalize(dir, 6, since); err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, MPDFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mpd, err := dash.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v\n%s", err, data)
+	}
+	if mpd.IsDynamic() || mpd.MinimumUpdatePeriod != "" || mpd.TimeShiftBufferDepth != "" {
+		t.Errorf("MPD is still live: type = %v, minimumUpdatePeriod = %v, timeShiftBufferDepth = %v", mpd.Type, mpd.MinimumUpdatePeriod, mpd.TimeShiftBufferDepth)
+	}
+	if mpd.MediaPresentationDuration != "PT24.0S" {
+		t.Errorf("mediaPresentationDuration = %v, want PT24.0S", mpd.MediaPresentationDuration)
+	}
+	tmpl := mpd.Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate
+	want := []dash.Segment{
+		{Number: 1, Time: 0, Duration: 6500},
+		{Number: 2, Time: 6500, Duration: 5500},
+		{Number: 3, Time: 12000, Duration: 6000},
+		{Number: 4, Time: 18000, Duration: 6000},
+	}
+	got := tmpl.Segments()
+	if len(got) != len(want) {
+		t.Fatalf("segments = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("segment %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, MasterFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, err := hls.DecodeMaster(data)
+	if err != nil {
+		t.Fatalf("DecodeMaster() error = %v\n%s", err, data)
+	}
+	if master.Variants[0].URI != "vod_media_0.m3u8" {
+		t.Errorf("variant URI = %v, want vod_media_0.m3u8", master.Variants[0].URI)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, "vod_media_0.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl, err := hls.DecodeMedia(data)
+	if err != nil {
+		t.Fatalf("DecodeMedia() error = %v\n%s", err, data)
+	}
+	if pl.PlaylistType != hls.PlaylistTypeVOD || !pl.EndList {
+		t.Errorf("playlist type = %v, endlist = %v, want a VOD playlist", pl.PlaylistType, pl.EndList)
+	}
+	if pl.MediaSequence != 1 || pl.TargetDuration != 7 || len(pl.Segments) != 4 {
+		t.Errorf("media sequence = %d, target duration = %d, segments = %d, want 1, 7, 4", pl.MediaSequence, pl.TargetDuration, len(pl.Segments))
+	}
+	if pl.Map == nil || pl.Map.URI != "init-stream0.m4s" {
+		t.Errorf("map = %+v, want init-stream0.m4s", pl.Map)
+	}
+	if pl.Segments[0].URI != "chunk-stream0-00001.m4s" || pl.Segments[1].Duration != 5.5 {
+		t.Errorf("first segments = %+v, %+v", pl.Segments[0], pl.Segments[1])
+	}
+}
+
+func TestFinalize_NoRecording(t *testing.T) {
+	if err := Finalize(t.TempDir(), 6, time.Time{}); err == nil {
+		t.Errorf("Finalize() error = nil, want an error without a manifest")
+	}
+}
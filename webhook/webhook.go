@@ -0,0 +1,137 @@
+// Package webhook delivers job lifecycle events to HTTP receivers.
+//
+// Every delivery is a JSON models.WebhookPayload POSTed to the receiver. When
+// the webhook has a secret, the body is signed with HMAC-SHA256 and the hex
+// digest is sent as "sha256=<digest>" in the X-Golive-Signature header.
+// Deliveries that fail with a network error, a 5xx, 408 or 429 response are
+// retried with exponential backoff. Events are delivered concurrently, so
+// receivers should order them by their timestamp.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+	"github.com/google/uuid"
+)
+
+const (
+	SignatureHeader = "X-Golive-Signature"
+	EventHeader     = "X-Golive-Event"
+	DeliveryHeader  = "X-Golive-Delivery"
+)
+
+var (
+	client = &http.Client{Timeout: 10 * time.Second}
+	// backoff is the delay before the first retry, doubled for every further attempt
+	backoff = time.Second
+)
+
+// Sign returns the signature header value for a body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether a signature header value matches the body.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Notify delivers an event about a job to the global webhooks and the job's
+// own webhooks in the background.
+func Notify(event models.WebhookEvent, job models.Job) {
+	hooks := receivers(event, job)
+	if len(hooks) == 0 {
+		return
+	}
+	payload := models.WebhookPayload{
+		ID:        uuid.NewString(),
+		Event:     event,
+		JobID:     job.ID,
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Job:       job,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("Failed to encode webhook payload", "jobID", job.ID, "event", event, "error", err)
+		return
+	}
+	for _, wh := range hooks {
+		go deliver(wh, event, payload.ID, body)
+	}
+}
+
+// receivers returns the webhooks subscribed to an event of a job.
+func receivers(event models.WebhookEvent, job models.Job) []models.Webhook {
+	var hooks []models.Webhook
+	for _, url := range config.WEBHOOK_URLS {
+		if url != "" {
+			hooks = append(hooks, models.Webhook{URL: url, Secret: config.WEBHOOK_SECRET})
+		}
+	}
+	for _, wh := range job.Configuration.Webhooks {
+		if wh.Wants(event) {
+			hooks = append(hooks, wh)
+		}
+	}
+	return hooks
+}
+
+// deliver posts a payload until the receiver accepts it or the attempts run out.
+func deliver(wh models.Webhook, event models.WebhookEvent, id string, body []byte) error {
+	delay := backoff
+	var err error
+	for attempt := 1; attempt <= config.WEBHOOK_MAX_ATTEMPTS; attempt++ {
+		var retry bool
+		if retry, err = post(wh, event, id, body); err == nil {
+			slog.Debug("Delivered webhook", "url", wh.URL, "event", event, "delivery", id, "attempt", attempt)
+			return nil
+		}
+		slog.Warn("Webhook delivery failed", "url", wh.URL, "event", event, "delivery", id, "attempt", attempt, "error", err)
+		if !retry || attempt == config.WEBHOOK_MAX_ATTEMPTS {
+			break
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+	slog.Error("Giving up on webhook delivery", "url", wh.URL, "event", event, "delivery", id, "error", err)
+	return err
+}
+
+// post makes a single delivery attempt and reports whether a failure is worth retrying.
+func post(wh models.Webhook, event models.WebhookEvent, id string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golive-webhook")
+	req.Header.Set(EventHeader, string(event))
+	req.Header.Set(DeliveryHeader, id)
+	if wh.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(wh.Secret, body))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook: %s responded %s", wh.URL, resp.Status)
+}
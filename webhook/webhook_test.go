@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arunjeyaprasad/golive/config"
+	"github.com/arunjeyaprasad/golive/models"
+)
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event":"job.created"}`)
+	signature := Sign("s3cret", body)
+	if !strings.HasPrefix(signature, "sha256=") {
+		t.Errorf("Sign() = %v, want a sha256= prefix", signature)
+	}
+	if !Verify("s3cret", body, signature) {
+		t.Errorf("Verify() = false for a valid signature")
+	}
+	if Verify("other", body, signature) || Verify("s3cret", []byte(`{}`), signature) {
+		t.Errorf("Verify() = true for a wrong secret or body")
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	backoff = time.Millisecond
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{name: "Accepted", statuses: []int{http.StatusNoContent}, wantAttempts: 1},
+		{name: "Retried until accepted", statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, wantAttempts: 3},
+		{name: "Client error is not retried", statuses: []int{http.StatusBadRequest}, wantAttempts: 1, wantErr: true},
+		{name: "Gives up after the last attempt", statuses: []int{http.StatusInternalServerError}, wantAttempts: int32(config.WEBHOOK_MAX_ATTEMPTS), wantErr: true},
+	}
+	body := []byte(`{"event":"job.started"}`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := attempts.Add(1)
+				got, _ := io.ReadAll(r.Body)
+				if !Verify("s3cret", got, r.Header.Get(SignatureHeader)) {
+					t.Errorf("delivery %d has an invalid signature", n)
+				}
+				if r.Header.Get(EventHeader) != "job.started" || r.Header.Get(DeliveryHeader) != "delivery-1" {
+					t.Errorf("delivery headers = %v", r.Header)
+				}
+				w.WriteHeader(tt.statuses[min(int(n), len(tt.statuses))-1])
+			}))
+			defer srv.Close()
+
+			err := deliver(models.Webhook{URL: srv.URL, Secret: "s3cret"}, models.WebhookEventStarted, "delivery-1", body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("deliver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("deliver() attempts = %d, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestNotify(t *testing.T) {
+	payloads := make(chan models.WebhookPayload, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload models.WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("payload is not JSON: %v", err)
+		}
+		payloads <- payload
+	}))
+	defer srv.Close()
+
+	job := models.Job{
+		ID: "job-1",
+		Configuration: models.JobCreateRequest{
+			Webhooks: []models.Webhook{
+				{URL: srv.URL + "/all", Secret: "s3cret"},
+				{URL: srv.URL + "/stopped", Events: []models.WebhookEvent{models.WebhookEventStopped}},
+			},
+		},
+	}
+	Notify(models.WebhookEventCreated, job)
+	select {
+	case payload := <-payloads:
+		if payload.Event != models.WebhookEventCreated || payload.JobID != "job-1" || payload.ID == "" {
+			t.Errorf("payload = %+v", payload)
+		}
+		if secret := payload.Job.Configuration.Webhooks[0].Secret; secret != "" {
+			t.Errorf("payload leaks the webhook secret %q", secret)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify() did not deliver the event")
+	}
+	select {
+	case payload := <-payloads:
+		t.Errorf("unsubscribed webhook received %v", payload.Event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}